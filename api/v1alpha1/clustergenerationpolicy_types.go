@@ -56,6 +56,12 @@ type ClusterGenerationPolicySpec struct {
 	// +listMapKey=resource
 	Sources []SourceGroupT `json:"sources"`
 
+	// Variables represents a list of named expressions evaluated in order before the conditions.
+	// Results are stored under 'vars' and are available for all the engines
+	// +listType=map
+	// +listMapKey=name
+	Variables []VariableT `json:"variables,omitempty"`
+
 	// Conditions represents a list of conditions that must be passed to meet the policy
 	// +listType=map
 	// +listMapKey=name
@@ -68,11 +74,20 @@ type ClusterGenerationPolicySpec struct {
 type ClusterGenerationPolicyStatus struct {
 	// Conditions represent the latest available observations of an object's state
 	Conditions []metav1.Condition `json:"conditions"`
+
+	// ObservedGeneration represents the generation of the policy that was reconciled last time
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	PolicyRuntimeStatusT `json:",inline"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:resource:path=clustergenerationpolicies,scope=Cluster
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Synced",type="string",JSONPath=".status.conditions[?(@.type==\"ResourceSynced\")].status"
+// +kubebuilder:printcolumn:name="Evaluations",type="integer",JSONPath=".status.counters.evaluations"
+// +kubebuilder:printcolumn:name="Errors",type="integer",JSONPath=".status.counters.errors"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // ClusterGenerationPolicy is the Schema for the clustergenerationpolicies API
 type ClusterGenerationPolicy struct {
@@ -91,6 +106,10 @@ func (p *ClusterGenerationPolicy) GetSources() []SourceGroupT {
 	return p.Spec.Sources
 }
 
+func (p *ClusterGenerationPolicy) GetRuntimeStatus() *PolicyRuntimeStatusT {
+	return &p.Status.PolicyRuntimeStatusT
+}
+
 // +kubebuilder:object:root=true
 
 // ClusterGenerationPolicyList contains a list of ClusterGenerationPolicy
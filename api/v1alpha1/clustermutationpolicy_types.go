@@ -24,12 +24,39 @@ const (
 	MutationPatchTypeJson           string = "jsonpatch"
 	MutationPatchTypeMerge          string = "jsonmerge"
 	MutationPatchTypeStrategicMerge string = "strategicmerge"
+
+	// Following types are always CEL expressions returning structured values, as in MutatingAdmissionPolicy
+	MutationPatchTypeApplyConfiguration string = "applyconfiguration"
+	MutationPatchTypeCelJsonPatch       string = "celjsonpatch"
+
+	// Ways to validate the object patched by a policy before returning the patch
+	MutationValidateResultNone    string = "none"
+	MutationValidateResultDryRun  string = "dryrun"
+	MutationValidateResultOpenapi string = "openapi"
+
+	// Ways to reinvoke the mutation webhook when the object is modified by later webhooks
+	MutationReinvocationPolicyNever    string = "Never"
+	MutationReinvocationPolicyIfNeeded string = "IfNeeded"
+
+	// Ways to handle the patches computed by a policy
+	MutationModeEnforce string = "enforce"
+	MutationModeAudit   string = "audit"
 )
 
 type PatchT struct {
+	// Name identifies the patch in logs and events. It's optional
+	Name string `json:"name,omitempty"`
+
 	Type     string `json:"type"`
 	Engine   string `json:"engine,omitempty"`
 	Template string `json:"template"`
+
+	// Conditions represents a list of conditions that must be passed to apply this patch.
+	// They are evaluated after the conditions of the policy
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Conditions []ConditionT `json:"conditions,omitempty"`
 }
 
 // ClusterMutationPolicySpec defines the desired state of ClusterMutationPolicy
@@ -39,6 +66,11 @@ type ClusterMutationPolicySpec struct {
 	// Policies with higher values are evaluated later.
 	Priority int `json:"priority,omitempty"`
 
+	// Mode represents how the patches of the policy are handled: 'enforce' applies them, while 'audit' only
+	// reports the operations that would be applied through events, logs and metrics. Defaults to 'enforce'
+	// +optional
+	Mode string `json:"mode,omitempty"`
+
 	// InterceptedResources represents a list of resource-groups that will be sent to the admissions server to be evaluated
 	// +listType=map
 	// +listMapKey=group
@@ -53,23 +85,82 @@ type ClusterMutationPolicySpec struct {
 	// +listMapKey=resource
 	Sources []SourceGroupT `json:"sources"`
 
-	// Conditions represents a list of conditions that must be passed to meet the policy
+	// Variables represents a list of named expressions evaluated in order before the conditions.
+	// Results are stored under 'vars' and are available for all the engines
 	// +listType=map
 	// +listMapKey=name
-	Conditions []ConditionT `json:"conditions"`
+	Variables []VariableT `json:"variables,omitempty"`
 
-	Patch PatchT `json:"patch"`
+	// Conditions represents a list of conditions that must be passed to meet the policy
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Conditions []ConditionT `json:"conditions,omitempty"`
+
+	// Program represents a template that returns the whole decision: allowed, message, warnings, patch and vars.
+	// It's evaluated when the conditions are met, replacing the patch template
+	// +optional
+	Program *ProgramT `json:"program,omitempty"`
+
+	// Patch represents the template that generates the patch applied to the object
+	// +optional
+	Patch PatchT `json:"patch,omitempty"`
+
+	// Patches represents a list of patches applied in order, each one over the result of the previous one.
+	// They are applied after 'patch' when both are defined. When any of them fails, the whole policy is aborted,
+	// so the object is never partially patched
+	// +listType=atomic
+	// +optional
+	Patches []PatchT `json:"patches,omitempty"`
+
+	// ValidateResult represents how the object patched by this policy is validated before returning the patch:
+	// 'dryrun' sends it to Kubernetes in dry-run mode, 'openapi' validates it offline against the OpenAPI schemas,
+	// and 'none' skips the validation. When empty, the mode set globally in the controller is used.
+	// Policies producing invalid objects are dropped
+	// +optional
+	ValidateResult string `json:"validateResult,omitempty"`
+
+	// ReinvocationPolicy represents whether the policy needs to be evaluated again when the object is modified
+	// by later mutations: Never or IfNeeded. The mutation webhook is reinvoked when any policy needs it.
+	// Policies with IfNeeded are checked to be idempotent on each evaluation
+	// +kubebuilder:validation:Enum=Never;IfNeeded
+	// +optional
+	ReinvocationPolicy string `json:"reinvocationPolicy,omitempty"`
+
+	// Tests represents a list of fixtures evaluated before activating the policy.
+	// When some of them fail, the previous version of the policy is kept active
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Tests []PolicyTestT `json:"tests,omitempty"`
 }
 
 // ClusterMutationPolicyStatus defines the observed state of ClusterMutationPolicy
 type ClusterMutationPolicyStatus struct {
 	// Conditions represent the latest available observations of an object's state
 	Conditions []metav1.Condition `json:"conditions"`
+
+	// ObservedGeneration represents the generation of the policy that was reconciled last time
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Webhook represents the registration of the policy in the admission webhook configuration
+	Webhook *PolicyWebhookStatusT `json:"webhook,omitempty"`
+
+	// TestResults represents the results of the tests run during the last reconciliation
+	TestResults []PolicyTestResultT `json:"testResults,omitempty"`
+
+	PolicyRuntimeStatusT `json:",inline"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:resource:path=clustermutationpolicies,scope=Cluster
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Synced",type="string",JSONPath=".status.conditions[?(@.type==\"ResourceSynced\")].status"
+// +kubebuilder:printcolumn:name="Registered",type="boolean",JSONPath=".status.webhook.registered"
+// +kubebuilder:printcolumn:name="Evaluations",type="integer",JSONPath=".status.counters.evaluations"
+// +kubebuilder:printcolumn:name="Violations",type="integer",JSONPath=".status.counters.violations"
+// +kubebuilder:printcolumn:name="Errors",type="integer",JSONPath=".status.counters.errors"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // ClusterMutationPolicy is the Schema for the clustermutationpolicies API
 type ClusterMutationPolicy struct {
@@ -88,6 +179,10 @@ func (p *ClusterMutationPolicy) GetSources() []SourceGroupT {
 	return p.Spec.Sources
 }
 
+func (p *ClusterMutationPolicy) GetRuntimeStatus() *PolicyRuntimeStatusT {
+	return &p.Status.PolicyRuntimeStatusT
+}
+
 // +kubebuilder:object:root=true
 
 // ClusterMutationPolicyList contains a list of ClusterMutationPolicy
@@ -48,23 +48,62 @@ type ClusterValidationPolicySpec struct {
 	// +listMapKey=resource
 	Sources []SourceGroupT `json:"sources"`
 
+	// Variables represents a list of named expressions evaluated in order before the conditions.
+	// Results are stored under 'vars' and are available for all the engines
+	// +listType=map
+	// +listMapKey=name
+	Variables []VariableT `json:"variables,omitempty"`
+
 	// Conditions represents a list of conditions that must be passed to meet the policy
 	// +listType=map
 	// +listMapKey=name
-	Conditions []ConditionT `json:"conditions"`
+	// +optional
+	Conditions []ConditionT `json:"conditions,omitempty"`
+
+	// Program represents a template that returns the whole decision: allowed, message, warnings and vars.
+	// It's evaluated when the conditions are met
+	// +optional
+	Program *ProgramT `json:"program,omitempty"`
 
-	Message MessageT `json:"message"`
+	// Message represents the template for the rejection message.
+	// When a program is defined, this template is used only when the program does not return a message
+	// +optional
+	Message MessageT `json:"message,omitempty"`
+
+	// Tests represents a list of fixtures evaluated before activating the policy.
+	// When some of them fail, the previous version of the policy is kept active
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Tests []PolicyTestT `json:"tests,omitempty"`
 }
 
 // ClusterValidationPolicyStatus defines the observed state of ClusterValidationPolicy
 type ClusterValidationPolicyStatus struct {
 	// Conditions represent the latest available observations of an object's state
 	Conditions []metav1.Condition `json:"conditions"`
+
+	// ObservedGeneration represents the generation of the policy that was reconciled last time
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Webhook represents the registration of the policy in the admission webhook configuration
+	Webhook *PolicyWebhookStatusT `json:"webhook,omitempty"`
+
+	// TestResults represents the results of the tests run during the last reconciliation
+	TestResults []PolicyTestResultT `json:"testResults,omitempty"`
+
+	PolicyRuntimeStatusT `json:",inline"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:resource:path=clustervalidationpolicies,scope=Cluster
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Synced",type="string",JSONPath=".status.conditions[?(@.type==\"ResourceSynced\")].status"
+// +kubebuilder:printcolumn:name="Registered",type="boolean",JSONPath=".status.webhook.registered"
+// +kubebuilder:printcolumn:name="Evaluations",type="integer",JSONPath=".status.counters.evaluations"
+// +kubebuilder:printcolumn:name="Violations",type="integer",JSONPath=".status.counters.violations"
+// +kubebuilder:printcolumn:name="Errors",type="integer",JSONPath=".status.counters.errors"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // ClusterValidationPolicy is the Schema for the clustervalidationpolicies API
 type ClusterValidationPolicy struct {
@@ -83,6 +122,10 @@ func (p *ClusterValidationPolicy) GetSources() []SourceGroupT {
 	return p.Spec.Sources
 }
 
+func (p *ClusterValidationPolicy) GetRuntimeStatus() *PolicyRuntimeStatusT {
+	return &p.Status.PolicyRuntimeStatusT
+}
+
 // +kubebuilder:object:root=true
 
 // ClusterValidationPolicyList contains a list of ClusterValidationPolicy
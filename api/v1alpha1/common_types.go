@@ -83,3 +83,139 @@ type ConditionT struct {
 	Key    string `json:"key"`
 	Value  string `json:"value"`
 }
+
+// VariableT represents a named expression that is evaluated before conditions.
+// Its result is stored in 'vars' under the given name, and can be read from any engine
+type VariableT struct {
+	Name     string `json:"name"`
+	Engine   string `json:"engine,omitempty"`
+	Template string `json:"template"`
+}
+
+// ProgramT represents a template that returns the whole decision of a policy at once,
+// as a JSON or YAML object. This way, data is computed once for allowing, messages, warnings and patches
+type ProgramT struct {
+	Engine   string `json:"engine,omitempty"`
+	Template string `json:"template"`
+}
+
+// PolicyTestT represents a fixture that is evaluated against the policy before activating it.
+// Objects are expressed in YAML, as templates are
+type PolicyTestT struct {
+	Name string `json:"name"`
+
+	// Operation represents the operation of the request: CREATE, UPDATE, DELETE or CONNECT. Defaults to CREATE
+	// +optional
+	Operation admissionV1.OperationType `json:"operation,omitempty"`
+
+	Object string `json:"object"`
+
+	// OldObject represents the previous state of the object, only for UPDATE operations
+	// +optional
+	OldObject string `json:"oldObject,omitempty"`
+
+	// UserInfo represents the user performing the request
+	// +optional
+	UserInfo string `json:"userInfo,omitempty"`
+
+	// Sources represents the objects returned by each source, as YAML lists, in the same order they are declared.
+	// Informers are not used during tests
+	// +optional
+	Sources []string `json:"sources,omitempty"`
+
+	// Lookups represents the objects returned by lookup functions, as a YAML list.
+	// Kubernetes is not reached during tests, so objects not listed here are not found
+	// +optional
+	Lookups string `json:"lookups,omitempty"`
+
+	// AccessReviews represents the answers given by 'can' functions.
+	// Kubernetes is not reached during tests, so actions not listed here are denied
+	// +optional
+	AccessReviews []PolicyTestAccessReviewT `json:"accessReviews,omitempty"`
+
+	Expect PolicyTestExpectationT `json:"expect"`
+}
+
+// PolicyTestAccessReviewT represents the answer given by 'can' functions to an action during tests
+type PolicyTestAccessReviewT struct {
+	// User represents the user performing the action. Empty means any user
+	// +optional
+	User string `json:"user,omitempty"`
+
+	Verb string `json:"verb"`
+
+	// +optional
+	Group string `json:"group,omitempty"`
+
+	Resource string `json:"resource"`
+
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	Allowed bool `json:"allowed"`
+}
+
+// PolicyTestExpectationT represents the expected outcome of a test. Empty fields are not checked
+type PolicyTestExpectationT struct {
+	// Allowed represents whether the object is expected to meet the policy, whatever its failureAction is
+	// +optional
+	Allowed *bool `json:"allowed,omitempty"`
+
+	// Message represents a text expected to be contained in the message returned by the policy
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// PatchedObject represents the object expected after applying the patch. Only for mutation policies
+	// +optional
+	PatchedObject string `json:"patchedObject,omitempty"`
+}
+
+// PolicyTestResultT represents the result of running one of the tests of a policy
+type PolicyTestResultT struct {
+	Name    string `json:"name"`
+	Passed  bool   `json:"passed"`
+	Message string `json:"message,omitempty"`
+}
+
+// PolicyWebhookStatusT represents the registration of a policy in the admission webhook configuration
+type PolicyWebhookStatusT struct {
+	Registered    bool   `json:"registered"`
+	Configuration string `json:"configuration,omitempty"`
+
+	// Rules represents the registered resource types, following the pattern {group}/{version}/{resource}/{operation}
+	Rules []string `json:"rules,omitempty"`
+}
+
+// PolicySourceStatusT represents the state of the informer that collects the objects of a source
+type PolicySourceStatusT struct {
+	metav1.GroupVersionResource `json:",inline"`
+
+	Started bool `json:"started"`
+	Synced  bool `json:"synced"`
+
+	// Items represents the amount of objects collected by the informer, before applying the filters
+	Items int `json:"items"`
+}
+
+// PolicyCountersT represents the times a policy was evaluated.
+// Counters are kept in memory and flushed periodically, so they are eventually consistent
+type PolicyCountersT struct {
+	Evaluations int64 `json:"evaluations"`
+	Violations  int64 `json:"violations"`
+	Errors      int64 `json:"errors"`
+
+	// NonIdempotent represents the times a mutation produced changes when it was applied again over its own output
+	NonIdempotent int64 `json:"nonIdempotent,omitempty"`
+
+	LastFlushTime *metav1.Time `json:"lastFlushTime,omitempty"`
+}
+
+// PolicyRuntimeStatusT represents the status of a policy that changes while it's evaluated.
+// It's updated periodically, instead of on each reconciliation
+type PolicyRuntimeStatusT struct {
+	Sources  []PolicySourceStatusT `json:"sources,omitempty"`
+	Counters PolicyCountersT       `json:"counters,omitempty"`
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// LibraryTemplateT represents a named piece of code that can be reused from policies' templates
+type LibraryTemplateT struct {
+	Name     string `json:"name"`
+	Template string `json:"template"`
+}
+
+// TemplateLibrarySpec defines the desired state of TemplateLibrary
+type TemplateLibrarySpec struct {
+
+	// Gotmpl represents a list of Go templates. They are commonly made of 'define' blocks.
+	// Policies can use them through 'include' or 'template' functions by the name of the blocks,
+	// or by '{library}/{name}' to render the entire item
+	// +listType=map
+	// +listMapKey=name
+	Gotmpl []LibraryTemplateT `json:"gotmpl,omitempty"`
+
+	// Starlark represents a list of Starlark modules.
+	// Policies can use them through 'load("{library}/{name}", "symbol")'
+	// +listType=map
+	// +listMapKey=name
+	Starlark []LibraryTemplateT `json:"starlark,omitempty"`
+}
+
+// TemplateLibraryStatus defines the observed state of TemplateLibrary
+type TemplateLibraryStatus struct {
+	// Conditions represent the latest available observations of an object's state
+	Conditions []metav1.Condition `json:"conditions"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path=templatelibraries,scope=Cluster
+// +kubebuilder:subresource:status
+
+// TemplateLibrary is the Schema for the templatelibraries API
+type TemplateLibrary struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   TemplateLibrarySpec   `json:"spec,omitempty"`
+	Status TemplateLibraryStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// TemplateLibraryList contains a list of TemplateLibrary
+type TemplateLibraryList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []TemplateLibrary `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&TemplateLibrary{}, &TemplateLibraryList{})
+}
@@ -121,6 +121,11 @@ func (in *ClusterGenerationPolicySpec) DeepCopyInto(out *ClusterGenerationPolicy
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Variables != nil {
+		in, out := &in.Variables, &out.Variables
+		*out = make([]VariableT, len(*in))
+		copy(*out, *in)
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]ConditionT, len(*in))
@@ -149,6 +154,7 @@ func (in *ClusterGenerationPolicyStatus) DeepCopyInto(out *ClusterGenerationPoli
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	in.PolicyRuntimeStatusT.DeepCopyInto(&out.PolicyRuntimeStatusT)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterGenerationPolicyStatus.
@@ -237,12 +243,36 @@ func (in *ClusterMutationPolicySpec) DeepCopyInto(out *ClusterMutationPolicySpec
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Variables != nil {
+		in, out := &in.Variables, &out.Variables
+		*out = make([]VariableT, len(*in))
+		copy(*out, *in)
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]ConditionT, len(*in))
 		copy(*out, *in)
 	}
-	out.Patch = in.Patch
+	if in.Program != nil {
+		in, out := &in.Program, &out.Program
+		*out = new(ProgramT)
+		**out = **in
+	}
+	in.Patch.DeepCopyInto(&out.Patch)
+	if in.Patches != nil {
+		in, out := &in.Patches, &out.Patches
+		*out = make([]PatchT, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Tests != nil {
+		in, out := &in.Tests, &out.Tests
+		*out = make([]PolicyTestT, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterMutationPolicySpec.
@@ -265,6 +295,17 @@ func (in *ClusterMutationPolicyStatus) DeepCopyInto(out *ClusterMutationPolicySt
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Webhook != nil {
+		in, out := &in.Webhook, &out.Webhook
+		*out = new(PolicyWebhookStatusT)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TestResults != nil {
+		in, out := &in.TestResults, &out.TestResults
+		*out = make([]PolicyTestResultT, len(*in))
+		copy(*out, *in)
+	}
+	in.PolicyRuntimeStatusT.DeepCopyInto(&out.PolicyRuntimeStatusT)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterMutationPolicyStatus.
@@ -353,12 +394,29 @@ func (in *ClusterValidationPolicySpec) DeepCopyInto(out *ClusterValidationPolicy
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Variables != nil {
+		in, out := &in.Variables, &out.Variables
+		*out = make([]VariableT, len(*in))
+		copy(*out, *in)
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]ConditionT, len(*in))
 		copy(*out, *in)
 	}
+	if in.Program != nil {
+		in, out := &in.Program, &out.Program
+		*out = new(ProgramT)
+		**out = **in
+	}
 	out.Message = in.Message
+	if in.Tests != nil {
+		in, out := &in.Tests, &out.Tests
+		*out = make([]PolicyTestT, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterValidationPolicySpec.
@@ -381,6 +439,17 @@ func (in *ClusterValidationPolicyStatus) DeepCopyInto(out *ClusterValidationPoli
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Webhook != nil {
+		in, out := &in.Webhook, &out.Webhook
+		*out = new(PolicyWebhookStatusT)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TestResults != nil {
+		in, out := &in.TestResults, &out.TestResults
+		*out = make([]PolicyTestResultT, len(*in))
+		copy(*out, *in)
+	}
+	in.PolicyRuntimeStatusT.DeepCopyInto(&out.PolicyRuntimeStatusT)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterValidationPolicyStatus.
@@ -408,6 +477,21 @@ func (in *ConditionT) DeepCopy() *ConditionT {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LibraryTemplateT) DeepCopyInto(out *LibraryTemplateT) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LibraryTemplateT.
+func (in *LibraryTemplateT) DeepCopy() *LibraryTemplateT {
+	if in == nil {
+		return nil
+	}
+	out := new(LibraryTemplateT)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MessageT) DeepCopyInto(out *MessageT) {
 	*out = *in
@@ -473,6 +557,11 @@ func (in *ObjectT) DeepCopy() *ObjectT {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PatchT) DeepCopyInto(out *PatchT) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]ConditionT, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PatchT.
@@ -485,6 +574,173 @@ func (in *PatchT) DeepCopy() *PatchT {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PolicyCountersT) DeepCopyInto(out *PolicyCountersT) {
+	*out = *in
+	if in.LastFlushTime != nil {
+		in, out := &in.LastFlushTime, &out.LastFlushTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PolicyCountersT.
+func (in *PolicyCountersT) DeepCopy() *PolicyCountersT {
+	if in == nil {
+		return nil
+	}
+	out := new(PolicyCountersT)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PolicyRuntimeStatusT) DeepCopyInto(out *PolicyRuntimeStatusT) {
+	*out = *in
+	if in.Sources != nil {
+		in, out := &in.Sources, &out.Sources
+		*out = make([]PolicySourceStatusT, len(*in))
+		copy(*out, *in)
+	}
+	in.Counters.DeepCopyInto(&out.Counters)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PolicyRuntimeStatusT.
+func (in *PolicyRuntimeStatusT) DeepCopy() *PolicyRuntimeStatusT {
+	if in == nil {
+		return nil
+	}
+	out := new(PolicyRuntimeStatusT)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PolicySourceStatusT) DeepCopyInto(out *PolicySourceStatusT) {
+	*out = *in
+	out.GroupVersionResource = in.GroupVersionResource
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PolicySourceStatusT.
+func (in *PolicySourceStatusT) DeepCopy() *PolicySourceStatusT {
+	if in == nil {
+		return nil
+	}
+	out := new(PolicySourceStatusT)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PolicyTestAccessReviewT) DeepCopyInto(out *PolicyTestAccessReviewT) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PolicyTestAccessReviewT.
+func (in *PolicyTestAccessReviewT) DeepCopy() *PolicyTestAccessReviewT {
+	if in == nil {
+		return nil
+	}
+	out := new(PolicyTestAccessReviewT)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PolicyTestExpectationT) DeepCopyInto(out *PolicyTestExpectationT) {
+	*out = *in
+	if in.Allowed != nil {
+		in, out := &in.Allowed, &out.Allowed
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PolicyTestExpectationT.
+func (in *PolicyTestExpectationT) DeepCopy() *PolicyTestExpectationT {
+	if in == nil {
+		return nil
+	}
+	out := new(PolicyTestExpectationT)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PolicyTestResultT) DeepCopyInto(out *PolicyTestResultT) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PolicyTestResultT.
+func (in *PolicyTestResultT) DeepCopy() *PolicyTestResultT {
+	if in == nil {
+		return nil
+	}
+	out := new(PolicyTestResultT)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PolicyTestT) DeepCopyInto(out *PolicyTestT) {
+	*out = *in
+	if in.Sources != nil {
+		in, out := &in.Sources, &out.Sources
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AccessReviews != nil {
+		in, out := &in.AccessReviews, &out.AccessReviews
+		*out = make([]PolicyTestAccessReviewT, len(*in))
+		copy(*out, *in)
+	}
+	in.Expect.DeepCopyInto(&out.Expect)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PolicyTestT.
+func (in *PolicyTestT) DeepCopy() *PolicyTestT {
+	if in == nil {
+		return nil
+	}
+	out := new(PolicyTestT)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PolicyWebhookStatusT) DeepCopyInto(out *PolicyWebhookStatusT) {
+	*out = *in
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PolicyWebhookStatusT.
+func (in *PolicyWebhookStatusT) DeepCopy() *PolicyWebhookStatusT {
+	if in == nil {
+		return nil
+	}
+	out := new(PolicyWebhookStatusT)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProgramT) DeepCopyInto(out *ProgramT) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProgramT.
+func (in *ProgramT) DeepCopy() *ProgramT {
+	if in == nil {
+		return nil
+	}
+	out := new(ProgramT)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ResourceGroupT) DeepCopyInto(out *ResourceGroupT) {
 	*out = *in
@@ -645,3 +901,124 @@ func (in *SourceGroupT) DeepCopy() *SourceGroupT {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TemplateLibrary) DeepCopyInto(out *TemplateLibrary) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TemplateLibrary.
+func (in *TemplateLibrary) DeepCopy() *TemplateLibrary {
+	if in == nil {
+		return nil
+	}
+	out := new(TemplateLibrary)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TemplateLibrary) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TemplateLibraryList) DeepCopyInto(out *TemplateLibraryList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]TemplateLibrary, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TemplateLibraryList.
+func (in *TemplateLibraryList) DeepCopy() *TemplateLibraryList {
+	if in == nil {
+		return nil
+	}
+	out := new(TemplateLibraryList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TemplateLibraryList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TemplateLibrarySpec) DeepCopyInto(out *TemplateLibrarySpec) {
+	*out = *in
+	if in.Gotmpl != nil {
+		in, out := &in.Gotmpl, &out.Gotmpl
+		*out = make([]LibraryTemplateT, len(*in))
+		copy(*out, *in)
+	}
+	if in.Starlark != nil {
+		in, out := &in.Starlark, &out.Starlark
+		*out = make([]LibraryTemplateT, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TemplateLibrarySpec.
+func (in *TemplateLibrarySpec) DeepCopy() *TemplateLibrarySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TemplateLibrarySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TemplateLibraryStatus) DeepCopyInto(out *TemplateLibraryStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TemplateLibraryStatus.
+func (in *TemplateLibraryStatus) DeepCopy() *TemplateLibraryStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TemplateLibraryStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VariableT) DeepCopyInto(out *VariableT) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VariableT.
+func (in *VariableT) DeepCopy() *VariableT {
+	if in == nil {
+		return nil
+	}
+	out := new(VariableT)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -19,6 +19,7 @@ package main
 import (
 	"crypto/tls"
 	"flag"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
@@ -36,6 +37,7 @@ import (
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
@@ -43,19 +45,28 @@ import (
 
 	//
 	"github.com/freepik-company/admitik/api/v1alpha1"
+	"github.com/freepik-company/admitik/internal/accessreview"
 	"github.com/freepik-company/admitik/internal/certificates"
 	"github.com/freepik-company/admitik/internal/controller"
 	"github.com/freepik-company/admitik/internal/controller/clustergenerationpolicy"
 	"github.com/freepik-company/admitik/internal/controller/clustermutationpolicy"
 	"github.com/freepik-company/admitik/internal/controller/clustervalidationpolicy"
 	"github.com/freepik-company/admitik/internal/controller/observedresource"
+	"github.com/freepik-company/admitik/internal/controller/policystatus"
 	"github.com/freepik-company/admitik/internal/controller/sources"
+	"github.com/freepik-company/admitik/internal/controller/templatelibrary"
+	"github.com/freepik-company/admitik/internal/evaluator"
 	"github.com/freepik-company/admitik/internal/globals"
+	"github.com/freepik-company/admitik/internal/kubelookup"
+	mutationConflictsRegistry "github.com/freepik-company/admitik/internal/registry/mutationconflicts"
+	policyCountersRegistry "github.com/freepik-company/admitik/internal/registry/policycounters"
 	policyStore "github.com/freepik-company/admitik/internal/registry/policystore"
 	resourceInformerRegistry "github.com/freepik-company/admitik/internal/registry/resourceinformer"
 	resourceObserverRegistry "github.com/freepik-company/admitik/internal/registry/resourceobserver"
 	sourcesRegistry "github.com/freepik-company/admitik/internal/registry/sources"
+	templateLibraryRegistry "github.com/freepik-company/admitik/internal/registry/templatelibrary"
 	"github.com/freepik-company/admitik/internal/server/admission"
+	"github.com/freepik-company/admitik/internal/strategicmerge"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -99,10 +110,22 @@ func main() {
 	var kubeClientQps float64
 	var kubeClientBurst int
 
+	var lookupAllowedResources string
+	var lookupCacheTTL time.Duration
+	var lookupMaxCallsPerRequest int
+
 	var enableSpecialLabels bool
 	var excludeAdmissionSelfNamespace bool
 	var excludedAdmissionNamespaces string
 
+	var enableNativeTranslation bool
+
+	var policyStatusFlushInterval time.Duration
+
+	var mutationValidateResult string
+	var mutationRecordAnnotation bool
+	var mutationConflictsReport bool
+
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metric endpoint binds to. "+
 		"Use the port :8080. If not set, it will be 0 in order to disable the metrics server")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
@@ -146,6 +169,16 @@ func main() {
 	flag.IntVar(&kubeClientBurst, "kube-client-burst", 10,
 		"The burst capacity of communication between the controller and the API Server")
 
+	// Lookup related flags
+	flag.StringVar(&lookupAllowedResources, "lookup-allowed-resources", "",
+		"Comma-separated list of resources that templates can look up, following kubectl style: {resource}.{group}. "+
+			"Entries can be scoped to a namespace and a verb: {namespace}/{resource}.{group}:{get|list}. "+
+			"Wildcards are allowed. Empty disables lookups")
+	flag.DurationVar(&lookupCacheTTL, "lookup-cache-ttl", 5*time.Second,
+		"Time a looked up resource is reused before asking Kubernetes again")
+	flag.IntVar(&lookupMaxCallsPerRequest, "lookup-max-calls-per-request", 10,
+		"Max calls to Kubernetes done by lookups from templates on each request. Zero means unlimited")
+
 	// Exclusion related flags
 	flag.BoolVar(&enableSpecialLabels, "enable-special-labels", false,
 		"Enable labels that perform sensitive actions")
@@ -154,6 +187,25 @@ func main() {
 	flag.StringVar(&excludedAdmissionNamespaces, "excluded-admission-namespaces", "",
 		"Comma-separated list of namespaces to be excluded from admission evaluations. Commonly used for 'kube-system'")
 
+	// Native translation related flags
+	flag.BoolVar(&enableNativeTranslation, "enable-native-translation", false,
+		"Translate eligible ClusterValidationPolicy objects into ValidatingAdmissionPolicy objects, "+
+			"serving them without the webhook. Requires Kubernetes v1.30+")
+
+	// Policy status related flags
+	flag.DurationVar(&policyStatusFlushInterval, "policy-status-flush-interval", 30*time.Second,
+		"Time between writes of the runtime status of the policies: evaluation counters and sources' state")
+
+	// Mutation related flags
+	flag.StringVar(&mutationValidateResult, "mutation-validate-result", v1alpha1.MutationValidateResultNone,
+		"Default way to validate the objects patched by ClusterMutationPolicy objects before returning the patch: "+
+			"none, dryrun or openapi. Policies can override it with 'validateResult'")
+	flag.BoolVar(&mutationRecordAnnotation, "mutation-record-annotation", false,
+		"Record the names and generations of the policies that changed an object in 'admitik.dev/mutated-by' annotation")
+	flag.BoolVar(&mutationConflictsReport, "mutation-conflicts-report", false,
+		"If set, the report of the mutation policies overlapping on each resource type is served by the metrics server "+
+			"at '"+admission.MutationConflictsReportPath+"'")
+
 	// Ref: https://pkg.go.dev/sigs.k8s.io/controller-runtime/pkg/log/zap@v0.21.0#Options.BindFlags
 	opts := zap.Options{
 		Development: true,
@@ -181,6 +233,43 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Create the client used by templates to look up resources in Kubernetes on evaluation time
+	lookupAllowedResourcesList := []string{}
+	if lookupAllowedResources != "" {
+		lookupAllowedResourcesList = strings.Split(lookupAllowedResources, ",")
+	}
+	globals.Application.KubeLookupClient, err = kubelookup.NewKubeLookupClient(globals.Application.Context,
+		kubelookup.KubeLookupClientOptions{
+			AllowedResources:   lookupAllowedResourcesList,
+			CacheTTL:           lookupCacheTTL,
+			MaxCallsPerRequest: lookupMaxCallsPerRequest,
+		},
+		globals.Application.KubeRawClient,
+		globals.Application.KubeDiscoveryClient)
+	if err != nil {
+		setupLog.Error(err, "unable to set up lookups client")
+		os.Exit(1)
+	}
+
+	// Create the client used by templates to ask Kubernetes authorizer about users' permissions
+	globals.Application.AccessReviewClient = accessreview.NewAccessReviewClient(globals.Application.Context,
+		globals.Application.KubeRawCoreClient)
+
+	// Create the evaluator that computes policies' decisions.
+	// It's shared by the admission server and the reconcilers running policies' tests
+	strategicMergePatcher, err := strategicmerge.NewStrategicMergePatcher(globals.Application.Context, &strategicmerge.StrategicMergePatcherDependencies{
+		DiscoveryClient: globals.Application.KubeDiscoveryClient,
+		DynamicClient:   globals.Application.KubeRawClient,
+	})
+	if err != nil {
+		setupLog.Error(err, "unable to set up strategic merge patcher")
+		os.Exit(1)
+	}
+
+	policyEvaluator := evaluator.NewEvaluator(evaluator.EvaluatorDependencies{
+		StrategicMergePatcher: strategicMergePatcher,
+	})
+
 	currentNamespace, err := globals.GetCurrentNamespace()
 	if err != nil {
 		setupLog.Error(err, "unable to get current namespace")
@@ -207,13 +296,38 @@ func main() {
 		TLSOpts: tlsOpts,
 	})
 
+	// Create registries managers that will be used by several controllers
+	clusterValidationPolicyReg := policyStore.NewPolicyStore[*v1alpha1.ClusterValidationPolicy]()
+	clusterMutationPolicyReg := policyStore.NewPolicyStore[*v1alpha1.ClusterMutationPolicy]()
+	clusterGenerationPolicyReg := policyStore.NewPolicyStore[*v1alpha1.ClusterGenerationPolicy]()
+	sourcesReg := sourcesRegistry.NewSourcesRegistry()
+	resourceObserverReg := resourceObserverRegistry.NewResourceObserverRegistry()
+	resourceInformerReg := resourceInformerRegistry.NewResourceInformerRegistry()
+	templateLibraryReg := templateLibraryRegistry.NewTemplateLibraryRegistry()
+	policyCountersReg := policyCountersRegistry.NewPolicyCountersRegistry()
+	var mutationConflictsReg *mutationConflictsRegistry.MutationConflictsRegistry
+
+	metricsServerOptions := metricsserver.Options{
+		BindAddress:   metricsAddr,
+		SecureServing: secureMetrics,
+		TLSOpts:       tlsOpts,
+	}
+
+	// Conflicts between mutation policies are only collected when they are reported.
+	// The report is served by the metrics server, so it's protected the same way as the metrics
+	if mutationConflictsReport {
+		mutationConflictsReg = mutationConflictsRegistry.NewMutationConflictsRegistry()
+		metricsServerOptions.ExtraHandlers = map[string]http.Handler{
+			admission.MutationConflictsReportPath: admission.NewMutationConflictsReportHandler(&admission.AdmissionServerDependencies{
+				ClusterMutationPolicyRegistry: clusterMutationPolicyReg,
+				MutationConflictsRegistry:     mutationConflictsReg,
+			}),
+		}
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme: scheme,
-		Metrics: metricsserver.Options{
-			BindAddress:   metricsAddr,
-			SecureServing: secureMetrics,
-			TLSOpts:       tlsOpts,
-		},
+		Scheme:                  scheme,
+		Metrics:                 metricsServerOptions,
 		WebhookServer:           webhookServer,
 		HealthProbeBindAddress:  probeAddr,
 		LeaderElection:          enableLeaderElection,
@@ -357,18 +471,34 @@ func main() {
 		admission.AdmissionServerValidationPath,
 		admission.AdmissionServerMutationPath)
 
-	// Create registries managers that will be used by several controllers
-	clusterValidationPolicyReg := policyStore.NewPolicyStore[*v1alpha1.ClusterValidationPolicy]()
-	clusterMutationPolicyReg := policyStore.NewPolicyStore[*v1alpha1.ClusterMutationPolicy]()
-	clusterGenerationPolicyReg := policyStore.NewPolicyStore[*v1alpha1.ClusterGenerationPolicy]()
-	sourcesReg := sourcesRegistry.NewSourcesRegistry()
-	resourceObserverReg := resourceObserverRegistry.NewResourceObserverRegistry()
-	resourceInformerReg := resourceInformerRegistry.NewResourceInformerRegistry()
+	// Template engines are called from several places, so libraries are reachable globally
+	globals.Application.TemplateLibraryRegistry = templateLibraryReg
+
+	// Policies referencing a library are reconciled again by their controllers when the library changes
+	clusterValidationPolicyEvents := make(chan event.GenericEvent)
+	clusterMutationPolicyEvents := make(chan event.GenericEvent)
+	clusterGenerationPolicyEvents := make(chan event.GenericEvent)
 
 	// Init internal registries controllers
 	// Following controllers manage internal registries for user-facing resources.
 	// IMPORTANT: All the replicas are able to process and leader is not chosen for this.
 	// TODO: Create a common controller to update all the registries at once (deduplicate code)
+	if err = (&templatelibrary.TemplateLibraryReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+
+		Options: templatelibrary.TemplateLibraryControllerOptions{},
+		Dependencies: templatelibrary.TemplateLibraryControllerDependencies{
+			TemplateLibraryRegistry:       templateLibraryReg,
+			ClusterValidationPolicyEvents: clusterValidationPolicyEvents,
+			ClusterMutationPolicyEvents:   clusterMutationPolicyEvents,
+			ClusterGenerationPolicyEvents: clusterGenerationPolicyEvents,
+		},
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "TemplateLibrary")
+		os.Exit(1)
+	}
+
 	if err = (&clustergenerationpolicy.ClusterGenerationPolicyReconciler{
 		Client: mgr.GetClient(),
 		Scheme: mgr.GetScheme(),
@@ -376,6 +506,7 @@ func main() {
 		Options: clustergenerationpolicy.ClusterGenerationPolicyControllerOptions{},
 		Dependencies: clustergenerationpolicy.ClusterGenerationPolicyControllerDependencies{
 			ClusterGenerationPolicyRegistry: clusterGenerationPolicyReg,
+			TemplateLibraryEvents:           clusterGenerationPolicyEvents,
 		},
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "ClusterGenerationPolicy")
@@ -397,6 +528,8 @@ func main() {
 		},
 		Dependencies: clustermutationpolicy.ClusterMutationPolicyControllerDependencies{
 			ClusterMutationPolicyRegistry: clusterMutationPolicyReg,
+			Evaluator:                     policyEvaluator,
+			TemplateLibraryEvents:         clusterMutationPolicyEvents,
 		},
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "ClusterMutationPolicy")
@@ -412,12 +545,15 @@ func main() {
 			EnableSpecialLabels:           enableSpecialLabels,
 			ExcludeAdmissionSelfNamespace: excludeAdmissionSelfNamespace,
 			ExcludedAdmissionNamespaces:   excludedAdmissionNamespaces,
+			EnableNativeTranslation:       enableNativeTranslation,
 
 			WebhookClientConfig: webhookClientConfigValidation,
 			WebhookTimeout:      webhooksClientTimeout,
 		},
 		Dependencies: clustervalidationpolicy.ClusterValidationPolicyControllerDependencies{
 			ClusterValidationPolicyRegistry: clusterValidationPolicyReg,
+			Evaluator:                       policyEvaluator,
+			TemplateLibraryEvents:           clusterValidationPolicyEvents,
 		},
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "ClusterValidationPolicy")
@@ -441,6 +577,7 @@ func main() {
 			SourcesRegistry:                 sourcesReg,
 			ResourceInformerRegistry:        resourceInformerReg,
 			ResourceObserverRegistry:        resourceObserverReg,
+			PolicyCountersRegistry:          policyCountersReg,
 		},
 	}
 	if err = mgr.Add(&observedResourceController); err != nil {
@@ -483,18 +620,47 @@ func main() {
 			//
 			TLSCertificate: webhooksServerCertificate,
 			TLSPrivateKey:  webhooksServerPrivateKey,
+
+			//
+			MutationValidateResult:   mutationValidateResult,
+			MutationRecordAnnotation: mutationRecordAnnotation,
 		},
 		admission.AdmissionServerDependencies{
 			Context:                         &globals.Application.Context,
 			SourcesRegistry:                 sourcesReg,
 			ClusterValidationPolicyRegistry: clusterValidationPolicyReg,
 			ClusterMutationPolicyRegistry:   clusterMutationPolicyReg,
+			PolicyCountersRegistry:          policyCountersReg,
+			MutationConflictsRegistry:       mutationConflictsReg,
+			Evaluator:                       policyEvaluator,
 		})
 	if err = mgr.Add(admissionServer); err != nil {
 		setupLog.Error(err, "failed adding admission server controller to manager")
 		os.Exit(1)
 	}
 
+	// Init PolicyStatusController.
+	// This controller periodically writes the runtime status of the policies: evaluation counters
+	// collected by the rest of controllers, and the state of the informers behind their sources.
+	// IMPORTANT: All the replicas are able to process and leader is not chosen for this.
+	// All of them add their counters, but only the leader writes the state of the sources
+	policyStatusController := policystatus.PolicyStatusController{
+		Client: mgr.GetClient(),
+		Options: policystatus.PolicyStatusControllerOptions{
+			FlushInterval: policyStatusFlushInterval,
+		},
+		Dependencies: policystatus.PolicyStatusControllerDependencies{
+			Context:                &globals.Application.Context,
+			Elected:                mgr.Elected(),
+			PolicyCountersRegistry: policyCountersReg,
+			SourcesRegistry:        sourcesReg,
+		},
+	}
+	if err = mgr.Add(&policyStatusController); err != nil {
+		setupLog.Error(err, "failed adding policy status controller to manager")
+		os.Exit(1)
+	}
+
 	//
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
@@ -0,0 +1,120 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package accessreview
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	//
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// callTimeout is the max time waited for Kubernetes on each review.
+	// It's kept low as reviews are done while the admission request is waiting
+	callTimeout = 3 * time.Second
+)
+
+// NewAccessReviewClient return a new AccessReviewClient
+func NewAccessReviewClient(ctx context.Context, client kubernetes.Interface) *AccessReviewClient {
+	return &AccessReviewClient{
+		ctx:    ctx,
+		client: client,
+	}
+}
+
+// Can issues a SubjectAccessReview to know whether the subject is allowed to perform an action.
+// Results are stored in the given cache, so the same question is asked only once per request
+func (c *AccessReviewClient) Can(cache *ResultCacheT, subject SubjectT, attributes ResourceAttributesT) (allowed bool, err error) {
+
+	if cache == nil {
+		cache = &ResultCacheT{}
+	}
+
+	cacheKey := getCacheKey(subject, attributes)
+	if allowed, found := cache.get(cacheKey); found {
+		return allowed, nil
+	}
+
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   subject.User,
+			UID:    subject.UID,
+			Groups: subject.Groups,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Verb:      attributes.Verb,
+				Group:     attributes.Group,
+				Resource:  attributes.Resource,
+				Namespace: attributes.Namespace,
+				Name:      attributes.Name,
+			},
+		},
+	}
+
+	if len(subject.Extra) > 0 {
+		review.Spec.Extra = make(map[string]authorizationv1.ExtraValue, len(subject.Extra))
+		for key, values := range subject.Extra {
+			review.Spec.Extra[key] = values
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.ctx, callTimeout)
+	defer cancel()
+
+	review, err = c.client.AuthorizationV1().SubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+	if err != nil {
+		return false, fmt.Errorf("failed creating SubjectAccessReview: %s", err.Error())
+	}
+
+	cache.set(cacheKey, review.Status.Allowed)
+	return review.Status.Allowed, nil
+}
+
+// getCacheKey return a key that identifies the question done to the authorizer
+func getCacheKey(subject SubjectT, attributes ResourceAttributesT) string {
+	groups := slices.Clone(subject.Groups)
+	slices.Sort(groups)
+
+	return strings.Join([]string{
+		subject.User, subject.UID, strings.Join(groups, ","),
+		attributes.Verb, attributes.Group, attributes.Resource, attributes.Namespace, attributes.Name,
+	}, "/")
+}
+
+func (rc *ResultCacheT) get(key string) (allowed bool, found bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	allowed, found = rc.results[key]
+	return allowed, found
+}
+
+func (rc *ResultCacheT) set(key string, allowed bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if rc.results == nil {
+		rc.results = make(map[string]bool)
+	}
+	rc.results[key] = allowed
+}
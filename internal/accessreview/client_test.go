@@ -0,0 +1,130 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package accessreview
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	//
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+// getTestAccessReviewClient return a client whose authorizer allows everything to user 'alice'.
+// Reviews received by the authorizer are appended to the given list
+func getTestAccessReviewClient(reviews *[]authorizationv1.SubjectAccessReview, reviewErr error) *AccessReviewClient {
+	clientset := fake.NewClientset()
+	clientset.PrependReactor("create", "subjectaccessreviews",
+		func(action clienttesting.Action) (bool, runtime.Object, error) {
+			review := action.(clienttesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+			*reviews = append(*reviews, *review)
+
+			if reviewErr != nil {
+				return true, nil, reviewErr
+			}
+
+			review = review.DeepCopy()
+			review.Status.Allowed = review.Spec.User == "alice"
+			return true, review, nil
+		})
+
+	return NewAccessReviewClient(context.Background(), clientset)
+}
+
+func TestCan(t *testing.T) {
+	attributes := ResourceAttributesT{Verb: "create", Resource: "pods", Namespace: "team-a"}
+
+	t.Run("authorizer decision is returned", func(t *testing.T) {
+		var reviews []authorizationv1.SubjectAccessReview
+		client := getTestAccessReviewClient(&reviews, nil)
+
+		allowed, err := client.Can(&ResultCacheT{}, SubjectT{User: "alice"}, attributes)
+		if err != nil || !allowed {
+			t.Errorf("expected 'alice' to be allowed, got %v, %v", allowed, err)
+		}
+
+		allowed, err = client.Can(&ResultCacheT{}, SubjectT{User: "bob"}, attributes)
+		if err != nil || allowed {
+			t.Errorf("expected 'bob' not to be allowed, got %v, %v", allowed, err)
+		}
+	})
+
+	t.Run("subject and attributes are sent to the authorizer", func(t *testing.T) {
+		var reviews []authorizationv1.SubjectAccessReview
+		client := getTestAccessReviewClient(&reviews, nil)
+
+		subject := SubjectT{User: "alice", UID: "1234", Groups: []string{"devs"}, Extra: map[string][]string{"scopes": {"a"}}}
+		_, _ = client.Can(nil, subject, attributes)
+
+		if len(reviews) != 1 {
+			t.Fatalf("expected a single review, got %d", len(reviews))
+		}
+		spec := reviews[0].Spec
+		if spec.User != "alice" || spec.UID != "1234" || strings.Join(spec.Groups, ",") != "devs" ||
+			strings.Join(spec.Extra["scopes"], ",") != "a" {
+			t.Errorf("unexpected subject in review: %+v", spec)
+		}
+		if *spec.ResourceAttributes != (authorizationv1.ResourceAttributes{Verb: "create", Resource: "pods", Namespace: "team-a"}) {
+			t.Errorf("unexpected attributes in review: %+v", spec.ResourceAttributes)
+		}
+	})
+
+	t.Run("same question is asked once per request", func(t *testing.T) {
+		var reviews []authorizationv1.SubjectAccessReview
+		client := getTestAccessReviewClient(&reviews, nil)
+
+		cache := &ResultCacheT{}
+		_, _ = client.Can(cache, SubjectT{User: "alice", Groups: []string{"a", "b"}}, attributes)
+		_, _ = client.Can(cache, SubjectT{User: "alice", Groups: []string{"b", "a"}}, attributes)
+		if len(reviews) != 1 {
+			t.Errorf("expected a single review for the same question, got %d", len(reviews))
+		}
+
+		_, _ = client.Can(cache, SubjectT{User: "alice", Groups: []string{"a", "b"}},
+			ResourceAttributesT{Verb: "delete", Resource: "pods", Namespace: "team-a"})
+		if len(reviews) != 2 {
+			t.Errorf("expected another review for a different question, got %d", len(reviews))
+		}
+
+		// Requests don't share their results
+		_, _ = client.Can(&ResultCacheT{}, SubjectT{User: "alice", Groups: []string{"a", "b"}}, attributes)
+		if len(reviews) != 3 {
+			t.Errorf("expected another review for a different request, got %d", len(reviews))
+		}
+	})
+
+	t.Run("failed reviews are reported and not cached", func(t *testing.T) {
+		var reviews []authorizationv1.SubjectAccessReview
+		client := getTestAccessReviewClient(&reviews, errors.New("authorizer unavailable"))
+
+		cache := &ResultCacheT{}
+		for range 2 {
+			allowed, err := client.Can(cache, SubjectT{User: "alice"}, attributes)
+			if allowed || err == nil || !strings.Contains(err.Error(), "failed creating SubjectAccessReview: authorizer unavailable") {
+				t.Fatalf("expected error about the review, got %v, %v", allowed, err)
+			}
+		}
+		if len(reviews) != 2 {
+			t.Errorf("expected failed reviews to be asked again, got %d", len(reviews))
+		}
+	})
+}
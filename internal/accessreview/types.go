@@ -0,0 +1,55 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package accessreview
+
+import (
+	"context"
+	"sync"
+
+	//
+	"k8s.io/client-go/kubernetes"
+)
+
+// AccessReviewClient asks Kubernetes authorizer whether a user can perform an action
+type AccessReviewClient struct {
+	ctx    context.Context
+	client kubernetes.Interface
+}
+
+// SubjectT represents the user whose permissions are reviewed
+type SubjectT struct {
+	User   string
+	UID    string
+	Groups []string
+	Extra  map[string][]string
+}
+
+// ResourceAttributesT represents the action that is reviewed
+type ResourceAttributesT struct {
+	Verb      string
+	Group     string
+	Resource  string
+	Namespace string
+	Name      string
+}
+
+// ResultCacheT stores the results of the reviews done during the evaluation of a request.
+// It's shared by all the templates evaluated for the same request
+type ResultCacheT struct {
+	mu      sync.Mutex
+	results map[string]bool
+}
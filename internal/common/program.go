@@ -0,0 +1,122 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	//
+	"sigs.k8s.io/yaml"
+
+	//
+	"github.com/freepik-company/admitik/api/v1alpha1"
+	"github.com/freepik-company/admitik/internal/template"
+)
+
+// ProgramDecisionT represents the decision returned by a policy program
+type ProgramDecisionT struct {
+	// Allowed is true when omitted, so programs only returning warnings or patches are shorter
+	Allowed  *bool    `json:"allowed,omitempty"`
+	Message  string   `json:"message,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+
+	// Patch can be a string, as the templates in 'patch', or a structured object.
+	// When the type is omitted, lists are considered 'jsonpatch' and the rest 'jsonmerge'
+	Patch     any    `json:"patch,omitempty"`
+	PatchType string `json:"patchType,omitempty"`
+
+	Vars map[string]any `json:"vars,omitempty"`
+}
+
+// IsAllowed returns whether the decision allows the object
+func (d *ProgramDecisionT) IsAllowed() bool {
+	return d.Allowed == nil || *d.Allowed
+}
+
+// GetPatch returns the patch type and the patch ready to be applied.
+// Empty patch is returned when the program did not return any
+func (d *ProgramDecisionT) GetPatch() (patchType string, patch []byte, err error) {
+	patchType = strings.ToLower(d.PatchType)
+
+	switch typedPatch := d.Patch.(type) {
+	case nil:
+		return patchType, nil, nil
+	case string:
+		patch = []byte(typedPatch)
+	default:
+		patch, err = json.Marshal(typedPatch)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed encoding patch: %s", err.Error())
+		}
+	}
+
+	if patchType == "" {
+		patchType = v1alpha1.MutationPatchTypeMerge
+		if _, isList := d.Patch.([]any); isList {
+			patchType = v1alpha1.MutationPatchTypeJson
+		}
+	}
+
+	return patchType, patch, nil
+}
+
+// EvaluateProgram evaluates a policy program and returns its decision.
+// CEL programs return the decision as a map, the rest of engines print it as JSON or YAML.
+// Vars returned by the program are stored into 'vars', so later templates can use them
+func EvaluateProgram(program *v1alpha1.ProgramT, injectedData *template.PolicyEvaluationDataT) (decision *ProgramDecisionT, err error) {
+
+	var programOutput any
+
+	switch program.Engine {
+	case template.EngineCel, "":
+		programOutput, err = template.EvaluateExpressionCel(program.Template, injectedData)
+		if err != nil {
+			return nil, fmt.Errorf("failed program: %s", err.Error())
+		}
+	default:
+		var parsedProgram string
+		parsedProgram, err = template.EvaluateTemplate(program.Engine, program.Template, injectedData)
+		if err != nil {
+			return nil, fmt.Errorf("failed program: %s", err.Error())
+		}
+
+		err = yaml.Unmarshal([]byte(parsedProgram), &programOutput)
+		if err != nil {
+			return nil, fmt.Errorf("failed decoding program output: %s", err.Error())
+		}
+	}
+
+	// Round trip through JSON to get a typed decision, rejecting unknown fields to spot typos early
+	programOutputBytes, err := json.Marshal(programOutput)
+	if err != nil {
+		return nil, fmt.Errorf("failed encoding program output: %s", err.Error())
+	}
+
+	decision = &ProgramDecisionT{}
+	err = yaml.UnmarshalStrict(programOutputBytes, decision)
+	if err != nil {
+		return nil, fmt.Errorf("program output is not a valid decision: %s", err.Error())
+	}
+
+	for key, value := range decision.Vars {
+		injectedData.SetVar(key, value)
+	}
+
+	return decision, nil
+}
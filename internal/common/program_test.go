@@ -0,0 +1,207 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"strings"
+	"testing"
+
+	//
+	"github.com/freepik-company/admitik/api/v1alpha1"
+	"github.com/freepik-company/admitik/internal/template"
+)
+
+func TestEvaluateProgram(t *testing.T) {
+	tests := []struct {
+		name    string
+		program v1alpha1.ProgramT
+
+		expectedAllowed  bool
+		expectedMessage  string
+		expectedWarnings []string
+		expectedVars     map[string]any
+
+		// expectedErr is a part of the expected error message. Empty when no error is expected
+		expectedErr string
+	}{
+		{
+			name: "CEL program returns a map",
+			program: v1alpha1.ProgramT{
+				Engine:   "cel",
+				Template: `{"allowed": false, "message": "name is " + object.metadata.name, "warnings": ["first"]}`,
+			},
+			expectedAllowed:  false,
+			expectedMessage:  "name is example",
+			expectedWarnings: []string{"first"},
+		},
+		{
+			name:            "engine defaults to CEL",
+			program:         v1alpha1.ProgramT{Template: `{"allowed": false}`},
+			expectedAllowed: false,
+		},
+		{
+			name:            "missing allowed means allowed",
+			program:         v1alpha1.ProgramT{Engine: "cel", Template: `{"warnings": ["only a warning"]}`},
+			expectedAllowed: true,
+			expectedWarnings: []string{
+				"only a warning",
+			},
+		},
+		{
+			name: "gotmpl program prints JSON",
+			program: v1alpha1.ProgramT{
+				Engine:   "gotmpl",
+				Template: `{"allowed": false, "message": "{{ .object.metadata.name }} is not allowed"}`,
+			},
+			expectedAllowed: false,
+			expectedMessage: "example is not allowed",
+		},
+		{
+			name: "starlark program prints YAML",
+			program: v1alpha1.ProgramT{
+				Engine:   "starlark",
+				Template: "print('allowed: true\\nvars:\\n  owner: ' + object['metadata']['name'])",
+			},
+			expectedAllowed: true,
+			expectedVars:    map[string]any{"owner": "example"},
+		},
+		{
+			name:        "failed CEL program",
+			program:     v1alpha1.ProgramT{Engine: "cel", Template: `object.missing.field`},
+			expectedErr: "failed program",
+		},
+		{
+			name:        "failed template program",
+			program:     v1alpha1.ProgramT{Engine: "gotmpl", Template: `{{ .broken `},
+			expectedErr: "failed program",
+		},
+		{
+			name:        "malformed output",
+			program:     v1alpha1.ProgramT{Engine: "gotmpl", Template: `{"allowed": [`},
+			expectedErr: "failed decoding program output",
+		},
+		{
+			name:        "unknown fields are rejected",
+			program:     v1alpha1.ProgramT{Engine: "cel", Template: `{"alowed": false}`},
+			expectedErr: "program output is not a valid decision",
+		},
+		{
+			name:        "fields with wrong types are rejected",
+			program:     v1alpha1.ProgramT{Engine: "gotmpl", Template: `{"allowed": "no"}`},
+			expectedErr: "program output is not a valid decision",
+		},
+		{
+			name:        "outputs other than objects are rejected",
+			program:     v1alpha1.ProgramT{Engine: "cel", Template: `["allowed"]`},
+			expectedErr: "program output is not a valid decision",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			injectedData := &template.PolicyEvaluationDataT{}
+			injectedData.Initialize()
+			injectedData.Object = map[string]any{"metadata": map[string]any{"name": "example"}}
+
+			decision, err := EvaluateProgram(&test.program, injectedData)
+
+			if test.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.expectedErr) {
+					t.Fatalf("expected error containing '%s', got: %v", test.expectedErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if decision.IsAllowed() != test.expectedAllowed {
+				t.Errorf("expected allowed to be %v, got %v", test.expectedAllowed, decision.IsAllowed())
+			}
+			if decision.Message != test.expectedMessage {
+				t.Errorf("expected message '%s', got '%s'", test.expectedMessage, decision.Message)
+			}
+			if strings.Join(decision.Warnings, ",") != strings.Join(test.expectedWarnings, ",") {
+				t.Errorf("expected warnings %v, got %v", test.expectedWarnings, decision.Warnings)
+			}
+			for key, value := range test.expectedVars {
+				if injectedData.Vars[key] != value {
+					t.Errorf("expected var '%s' to be '%v', got '%v'", key, value, injectedData.Vars[key])
+				}
+			}
+		})
+	}
+}
+
+func TestProgramDecisionGetPatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		decision ProgramDecisionT
+
+		expectedPatchType string
+		expectedPatch     string
+	}{
+		{
+			name:     "no patch",
+			decision: ProgramDecisionT{},
+		},
+		{
+			name:              "string patch keeps the declared type",
+			decision:          ProgramDecisionT{Patch: `{"metadata":{"labels":{"a":"b"}}}`, PatchType: "StrategicMerge"},
+			expectedPatchType: "strategicmerge",
+			expectedPatch:     `{"metadata":{"labels":{"a":"b"}}}`,
+		},
+		{
+			name:              "structured map defaults to jsonmerge",
+			decision:          ProgramDecisionT{Patch: map[string]any{"metadata": map[string]any{"labels": map[string]any{"a": "b"}}}},
+			expectedPatchType: v1alpha1.MutationPatchTypeMerge,
+			expectedPatch:     `{"metadata":{"labels":{"a":"b"}}}`,
+		},
+		{
+			name: "structured list defaults to jsonpatch",
+			decision: ProgramDecisionT{Patch: []any{
+				map[string]any{"op": "remove", "path": "/metadata/labels/a"},
+			}},
+			expectedPatchType: v1alpha1.MutationPatchTypeJson,
+			expectedPatch:     `[{"op":"remove","path":"/metadata/labels/a"}]`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			patchType, patch, err := test.decision.GetPatch()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if test.expectedPatch == "" {
+				if patch != nil {
+					t.Errorf("expected no patch, got '%s'", string(patch))
+				}
+				return
+			}
+
+			if patchType != test.expectedPatchType {
+				t.Errorf("expected patch type '%s', got '%s'", test.expectedPatchType, patchType)
+			}
+			if string(patch) != test.expectedPatch {
+				t.Errorf("expected patch '%s', got '%s'", test.expectedPatch, string(patch))
+			}
+		})
+	}
+}
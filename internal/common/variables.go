@@ -0,0 +1,50 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+
+	//
+	"github.com/freepik-company/admitik/api/v1alpha1"
+	"github.com/freepik-company/admitik/internal/template"
+)
+
+// PopulateVariables evaluates a list of templated variables in order, storing each result into 'vars'.
+// This way, later variables, conditions and templates can use them without computing them again.
+// CEL results are stored as typed values (lists, maps, numbers...), the rest of engines store strings
+func PopulateVariables(variableList []v1alpha1.VariableT, injectedData *template.PolicyEvaluationDataT) (err error) {
+	for _, variable := range variableList {
+
+		var value any
+
+		switch variable.Engine {
+		case template.EngineCel, "":
+			value, err = template.EvaluateExpressionCel(variable.Template, injectedData)
+		default:
+			value, err = template.EvaluateTemplate(variable.Engine, variable.Template, injectedData)
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed variable '%s': %s", variable.Name, err.Error())
+		}
+
+		injectedData.SetVar(variable.Name, value)
+	}
+
+	return nil
+}
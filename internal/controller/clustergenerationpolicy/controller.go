@@ -28,8 +28,11 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	controllerRuntimeController "sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	//
 	"github.com/freepik-company/admitik/api/v1alpha1"
@@ -42,6 +45,9 @@ type ClusterGenerationPolicyControllerOptions struct {
 
 type ClusterGenerationPolicyControllerDependencies struct {
 	ClusterGenerationPolicyRegistry *policyStore.PolicyStore[*v1alpha1.ClusterGenerationPolicy]
+
+	// TemplateLibraryEvents carries the policies to reconcile when their libraries change
+	TemplateLibraryEvents <-chan event.GenericEvent
 }
 
 // ClusterGenerationPolicyReconciler reconciles a ClusterGenerationPolicy object
@@ -122,15 +128,34 @@ func (r *ClusterGenerationPolicyReconciler) Reconcile(ctx context.Context, req c
 
 	// 5. Update the status before the requeue
 	defer func() {
-		err = controller.UpdateWithRetry(ctx, r.Client, objectManifest, func(object client.Object) error {
+		desiredStatus := objectManifest.Status
+		desiredStatus.ObservedGeneration = objectManifest.Generation
+
+		statusErr := controller.UpdateStatusWithRetry(ctx, r.Client, objectManifest, func(object client.Object) error {
+			// Runtime status is owned by the status controller, so it's kept as it is
+			desiredStatus.PolicyRuntimeStatusT = object.(*v1alpha1.ClusterGenerationPolicy).Status.PolicyRuntimeStatusT
+			object.(*v1alpha1.ClusterGenerationPolicy).Status = desiredStatus
 			return nil
 		})
-		if err != nil {
-			logger.Info(fmt.Sprintf(controller.ResourceConditionUpdateError, controller.ClusterGenerationPolicyResourceType, req.Name, err.Error()))
+		if statusErr != nil {
+			logger.Info(fmt.Sprintf(controller.ResourceConditionUpdateError, controller.ClusterGenerationPolicyResourceType, req.Name, statusErr.Error()))
 		}
 	}()
 
-	// 6. The resource already exists: manage the update
+	// 6. Check the policy before serving it. Invalid policies are removed from the registry
+	err = checkClusterGenerationPolicy(objectManifest)
+	if err != nil {
+		r.UpdateConditionInvalidPolicy(objectManifest, err)
+		logger.Info(fmt.Sprintf(controller.ResourceReconcileError, controller.ClusterGenerationPolicyResourceType, req.Name, err.Error()))
+
+		err = r.ReconcileClusterGenerationPolicy(ctx, watch.Deleted, objectManifest)
+		if err != nil {
+			logger.Info(fmt.Sprintf(controller.ResourceReconcileError, controller.ClusterGenerationPolicyResourceType, req.Name, err.Error()))
+		}
+		return result, err
+	}
+
+	// 7. The resource already exists: manage the update
 	err = r.ReconcileClusterGenerationPolicy(ctx, watch.Modified, objectManifest)
 	if err != nil {
 		r.UpdateConditionKubernetesApiCallFailure(objectManifest)
@@ -138,7 +163,7 @@ func (r *ClusterGenerationPolicyReconciler) Reconcile(ctx context.Context, req c
 		return result, err
 	}
 
-	// 7. Success, update the status
+	// 8. Success, update the status
 	r.UpdateConditionSuccess(objectManifest)
 
 	return result, err
@@ -148,6 +173,9 @@ func (r *ClusterGenerationPolicyReconciler) Reconcile(ctx context.Context, req c
 func (r *ClusterGenerationPolicyReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&v1alpha1.ClusterGenerationPolicy{}).
+		// Policies are re-evaluated when the libraries they reference change.
+		// TemplateLibrary controller sends them once its registry is up-to-date
+		WatchesRawSource(source.Channel(r.Dependencies.TemplateLibraryEvents, &handler.EnqueueRequestForObject{})).
 		WithEventFilter(predicate.GenerationChangedPredicate{}).
 		WithOptions(controllerRuntimeController.Options{
 			NeedLeaderElection: pointer.Bool(false),
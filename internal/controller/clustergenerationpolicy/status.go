@@ -17,6 +17,9 @@ limitations under the License.
 package clustergenerationpolicy
 
 import (
+	"fmt"
+
+	//
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	//
@@ -41,3 +44,12 @@ func (r *ClusterGenerationPolicyReconciler) UpdateConditionKubernetesApiCallFail
 
 	controller.UpdateCondition(&cPolicy.Status.Conditions, condition)
 }
+
+func (r *ClusterGenerationPolicyReconciler) UpdateConditionInvalidPolicy(cPolicy *v1alpha1.ClusterGenerationPolicy, err error) {
+
+	//
+	condition := controller.NewCondition(controller.ConditionTypeResourceSynced, metav1.ConditionFalse,
+		controller.ConditionReasonInvalidPolicyType, fmt.Sprintf(controller.ConditionReasonInvalidPolicyMessage, err.Error()))
+
+	controller.UpdateCondition(&cPolicy.Status.Conditions, condition)
+}
@@ -0,0 +1,40 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clustergenerationpolicy
+
+import (
+	"errors"
+
+	//
+	"github.com/freepik-company/admitik/api/v1alpha1"
+	"github.com/freepik-company/admitik/internal/controller"
+)
+
+// checkClusterGenerationPolicy validates the fields of a policy that can not be validated by the CRD,
+// such as templates, returning an error describing the broken ones
+func checkClusterGenerationPolicy(resourceManifest *v1alpha1.ClusterGenerationPolicy) error {
+	var errorList []error
+
+	errorList = append(errorList, controller.CheckSources(resourceManifest.Spec.Sources)...)
+	errorList = append(errorList, controller.CheckVariables(resourceManifest.Spec.Variables)...)
+	errorList = append(errorList, controller.CheckConditions(resourceManifest.Spec.Conditions)...)
+
+	errorList = append(errorList, controller.CheckTemplate("object definition",
+		resourceManifest.Spec.Object.Definition.Engine, resourceManifest.Spec.Object.Definition.Template))
+
+	return errors.Join(errorList...)
+}
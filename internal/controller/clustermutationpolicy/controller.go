@@ -29,12 +29,16 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	controllerRuntimeController "sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	//
 	"github.com/freepik-company/admitik/api/v1alpha1"
 	"github.com/freepik-company/admitik/internal/controller"
+	"github.com/freepik-company/admitik/internal/evaluator"
 	policyStore "github.com/freepik-company/admitik/internal/registry/policystore"
 )
 
@@ -50,6 +54,10 @@ type ClusterMutationPolicyControllerOptions struct {
 
 type ClusterMutationPolicyControllerDependencies struct {
 	ClusterMutationPolicyRegistry *policyStore.PolicyStore[*v1alpha1.ClusterMutationPolicy]
+	Evaluator                     *evaluator.Evaluator
+
+	// TemplateLibraryEvents carries the policies to reconcile when their libraries change
+	TemplateLibraryEvents <-chan event.GenericEvent
 }
 
 // ClusterMutationPolicyReconciler reconciles a ClusterMutationPolicy object
@@ -130,15 +138,46 @@ func (r *ClusterMutationPolicyReconciler) Reconcile(ctx context.Context, req ctr
 
 	// 5. Update the status before the requeue
 	defer func() {
-		err = controller.UpdateWithRetry(ctx, r.Client, objectManifest, func(object client.Object) error {
+		desiredStatus := objectManifest.Status
+		desiredStatus.ObservedGeneration = objectManifest.Generation
+
+		statusErr := controller.UpdateStatusWithRetry(ctx, r.Client, objectManifest, func(object client.Object) error {
+			// Runtime status is owned by the status controller, so it's kept as it is
+			desiredStatus.PolicyRuntimeStatusT = object.(*v1alpha1.ClusterMutationPolicy).Status.PolicyRuntimeStatusT
+			object.(*v1alpha1.ClusterMutationPolicy).Status = desiredStatus
 			return nil
 		})
-		if err != nil {
-			logger.Info(fmt.Sprintf(controller.ResourceConditionUpdateError, controller.ClusterMutationPolicyResourceType, req.Name, err.Error()))
+		if statusErr != nil {
+			logger.Info(fmt.Sprintf(controller.ResourceConditionUpdateError, controller.ClusterMutationPolicyResourceType, req.Name, statusErr.Error()))
 		}
 	}()
 
-	// 6. The resource already exists: manage the update
+	// 6. Check the policy before serving it. Invalid policies are removed from the registry
+	err = checkClusterMutationPolicy(objectManifest)
+	if err != nil {
+		r.UpdateConditionInvalidPolicy(objectManifest, err)
+		logger.Info(fmt.Sprintf(controller.ResourceReconcileError, controller.ClusterMutationPolicyResourceType, req.Name, err.Error()))
+
+		err = r.ReconcileClusterMutationPolicy(ctx, watch.Deleted, objectManifest)
+		if err != nil {
+			logger.Info(fmt.Sprintf(controller.ResourceReconcileError, controller.ClusterMutationPolicyResourceType, req.Name, err.Error()))
+		}
+		return result, err
+	}
+
+	// 7. Run the tests before serving the policy. On failures, the previous version is kept in the registry.
+	// The registry is in memory, so policies failing their tests are not served after a restart
+	objectManifest.Status.TestResults = r.Dependencies.Evaluator.RunMutationPolicyTests(objectManifest)
+	err = controller.CheckTestResults(objectManifest.Status.TestResults)
+	if err != nil {
+		r.UpdateConditionTestsFailed(objectManifest, err)
+		logger.Info(fmt.Sprintf(controller.ResourceReconcileError, controller.ClusterMutationPolicyResourceType, req.Name, err.Error()))
+
+		// Tests only depend on the policy and its libraries, and changes on them trigger a new reconciliation
+		return result, nil
+	}
+
+	// 8. The resource already exists: manage the update
 	err = r.ReconcileClusterMutationPolicy(ctx, watch.Modified, objectManifest)
 	if err != nil {
 		r.UpdateConditionKubernetesApiCallFailure(objectManifest)
@@ -146,7 +185,7 @@ func (r *ClusterMutationPolicyReconciler) Reconcile(ctx context.Context, req ctr
 		return result, err
 	}
 
-	// 7. Success, update the status
+	// 9. Success, update the status
 	r.UpdateConditionSuccess(objectManifest)
 
 	return result, err
@@ -156,6 +195,9 @@ func (r *ClusterMutationPolicyReconciler) Reconcile(ctx context.Context, req ctr
 func (r *ClusterMutationPolicyReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&v1alpha1.ClusterMutationPolicy{}).
+		// Policies are re-evaluated when the libraries they reference change.
+		// TemplateLibrary controller sends them once its registry is up-to-date
+		WatchesRawSource(source.Channel(r.Dependencies.TemplateLibraryEvents, &handler.EnqueueRequestForObject{})).
 		WithEventFilter(predicate.GenerationChangedPredicate{}).
 		WithOptions(controllerRuntimeController.Options{
 			NeedLeaderElection: pointer.Bool(false),
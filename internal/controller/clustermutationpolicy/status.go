@@ -17,6 +17,9 @@ limitations under the License.
 package clustermutationpolicy
 
 import (
+	"fmt"
+
+	//
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	//
@@ -41,3 +44,21 @@ func (r *ClusterMutationPolicyReconciler) UpdateConditionKubernetesApiCallFailur
 
 	controller.UpdateCondition(&cmPolicy.Status.Conditions, condition)
 }
+
+func (r *ClusterMutationPolicyReconciler) UpdateConditionInvalidPolicy(cmPolicy *v1alpha1.ClusterMutationPolicy, err error) {
+
+	//
+	condition := controller.NewCondition(controller.ConditionTypeResourceSynced, metav1.ConditionFalse,
+		controller.ConditionReasonInvalidPolicyType, fmt.Sprintf(controller.ConditionReasonInvalidPolicyMessage, err.Error()))
+
+	controller.UpdateCondition(&cmPolicy.Status.Conditions, condition)
+}
+
+func (r *ClusterMutationPolicyReconciler) UpdateConditionTestsFailed(cmPolicy *v1alpha1.ClusterMutationPolicy, err error) {
+
+	//
+	condition := controller.NewCondition(controller.ConditionTypeResourceSynced, metav1.ConditionFalse,
+		controller.ConditionReasonTestsFailedType, fmt.Sprintf(controller.ConditionReasonTestsFailedMessage, err.Error()))
+
+	controller.UpdateCondition(&cmPolicy.Status.Conditions, condition)
+}
@@ -148,6 +148,13 @@ func (r *ClusterMutationPolicyReconciler) ReconcileClusterMutationPolicy(ctx con
 		}
 	}
 
+	// Report the registration of the policy in the status
+	resourceManifest.Status.Webhook = &v1alpha1.PolicyWebhookStatusT{
+		Registered:    len(desiredWatchedTypes) > 0,
+		Configuration: MutatingWebhookConfigurationName,
+		Rules:         desiredWatchedTypes,
+	}
+
 	return nil
 }
 
@@ -158,6 +165,7 @@ func (r *ClusterMutationPolicyReconciler) getMergedMutatingWebhookConfiguration(
 
 	// Craft ValidatingWebhookConfiguration rules based on the pool keys
 	currentVwcRules := []admissionregv1.RuleWithOperations{}
+	reinvocationPolicy := admissionregv1.NeverReinvocationPolicy
 	InterceptedResourcesPatterns := r.Dependencies.ClusterMutationPolicyRegistry.GetCollectionNames()
 
 	for _, resourcePattern := range InterceptedResourcesPatterns {
@@ -178,6 +186,13 @@ func (r *ClusterMutationPolicyReconciler) getMergedMutatingWebhookConfiguration(
 			Operations: []admissionregv1.OperationType{admissionregv1.OperationType(resourcePatternParts[3])},
 		}
 		currentVwcRules = append(currentVwcRules, tmpRule)
+
+		// Webhook is reinvoked for all the policies, so it's enough with one policy needing it
+		for _, policy := range r.Dependencies.ClusterMutationPolicyRegistry.GetResources(resourcePattern) {
+			if strings.EqualFold(policy.Spec.ReinvocationPolicy, v1alpha1.MutationReinvocationPolicyIfNeeded) {
+				reinvocationPolicy = admissionregv1.IfNeededReinvocationPolicy
+			}
+		}
 	}
 
 	// Obtain potential existing ValidatingWebhookConfiguration
@@ -208,6 +223,7 @@ func (r *ClusterMutationPolicyReconciler) getMergedMutatingWebhookConfiguration(
 	tmpWebhookObj.ClientConfig = r.Options.WebhookClientConfig
 	tmpWebhookObj.Rules = currentVwcRules
 	tmpWebhookObj.TimeoutSeconds = &timeoutSecondsConverted
+	tmpWebhookObj.ReinvocationPolicy = &reinvocationPolicy
 
 	// Ignore sensitive namespaces to avoid breaking Kubernetes essential services or chicken-egg scenarios
 	selectedNamespaces := []string{}
@@ -0,0 +1,103 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clustermutationpolicy
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	//
+	"github.com/freepik-company/admitik/api/v1alpha1"
+	"github.com/freepik-company/admitik/internal/controller"
+	"github.com/freepik-company/admitik/internal/template"
+)
+
+// checkClusterMutationPolicy validates the fields of a policy that can not be validated by the CRD,
+// such as templates or enums, returning an error describing the broken ones
+func checkClusterMutationPolicy(resourceManifest *v1alpha1.ClusterMutationPolicy) error {
+	var errorList []error
+
+	errorList = append(errorList, controller.CheckSources(resourceManifest.Spec.Sources)...)
+	errorList = append(errorList, controller.CheckVariables(resourceManifest.Spec.Variables)...)
+	errorList = append(errorList, controller.CheckConditions(resourceManifest.Spec.Conditions)...)
+	errorList = append(errorList, controller.CheckTests(resourceManifest.Spec.Tests)...)
+
+	switch strings.ToLower(resourceManifest.Spec.ValidateResult) {
+	case "", v1alpha1.MutationValidateResultNone, v1alpha1.MutationValidateResultDryRun, v1alpha1.MutationValidateResultOpenapi:
+	default:
+		errorList = append(errorList, fmt.Errorf("validateResult: unknown mode '%s'", resourceManifest.Spec.ValidateResult))
+	}
+
+	switch strings.ToLower(resourceManifest.Spec.Mode) {
+	case "", v1alpha1.MutationModeEnforce, v1alpha1.MutationModeAudit:
+	default:
+		errorList = append(errorList, fmt.Errorf("mode: unknown mode '%s'", resourceManifest.Spec.Mode))
+	}
+
+	switch strings.ToLower(resourceManifest.Spec.ReinvocationPolicy) {
+	case "", strings.ToLower(v1alpha1.MutationReinvocationPolicyNever), strings.ToLower(v1alpha1.MutationReinvocationPolicyIfNeeded):
+	default:
+		errorList = append(errorList, fmt.Errorf("reinvocationPolicy: unknown policy '%s'", resourceManifest.Spec.ReinvocationPolicy))
+	}
+
+	// Program replaces the patch, so the patch is only checked without it
+	if resourceManifest.Spec.Program != nil {
+		errorList = append(errorList, controller.CheckTemplate("program",
+			resourceManifest.Spec.Program.Engine, resourceManifest.Spec.Program.Template))
+	} else {
+		if resourceManifest.Spec.Patch.Template == "" && len(resourceManifest.Spec.Patches) == 0 {
+			errorList = append(errorList, fmt.Errorf("patch: template or patches are required when program is not defined"))
+		}
+
+		if resourceManifest.Spec.Patch.Template != "" {
+			errorList = append(errorList, checkPatch("patch", &resourceManifest.Spec.Patch)...)
+		}
+
+		for patchIndex, patch := range resourceManifest.Spec.Patches {
+			errorList = append(errorList, checkPatch(fmt.Sprintf("patches[%d]", patchIndex), &patch)...)
+		}
+	}
+
+	return errors.Join(errorList...)
+}
+
+// checkPatch validates the type, template and conditions of a patch
+func checkPatch(field string, patch *v1alpha1.PatchT) (errorList []error) {
+	switch strings.ToLower(patch.Type) {
+
+	// Empty type is evaluated as 'jsonpatch', so existing policies without it keep working
+	case "", v1alpha1.MutationPatchTypeJson, v1alpha1.MutationPatchTypeMerge, v1alpha1.MutationPatchTypeStrategicMerge:
+		errorList = append(errorList, controller.CheckTemplate(field, patch.Engine, patch.Template))
+
+	// CEL based types are always evaluated as CEL expressions
+	case v1alpha1.MutationPatchTypeApplyConfiguration, v1alpha1.MutationPatchTypeCelJsonPatch:
+		if patch.Engine != "" && patch.Engine != template.EngineCel {
+			errorList = append(errorList, fmt.Errorf("%s: type '%s' only supports '%s' engine", field, patch.Type, template.EngineCel))
+		}
+		errorList = append(errorList, controller.CheckTemplate(field, template.EngineCel, patch.Template))
+
+	default:
+		errorList = append(errorList, fmt.Errorf("%s: unknown type '%s'", field, patch.Type))
+	}
+
+	for _, err := range controller.CheckConditions(patch.Conditions) {
+		errorList = append(errorList, fmt.Errorf("%s: %s", field, err.Error()))
+	}
+
+	return errorList
+}
@@ -0,0 +1,177 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clustermutationpolicy
+
+import (
+	"strings"
+	"testing"
+
+	//
+	"github.com/freepik-company/admitik/api/v1alpha1"
+)
+
+func TestCheckClusterMutationPolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(spec *v1alpha1.ClusterMutationPolicySpec)
+
+		// expectedErrs are parts of the expected error message. Empty when no error is expected
+		expectedErrs []string
+	}{
+		{
+			name:   "valid policy",
+			modify: func(spec *v1alpha1.ClusterMutationPolicySpec) {},
+		},
+		{
+			name: "known enums are case insensitive",
+			modify: func(spec *v1alpha1.ClusterMutationPolicySpec) {
+				spec.ValidateResult = "DryRun"
+				spec.Mode = "Audit"
+				spec.ReinvocationPolicy = "ifneeded"
+			},
+		},
+		{
+			name: "enums as declared in the API are accepted",
+			modify: func(spec *v1alpha1.ClusterMutationPolicySpec) {
+				spec.ValidateResult = v1alpha1.MutationValidateResultOpenapi
+				spec.Mode = v1alpha1.MutationModeEnforce
+				spec.ReinvocationPolicy = v1alpha1.MutationReinvocationPolicyNever
+			},
+		},
+		{
+			name: "unknown validateResult",
+			modify: func(spec *v1alpha1.ClusterMutationPolicySpec) {
+				spec.ValidateResult = "strict"
+			},
+			expectedErrs: []string{"validateResult: unknown mode 'strict'"},
+		},
+		{
+			name: "unknown mode",
+			modify: func(spec *v1alpha1.ClusterMutationPolicySpec) {
+				spec.Mode = "permissive"
+			},
+			expectedErrs: []string{"mode: unknown mode 'permissive'"},
+		},
+		{
+			name: "unknown reinvocationPolicy",
+			modify: func(spec *v1alpha1.ClusterMutationPolicySpec) {
+				spec.ReinvocationPolicy = "Always"
+			},
+			expectedErrs: []string{"reinvocationPolicy: unknown policy 'Always'"},
+		},
+		{
+			name: "empty patch type defaults to jsonpatch",
+			modify: func(spec *v1alpha1.ClusterMutationPolicySpec) {
+				spec.Patch.Type = ""
+			},
+		},
+		{
+			name: "every patch type is accepted",
+			modify: func(spec *v1alpha1.ClusterMutationPolicySpec) {
+				spec.Patch = v1alpha1.PatchT{}
+				spec.Patches = []v1alpha1.PatchT{
+					{Type: "JsonPatch", Engine: "cel", Template: `[{"op": "remove", "path": "/metadata/labels/a"}]`},
+					{Type: v1alpha1.MutationPatchTypeMerge, Engine: "cel", Template: `{"metadata": {}}`},
+					{Type: v1alpha1.MutationPatchTypeStrategicMerge, Engine: "cel", Template: `{"metadata": {}}`},
+					{Type: v1alpha1.MutationPatchTypeApplyConfiguration, Template: `Object{metadata: Object.metadata{}}`},
+					{Type: v1alpha1.MutationPatchTypeCelJsonPatch, Engine: "cel", Template: `[JSONPatch{op: "remove", path: "/metadata/labels/a"}]`},
+				}
+			},
+		},
+		{
+			name: "unknown patch type",
+			modify: func(spec *v1alpha1.ClusterMutationPolicySpec) {
+				spec.Patch.Type = "xmlpatch"
+			},
+			expectedErrs: []string{"patch: unknown type 'xmlpatch'"},
+		},
+		{
+			name: "unknown type in patches",
+			modify: func(spec *v1alpha1.ClusterMutationPolicySpec) {
+				spec.Patch = v1alpha1.PatchT{}
+				spec.Patches = []v1alpha1.PatchT{
+					{Type: v1alpha1.MutationPatchTypeMerge, Engine: "cel", Template: `{"metadata": {}}`},
+					{Type: "xmlpatch", Template: "<metadata/>"},
+				}
+			},
+			expectedErrs: []string{"patches[1]: unknown type 'xmlpatch'"},
+		},
+		{
+			name: "CEL based patch types only support CEL",
+			modify: func(spec *v1alpha1.ClusterMutationPolicySpec) {
+				spec.Patch = v1alpha1.PatchT{Type: v1alpha1.MutationPatchTypeApplyConfiguration, Engine: "gotmpl", Template: `Object{}`}
+			},
+			expectedErrs: []string{"patch: type 'applyconfiguration' only supports 'cel' engine"},
+		},
+		{
+			name: "patch or patches are required",
+			modify: func(spec *v1alpha1.ClusterMutationPolicySpec) {
+				spec.Patch = v1alpha1.PatchT{}
+			},
+			expectedErrs: []string{"patch: template or patches are required when program is not defined"},
+		},
+		{
+			name: "program replaces the patch",
+			modify: func(spec *v1alpha1.ClusterMutationPolicySpec) {
+				spec.Patch = v1alpha1.PatchT{Type: "xmlpatch"}
+				spec.Program = &v1alpha1.ProgramT{Engine: "cel", Template: `{"allowed": true}`}
+			},
+		},
+		{
+			name: "every broken field is reported",
+			modify: func(spec *v1alpha1.ClusterMutationPolicySpec) {
+				spec.ValidateResult = "strict"
+				spec.Mode = "permissive"
+				spec.Patch.Type = "xmlpatch"
+			},
+			expectedErrs: []string{"validateResult: unknown mode 'strict'", "mode: unknown mode 'permissive'", "patch: unknown type 'xmlpatch'"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			policy := &v1alpha1.ClusterMutationPolicy{
+				Spec: v1alpha1.ClusterMutationPolicySpec{
+					Patch: v1alpha1.PatchT{
+						Type:     v1alpha1.MutationPatchTypeJson,
+						Engine:   "gotmpl",
+						Template: `[{"op": "add", "path": "/metadata/labels/owner", "value": "{{ .object.metadata.name }}"}]`,
+					},
+				},
+			}
+			test.modify(&policy.Spec)
+
+			err := checkClusterMutationPolicy(policy)
+
+			if len(test.expectedErrs) == 0 {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("expected errors %v, got none", test.expectedErrs)
+			}
+			for _, expectedErr := range test.expectedErrs {
+				if !strings.Contains(err.Error(), expectedErr) {
+					t.Errorf("expected error containing '%s', got: %v", expectedErr, err)
+				}
+			}
+		})
+	}
+}
@@ -29,12 +29,17 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	controllerRuntimeController "sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	//
 	"github.com/freepik-company/admitik/api/v1alpha1"
 	"github.com/freepik-company/admitik/internal/controller"
+	"github.com/freepik-company/admitik/internal/evaluator"
 	policyStore "github.com/freepik-company/admitik/internal/registry/policystore"
 )
 
@@ -44,12 +49,19 @@ type ClusterValidationPolicyControllerOptions struct {
 	ExcludeAdmissionSelfNamespace bool
 	ExcludedAdmissionNamespaces   string
 
+	// EnableNativeTranslation enables translating eligible policies into ValidatingAdmissionPolicy objects
+	EnableNativeTranslation bool
+
 	WebhookClientConfig admissionregv1.WebhookClientConfig
 	WebhookTimeout      int
 }
 
 type ClusterValidationPolicyControllerDependencies struct {
 	ClusterValidationPolicyRegistry *policyStore.PolicyStore[*v1alpha1.ClusterValidationPolicy]
+	Evaluator                       *evaluator.Evaluator
+
+	// TemplateLibraryEvents carries the policies to reconcile when their libraries change
+	TemplateLibraryEvents <-chan event.GenericEvent
 }
 
 // ClusterValidationPolicyReconciler reconciles a ClusterValidationPolicy object
@@ -66,6 +78,8 @@ type ClusterValidationPolicyReconciler struct {
 // +kubebuilder:rbac:groups=admitik.dev,resources=clustervalidationpolicies/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=admitik.dev,resources=clustervalidationpolicies/finalizers,verbs=update
 // +kubebuilder:rbac:groups="admissionregistration.k8s.io",resources=validatingwebhookconfigurations,verbs=get;list;create;update;patch;delete;watch
+// +kubebuilder:rbac:groups="admissionregistration.k8s.io",resources=validatingadmissionpolicies;validatingadmissionpolicybindings,verbs=get;list;create;update;patch;delete;watch
+// +kubebuilder:rbac:groups="authorization.k8s.io",resources=subjectaccessreviews,verbs=create
 // +kubebuilder:rbac:groups="*",resources="*",verbs="*"
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
@@ -130,15 +144,46 @@ func (r *ClusterValidationPolicyReconciler) Reconcile(ctx context.Context, req c
 
 	// 5. Update the status before the requeue
 	defer func() {
-		err = controller.UpdateWithRetry(ctx, r.Client, objectManifest, func(object client.Object) error {
+		desiredStatus := objectManifest.Status
+		desiredStatus.ObservedGeneration = objectManifest.Generation
+
+		statusErr := controller.UpdateStatusWithRetry(ctx, r.Client, objectManifest, func(object client.Object) error {
+			// Runtime status is owned by the status controller, so it's kept as it is
+			desiredStatus.PolicyRuntimeStatusT = object.(*v1alpha1.ClusterValidationPolicy).Status.PolicyRuntimeStatusT
+			object.(*v1alpha1.ClusterValidationPolicy).Status = desiredStatus
 			return nil
 		})
-		if err != nil {
-			logger.Info(fmt.Sprintf(controller.ResourceConditionUpdateError, controller.ClusterValidationPolicyResourceType, req.Name, err.Error()))
+		if statusErr != nil {
+			logger.Info(fmt.Sprintf(controller.ResourceConditionUpdateError, controller.ClusterValidationPolicyResourceType, req.Name, statusErr.Error()))
 		}
 	}()
 
-	// 6. The resource already exists: manage the update
+	// 6. Check the policy before serving it. Invalid policies are removed from the registry
+	err = checkClusterValidationPolicy(objectManifest)
+	if err != nil {
+		r.UpdateConditionInvalidPolicy(objectManifest, err)
+		logger.Info(fmt.Sprintf(controller.ResourceReconcileError, controller.ClusterValidationPolicyResourceType, req.Name, err.Error()))
+
+		err = r.ReconcileClusterValidationPolicy(ctx, watch.Deleted, objectManifest)
+		if err != nil {
+			logger.Info(fmt.Sprintf(controller.ResourceReconcileError, controller.ClusterValidationPolicyResourceType, req.Name, err.Error()))
+		}
+		return result, err
+	}
+
+	// 7. Run the tests before serving the policy. On failures, the previous version is kept in the registry.
+	// The registry is in memory, so policies failing their tests are not served after a restart
+	objectManifest.Status.TestResults = r.Dependencies.Evaluator.RunValidationPolicyTests(objectManifest)
+	err = controller.CheckTestResults(objectManifest.Status.TestResults)
+	if err != nil {
+		r.UpdateConditionTestsFailed(objectManifest, err)
+		logger.Info(fmt.Sprintf(controller.ResourceReconcileError, controller.ClusterValidationPolicyResourceType, req.Name, err.Error()))
+
+		// Tests only depend on the policy and its libraries, and changes on them trigger a new reconciliation
+		return result, nil
+	}
+
+	// 8. The resource already exists: manage the update
 	err = r.ReconcileClusterValidationPolicy(ctx, watch.Modified, objectManifest)
 	if err != nil {
 		r.UpdateConditionKubernetesApiCallFailure(objectManifest)
@@ -146,7 +191,7 @@ func (r *ClusterValidationPolicyReconciler) Reconcile(ctx context.Context, req c
 		return result, err
 	}
 
-	// 7. Success, update the status
+	// 9. Success, update the status
 	r.UpdateConditionSuccess(objectManifest)
 
 	return result, err
@@ -154,8 +199,29 @@ func (r *ClusterValidationPolicyReconciler) Reconcile(ctx context.Context, req c
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *ClusterValidationPolicyReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	builder := ctrl.NewControllerManagedBy(mgr).
 		For(&v1alpha1.ClusterValidationPolicy{}).
+		// Policies are re-evaluated when the libraries they reference change.
+		// TemplateLibrary controller sends them once its registry is up-to-date
+		WatchesRawSource(source.Channel(r.Dependencies.TemplateLibraryEvents, &handler.EnqueueRequestForObject{}))
+
+	// Native objects are only watched when translation is enabled, as they are not available in old clusters.
+	// Changes done by others are reverted this way. When they are not available, policies are served by the webhook
+	if r.Options.EnableNativeTranslation && isNativeValidatingAdmissionPolicyAvailable(mgr) {
+		builder = builder.
+			Owns(&admissionregv1.ValidatingAdmissionPolicy{}).
+			Owns(&admissionregv1.ValidatingAdmissionPolicyBinding{})
+	}
+
+	// Objects translated on previous runs are deleted when translation is disabled
+	if !r.Options.EnableNativeTranslation {
+		err := mgr.Add(manager.RunnableFunc(r.deleteAllNativeValidatingAdmissionPolicies))
+		if err != nil {
+			return err
+		}
+	}
+
+	return builder.
 		WithEventFilter(predicate.GenerationChangedPredicate{}).
 		WithOptions(controllerRuntimeController.Options{
 			NeedLeaderElection: pointer.Bool(false),
@@ -0,0 +1,336 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clustervalidationpolicy
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	//
+	"github.com/google/cel-go/cel"
+	celast "github.com/google/cel-go/common/ast"
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	//
+	"github.com/freepik-company/admitik/api/v1alpha1"
+	"github.com/freepik-company/admitik/internal/template"
+)
+
+const (
+	// NativeResourceNamePrefix represents the prefix of the names for the ValidatingAdmissionPolicy
+	// and ValidatingAdmissionPolicyBinding objects created from ClusterValidationPolicy objects
+	NativeResourceNamePrefix = "admitik-"
+
+	// NativeResourceManagedByLabel represents the label added to the objects created from ClusterValidationPolicy objects
+	NativeResourceManagedByLabel = "app.kubernetes.io/managed-by"
+)
+
+var (
+	// nativeUnsupportedVariables represents the data injected by Admitik into CEL that is not
+	// available in ValidatingAdmissionPolicy objects
+	nativeUnsupportedVariables = []string{"operation", "userInfo", "changes", "originalObject", "sources", "vars"}
+)
+
+// reconcileNativeTranslation translates a ClusterValidationPolicy into native objects when it's eligible,
+// deleting them otherwise. Eligibility is reported in the status of the policy
+func (r *ClusterValidationPolicyReconciler) reconcileNativeTranslation(ctx context.Context, policy *v1alpha1.ClusterValidationPolicy) (translated bool, err error) {
+
+	issues := getNativeTranslationIssues(policy)
+	if len(issues) > 0 {
+		r.UpdateConditionNativeTranslationIneligible(policy, issues)
+		return false, r.deleteNativeValidatingAdmissionPolicy(ctx, policy)
+	}
+
+	err = r.syncNativeValidatingAdmissionPolicy(ctx, policy)
+	if err != nil {
+		// Partially synced objects are removed, as the policy will be served by the webhook
+		_ = r.deleteNativeValidatingAdmissionPolicy(ctx, policy)
+		return false, err
+	}
+
+	r.UpdateConditionNativeTranslated(policy, getNativeResourceName(policy))
+	return true, nil
+}
+
+// getNativeTranslationIssues return the reasons why a ClusterValidationPolicy can not be translated into
+// a ValidatingAdmissionPolicy. An empty list means the policy is eligible
+func getNativeTranslationIssues(policy *v1alpha1.ClusterValidationPolicy) (issues []string) {
+
+	if len(policy.Spec.Sources) > 0 {
+		issues = append(issues, "sources are not supported")
+	}
+
+	if len(policy.Spec.Variables) > 0 {
+		issues = append(issues, "variables are not supported")
+	}
+
+	if policy.Spec.Program != nil {
+		issues = append(issues, "program is not supported")
+	}
+
+	if len(policy.Spec.Conditions) == 0 {
+		issues = append(issues, "at least one condition is required")
+	}
+
+	for _, condition := range policy.Spec.Conditions {
+		if condition.Engine != template.EngineCel && condition.Engine != "" {
+			issues = append(issues, fmt.Sprintf("condition '%s' does not use CEL", condition.Name))
+			continue
+		}
+
+		if condition.Value != "true" && condition.Value != "false" {
+			issues = append(issues, fmt.Sprintf("condition '%s' is not compared with 'true' or 'false'", condition.Name))
+			continue
+		}
+
+		if issue := getNativeExpressionIssue(condition.Key); issue != "" {
+			issues = append(issues, fmt.Sprintf("condition '%s' %s", condition.Name, issue))
+		}
+	}
+
+	if policy.Spec.Message.Engine != template.EngineCel && policy.Spec.Message.Engine != "" {
+		issues = append(issues, "message does not use CEL")
+	} else if issue := getNativeExpressionIssue(policy.Spec.Message.Template); issue != "" {
+		issues = append(issues, fmt.Sprintf("message %s", issue))
+	}
+
+	return issues
+}
+
+// getNativeExpressionIssue return the reason why a CEL expression can not be used in a ValidatingAdmissionPolicy.
+// Empty string means the expression only uses data and functions also available in Kubernetes
+func getNativeExpressionIssue(expression string) string {
+
+	if strings.TrimSpace(expression) == "" {
+		return ""
+	}
+
+	env, err := cel.NewEnv()
+	if err != nil {
+		return fmt.Sprintf("can not be checked: %s", err.Error())
+	}
+
+	ast, issues := env.Parse(expression)
+	if issues != nil && issues.Err() != nil {
+		return fmt.Sprintf("can not be parsed: %s", issues.Err())
+	}
+
+	admitikFunctions := template.GetCelFunctionNames()
+
+	var foundIssues []string
+	celast.PreOrderVisit(ast.NativeRep().Expr(), celast.NewExprVisitor(func(expr celast.Expr) {
+		var issue string
+
+		switch expr.Kind() {
+		case celast.IdentKind:
+			if slices.Contains(nativeUnsupportedVariables, expr.AsIdent()) {
+				issue = fmt.Sprintf("uses '%s'", expr.AsIdent())
+			}
+		case celast.CallKind:
+			if slices.Contains(admitikFunctions, expr.AsCall().FunctionName()) {
+				issue = fmt.Sprintf("uses '%s()'", expr.AsCall().FunctionName())
+			}
+		}
+
+		if issue != "" && !slices.Contains(foundIssues, issue) {
+			foundIssues = append(foundIssues, issue)
+		}
+	}))
+
+	return strings.Join(foundIssues, ", ")
+}
+
+// getNativeResourceName return the name used for native objects created from a ClusterValidationPolicy
+func getNativeResourceName(policy *v1alpha1.ClusterValidationPolicy) string {
+	return NativeResourceNamePrefix + policy.Name
+}
+
+// syncNativeValidatingAdmissionPolicy creates or updates the ValidatingAdmissionPolicy and its binding
+// for a ClusterValidationPolicy. Both are owned by the policy, so Kubernetes deletes them with it
+func (r *ClusterValidationPolicyReconciler) syncNativeValidatingAdmissionPolicy(ctx context.Context, policy *v1alpha1.ClusterValidationPolicy) (err error) {
+
+	nativeName := getNativeResourceName(policy)
+
+	// Policy
+	nativePolicy := &admissionregv1.ValidatingAdmissionPolicy{}
+	nativePolicy.Name = nativeName
+
+	_, err = controllerutil.CreateOrUpdate(ctx, r.Client, nativePolicy, func() error {
+		if nativePolicy.Labels == nil {
+			nativePolicy.Labels = map[string]string{}
+		}
+		nativePolicy.Labels[NativeResourceManagedByLabel] = "admitik"
+
+		nativePolicy.Spec = r.getNativeValidatingAdmissionPolicySpec(policy)
+		return controllerutil.SetControllerReference(policy, nativePolicy, r.Scheme)
+	})
+	if err != nil {
+		return fmt.Errorf("error syncing ValidatingAdmissionPolicy '%s': %s", nativeName, err.Error())
+	}
+
+	// Binding
+	nativeBinding := &admissionregv1.ValidatingAdmissionPolicyBinding{}
+	nativeBinding.Name = nativeName
+
+	_, err = controllerutil.CreateOrUpdate(ctx, r.Client, nativeBinding, func() error {
+		if nativeBinding.Labels == nil {
+			nativeBinding.Labels = map[string]string{}
+		}
+		nativeBinding.Labels[NativeResourceManagedByLabel] = "admitik"
+
+		// Enforced policies deny the request, the rest only warn and audit as they do in the webhook
+		validationActions := []admissionregv1.ValidationAction{admissionregv1.Warn, admissionregv1.Audit}
+		if strings.ToLower(policy.Spec.FailureAction) == v1alpha1.ValidationFailureActionEnforce {
+			validationActions = []admissionregv1.ValidationAction{admissionregv1.Deny}
+		}
+
+		nativeBinding.Spec = admissionregv1.ValidatingAdmissionPolicyBindingSpec{
+			PolicyName:        nativeName,
+			ValidationActions: validationActions,
+		}
+		return controllerutil.SetControllerReference(policy, nativeBinding, r.Scheme)
+	})
+	if err != nil {
+		return fmt.Errorf("error syncing ValidatingAdmissionPolicyBinding '%s': %s", nativeName, err.Error())
+	}
+
+	return nil
+}
+
+// getNativeValidatingAdmissionPolicySpec return the ValidatingAdmissionPolicy spec equivalent to a ClusterValidationPolicy
+func (r *ClusterValidationPolicyReconciler) getNativeValidatingAdmissionPolicySpec(policy *v1alpha1.ClusterValidationPolicy) admissionregv1.ValidatingAdmissionPolicySpec {
+
+	failurePolicy := admissionregv1.Fail
+	matchPolicy := admissionregv1.Equivalent
+
+	resourceRules := []admissionregv1.NamedRuleWithOperations{}
+	for _, intercResourceGroup := range policy.Spec.InterceptedResources {
+		resourceRules = append(resourceRules, admissionregv1.NamedRuleWithOperations{
+			RuleWithOperations: admissionregv1.RuleWithOperations{
+				Operations: intercResourceGroup.Operations,
+				Rule: admissionregv1.Rule{
+					APIGroups:   []string{intercResourceGroup.Group},
+					APIVersions: []string{intercResourceGroup.Version},
+					Resources:   []string{intercResourceGroup.Resource},
+					Scope:       &ValidatingWebhookConfigurationRuleScopeAll,
+				},
+			},
+		})
+	}
+
+	// Each condition is a validation. Conditions compared with 'false' are negated
+	validations := []admissionregv1.Validation{}
+	for _, condition := range policy.Spec.Conditions {
+		expression := condition.Key
+		if condition.Value == "false" {
+			expression = fmt.Sprintf("!(%s)", strings.TrimSpace(condition.Key))
+		}
+
+		validations = append(validations, admissionregv1.Validation{
+			Expression:        expression,
+			Message:           fmt.Sprintf("ClusterValidationPolicy '%s' failed on condition '%s'", policy.Name, condition.Name),
+			MessageExpression: strings.TrimSpace(policy.Spec.Message.Template),
+		})
+	}
+
+	return admissionregv1.ValidatingAdmissionPolicySpec{
+		FailurePolicy: &failurePolicy,
+		MatchConstraints: &admissionregv1.MatchResources{
+			NamespaceSelector: r.getAdmissionNamespaceSelector(),
+			ObjectSelector:    r.getAdmissionObjectSelector(),
+			ResourceRules:     resourceRules,
+			MatchPolicy:       &matchPolicy,
+		},
+		Validations: validations,
+	}
+}
+
+// deleteNativeValidatingAdmissionPolicy deletes the ValidatingAdmissionPolicy and its binding
+// created for a ClusterValidationPolicy, when they exist
+func (r *ClusterValidationPolicyReconciler) deleteNativeValidatingAdmissionPolicy(ctx context.Context, policy *v1alpha1.ClusterValidationPolicy) (err error) {
+
+	nativeName := getNativeResourceName(policy)
+
+	for _, nativeObject := range []client.Object{
+		&admissionregv1.ValidatingAdmissionPolicyBinding{},
+		&admissionregv1.ValidatingAdmissionPolicy{},
+	} {
+		err = r.Get(ctx, types.NamespacedName{Name: nativeName}, nativeObject)
+		if err != nil {
+			// Clusters without native policies have nothing to clean
+			if errors.IsNotFound(err) || meta.IsNoMatchError(err) {
+				continue
+			}
+			return fmt.Errorf("error getting native object '%s': %s", nativeName, err.Error())
+		}
+
+		// Only delete objects owned by this policy
+		if !metav1.IsControlledBy(nativeObject, policy) {
+			continue
+		}
+
+		err = client.IgnoreNotFound(r.Delete(ctx, nativeObject))
+		if err != nil {
+			return fmt.Errorf("error deleting native object '%s': %s", nativeName, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// deleteAllNativeValidatingAdmissionPolicies deletes the ValidatingAdmissionPolicy objects and their bindings
+// created from existing ClusterValidationPolicy objects. It's executed on start when the translation is disabled,
+// as objects created on previous runs would enforce the policies a second time next to the webhook.
+// Only objects controlled by the policies are deleted, so objects labeled the same way by others are kept
+func (r *ClusterValidationPolicyReconciler) deleteAllNativeValidatingAdmissionPolicies(ctx context.Context) error {
+	logger := log.FromContext(ctx)
+
+	policyList := &v1alpha1.ClusterValidationPolicyList{}
+	err := r.List(ctx, policyList)
+	if err != nil {
+		logger.Info(fmt.Sprintf("error listing ClusterValidationPolicy objects to delete native objects created by previous translations: %s", err.Error()))
+		return nil
+	}
+
+	for _, policy := range policyList.Items {
+		err = r.deleteNativeValidatingAdmissionPolicy(ctx, &policy)
+		if err != nil {
+			logger.Info(fmt.Sprintf("error deleting native objects created by previous translations: %s", err.Error()))
+		}
+	}
+
+	// Errors are not returned, as the manager is stopped when a runnable fails
+	return nil
+}
+
+// isNativeValidatingAdmissionPolicyAvailable checks whether the cluster serves ValidatingAdmissionPolicy objects
+func isNativeValidatingAdmissionPolicyAvailable(mgr ctrl.Manager) bool {
+	_, err := mgr.GetRESTMapper().RESTMapping(
+		admissionregv1.SchemeGroupVersion.WithKind("ValidatingAdmissionPolicy").GroupKind(),
+		admissionregv1.SchemeGroupVersion.Version)
+	return err == nil
+}
@@ -0,0 +1,358 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clustervalidationpolicy
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	//
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	//
+	"github.com/freepik-company/admitik/api/v1alpha1"
+)
+
+// getTestNativePolicy return a ClusterValidationPolicy eligible for native translation
+func getTestNativePolicy(name string) *v1alpha1.ClusterValidationPolicy {
+	return &v1alpha1.ClusterValidationPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: name, UID: types.UID(name + "-uid")},
+		Spec: v1alpha1.ClusterValidationPolicySpec{
+			FailureAction: v1alpha1.ValidationFailureActionEnforce,
+			InterceptedResources: []v1alpha1.AdmissionResourceGroupT{{
+				GroupVersionResource: metav1.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"},
+				Operations:           []admissionregv1.OperationType{admissionregv1.Create, admissionregv1.Update},
+			}},
+			Conditions: []v1alpha1.ConditionT{
+				{Name: "has-replicas", Key: "has(object.spec.replicas)", Value: "true"},
+				{Name: "not-too-many", Engine: "cel", Key: " object.spec.replicas > 10 ", Value: "false"},
+			},
+			Message: v1alpha1.MessageT{Engine: "cel", Template: ` "replicas are not valid" `},
+		},
+	}
+}
+
+// getTestNativeReconciler return a reconciler backed by a fake client that already contains the given objects
+func getTestNativeReconciler(t *testing.T, objects ...client.Object) *ClusterValidationPolicyReconciler {
+	scheme := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := admissionregv1.AddToScheme(scheme); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return &ClusterValidationPolicyReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build(),
+		Scheme: scheme,
+	}
+}
+
+func TestNativeTranslationIssues(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(policy *v1alpha1.ClusterValidationPolicy)
+
+		// expectedIssues are parts of the expected issues, in order. Empty when the policy is eligible
+		expectedIssues []string
+	}{
+		{
+			name:   "eligible policy",
+			modify: func(policy *v1alpha1.ClusterValidationPolicy) {},
+		},
+		{
+			name: "sources, variables and program are not supported",
+			modify: func(policy *v1alpha1.ClusterValidationPolicy) {
+				policy.Spec.Sources = []v1alpha1.SourceGroupT{{}}
+				policy.Spec.Variables = []v1alpha1.VariableT{{Name: "owner", Template: "'someone'"}}
+				policy.Spec.Program = &v1alpha1.ProgramT{Template: "{}"}
+			},
+			expectedIssues: []string{"sources are not supported", "variables are not supported", "program is not supported"},
+		},
+		{
+			name: "conditions are required",
+			modify: func(policy *v1alpha1.ClusterValidationPolicy) {
+				policy.Spec.Conditions = nil
+			},
+			expectedIssues: []string{"at least one condition is required"},
+		},
+		{
+			name: "conditions must use CEL",
+			modify: func(policy *v1alpha1.ClusterValidationPolicy) {
+				policy.Spec.Conditions[0].Engine = "gotmpl"
+			},
+			expectedIssues: []string{"condition 'has-replicas' does not use CEL"},
+		},
+		{
+			name: "conditions must be compared with booleans",
+			modify: func(policy *v1alpha1.ClusterValidationPolicy) {
+				policy.Spec.Conditions[1].Value = "3"
+			},
+			expectedIssues: []string{"condition 'not-too-many' is not compared with 'true' or 'false'"},
+		},
+		{
+			name: "conditions can not use data injected by Admitik",
+			modify: func(policy *v1alpha1.ClusterValidationPolicy) {
+				policy.Spec.Conditions[0].Key = "operation == 'CREATE' && userInfo.username != '' && operation != 'DELETE'"
+			},
+			expectedIssues: []string{"condition 'has-replicas' uses 'operation', uses 'userInfo'"},
+		},
+		{
+			name: "conditions can not use functions added by Admitik",
+			modify: func(policy *v1alpha1.ClusterValidationPolicy) {
+				policy.Spec.Conditions[0].Key = "changed('/spec/replicas')"
+			},
+			expectedIssues: []string{"condition 'has-replicas' uses 'changed()'"},
+		},
+		{
+			name: "conditions must be parsed",
+			modify: func(policy *v1alpha1.ClusterValidationPolicy) {
+				policy.Spec.Conditions[0].Key = "object.spec.("
+			},
+			expectedIssues: []string{"condition 'has-replicas' can not be parsed"},
+		},
+		{
+			name: "message must use CEL",
+			modify: func(policy *v1alpha1.ClusterValidationPolicy) {
+				policy.Spec.Message.Engine = "gotmpl"
+			},
+			expectedIssues: []string{"message does not use CEL"},
+		},
+		{
+			name: "message can not use data injected by Admitik",
+			modify: func(policy *v1alpha1.ClusterValidationPolicy) {
+				policy.Spec.Message.Template = "'changed by ' + vars.owner"
+			},
+			expectedIssues: []string{"message uses 'vars'"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			policy := getTestNativePolicy("example")
+			test.modify(policy)
+
+			issues := getNativeTranslationIssues(policy)
+
+			if len(issues) != len(test.expectedIssues) {
+				t.Fatalf("expected issues %v, got %v", test.expectedIssues, issues)
+			}
+			for i, expectedIssue := range test.expectedIssues {
+				if !strings.Contains(issues[i], expectedIssue) {
+					t.Errorf("expected issue containing '%s', got '%s'", expectedIssue, issues[i])
+				}
+			}
+		})
+	}
+}
+
+func TestNativeValidatingAdmissionPolicySpec(t *testing.T) {
+	reconciler := &ClusterValidationPolicyReconciler{
+		Options: ClusterValidationPolicyControllerOptions{
+			CurrentNamespace:              "admitik",
+			ExcludeAdmissionSelfNamespace: true,
+			EnableSpecialLabels:           true,
+		},
+	}
+
+	spec := reconciler.getNativeValidatingAdmissionPolicySpec(getTestNativePolicy("example"))
+
+	if spec.FailurePolicy == nil || *spec.FailurePolicy != admissionregv1.Fail {
+		t.Errorf("expected failure policy 'Fail', got %v", spec.FailurePolicy)
+	}
+
+	// Match constraints
+	if spec.MatchConstraints == nil || len(spec.MatchConstraints.ResourceRules) != 1 {
+		t.Fatalf("expected one resource rule, got %v", spec.MatchConstraints)
+	}
+	rule := spec.MatchConstraints.ResourceRules[0]
+	if rule.APIGroups[0] != "apps" || rule.APIVersions[0] != "v1" || rule.Resources[0] != "deployments" {
+		t.Errorf("unexpected resource rule: %v", rule.Rule)
+	}
+	if len(rule.Operations) != 2 || rule.Operations[0] != admissionregv1.Create || rule.Operations[1] != admissionregv1.Update {
+		t.Errorf("unexpected operations: %v", rule.Operations)
+	}
+	if spec.MatchConstraints.NamespaceSelector == nil ||
+		spec.MatchConstraints.NamespaceSelector.MatchExpressions[0].Values[0] != "admitik" {
+		t.Errorf("expected the namespace selector to exclude Admitik namespace, got %v", spec.MatchConstraints.NamespaceSelector)
+	}
+	if spec.MatchConstraints.ObjectSelector == nil {
+		t.Errorf("expected an object selector for the special labels")
+	}
+
+	// Validations
+	expectedExpressions := []string{"has(object.spec.replicas)", "!(object.spec.replicas > 10)"}
+	if len(spec.Validations) != len(expectedExpressions) {
+		t.Fatalf("expected %d validations, got %d", len(expectedExpressions), len(spec.Validations))
+	}
+	for i, validation := range spec.Validations {
+		if validation.Expression != expectedExpressions[i] {
+			t.Errorf("expected expression '%s', got '%s'", expectedExpressions[i], validation.Expression)
+		}
+		if validation.MessageExpression != `"replicas are not valid"` {
+			t.Errorf("unexpected message expression '%s'", validation.MessageExpression)
+		}
+		if !strings.Contains(validation.Message, "ClusterValidationPolicy 'example'") {
+			t.Errorf("unexpected message '%s'", validation.Message)
+		}
+	}
+}
+
+func TestSyncNativeValidatingAdmissionPolicy(t *testing.T) {
+	tests := []struct {
+		name          string
+		failureAction string
+
+		expectedActions []admissionregv1.ValidationAction
+	}{
+		{
+			name:            "enforced policies deny",
+			failureAction:   v1alpha1.ValidationFailureActionEnforce,
+			expectedActions: []admissionregv1.ValidationAction{admissionregv1.Deny},
+		},
+		{
+			name:            "permissive policies warn and audit",
+			failureAction:   v1alpha1.ValidationFailureActionPermissive,
+			expectedActions: []admissionregv1.ValidationAction{admissionregv1.Warn, admissionregv1.Audit},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			policy := getTestNativePolicy("example")
+			policy.Spec.FailureAction = test.failureAction
+			reconciler := getTestNativeReconciler(t, policy)
+
+			err := reconciler.syncNativeValidatingAdmissionPolicy(context.Background(), policy)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			nativePolicy := &admissionregv1.ValidatingAdmissionPolicy{}
+			err = reconciler.Get(context.Background(), types.NamespacedName{Name: "admitik-example"}, nativePolicy)
+			if err != nil {
+				t.Fatalf("unexpected error getting the ValidatingAdmissionPolicy: %v", err)
+			}
+			if !metav1.IsControlledBy(nativePolicy, policy) {
+				t.Errorf("expected the ValidatingAdmissionPolicy to be controlled by the policy")
+			}
+			if nativePolicy.Labels[NativeResourceManagedByLabel] != "admitik" {
+				t.Errorf("expected the ValidatingAdmissionPolicy to be labeled, got %v", nativePolicy.Labels)
+			}
+
+			nativeBinding := &admissionregv1.ValidatingAdmissionPolicyBinding{}
+			err = reconciler.Get(context.Background(), types.NamespacedName{Name: "admitik-example"}, nativeBinding)
+			if err != nil {
+				t.Fatalf("unexpected error getting the ValidatingAdmissionPolicyBinding: %v", err)
+			}
+			if !metav1.IsControlledBy(nativeBinding, policy) {
+				t.Errorf("expected the ValidatingAdmissionPolicyBinding to be controlled by the policy")
+			}
+			if nativeBinding.Spec.PolicyName != "admitik-example" {
+				t.Errorf("expected the binding to reference 'admitik-example', got '%s'", nativeBinding.Spec.PolicyName)
+			}
+			if len(nativeBinding.Spec.ValidationActions) != len(test.expectedActions) {
+				t.Fatalf("expected actions %v, got %v", test.expectedActions, nativeBinding.Spec.ValidationActions)
+			}
+			for i, action := range test.expectedActions {
+				if nativeBinding.Spec.ValidationActions[i] != action {
+					t.Errorf("expected actions %v, got %v", test.expectedActions, nativeBinding.Spec.ValidationActions)
+				}
+			}
+		})
+	}
+}
+
+func TestDeleteAllNativeValidatingAdmissionPolicies(t *testing.T) {
+	policy := getTestNativePolicy("example")
+
+	// Objects labeled as managed by Admitik, but not created from a ClusterValidationPolicy
+	foreignPolicy := &admissionregv1.ValidatingAdmissionPolicy{ObjectMeta: metav1.ObjectMeta{
+		Name:   "foreign",
+		Labels: map[string]string{NativeResourceManagedByLabel: "admitik"},
+	}}
+	foreignBinding := &admissionregv1.ValidatingAdmissionPolicyBinding{ObjectMeta: metav1.ObjectMeta{
+		Name:   "foreign",
+		Labels: map[string]string{NativeResourceManagedByLabel: "admitik"},
+	}}
+
+	// Object using the name of a translation, but not controlled by the policy
+	unownedPolicy := getTestNativePolicy("unowned")
+	unownedNativePolicy := &admissionregv1.ValidatingAdmissionPolicy{ObjectMeta: metav1.ObjectMeta{
+		Name:   "admitik-unowned",
+		Labels: map[string]string{NativeResourceManagedByLabel: "admitik"},
+	}}
+
+	reconciler := getTestNativeReconciler(t, policy, unownedPolicy, foreignPolicy, foreignBinding, unownedNativePolicy)
+
+	err := reconciler.syncNativeValidatingAdmissionPolicy(context.Background(), policy)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err = reconciler.deleteAllNativeValidatingAdmissionPolicies(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		object client.Object
+
+		expectedDeleted bool
+	}{
+		{
+			name:            "translated policy is deleted",
+			object:          &admissionregv1.ValidatingAdmissionPolicy{ObjectMeta: metav1.ObjectMeta{Name: "admitik-example"}},
+			expectedDeleted: true,
+		},
+		{
+			name:            "translated binding is deleted",
+			object:          &admissionregv1.ValidatingAdmissionPolicyBinding{ObjectMeta: metav1.ObjectMeta{Name: "admitik-example"}},
+			expectedDeleted: true,
+		},
+		{
+			name:   "foreign policy is kept",
+			object: &admissionregv1.ValidatingAdmissionPolicy{ObjectMeta: metav1.ObjectMeta{Name: "foreign"}},
+		},
+		{
+			name:   "foreign binding is kept",
+			object: &admissionregv1.ValidatingAdmissionPolicyBinding{ObjectMeta: metav1.ObjectMeta{Name: "foreign"}},
+		},
+		{
+			name:   "policy not controlled by its ClusterValidationPolicy is kept",
+			object: &admissionregv1.ValidatingAdmissionPolicy{ObjectMeta: metav1.ObjectMeta{Name: "admitik-unowned"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := reconciler.Get(context.Background(), client.ObjectKeyFromObject(test.object), test.object)
+
+			if test.expectedDeleted != errors.IsNotFound(err) {
+				t.Errorf("expected deleted to be %v, got error: %v", test.expectedDeleted, err)
+			}
+		})
+	}
+}
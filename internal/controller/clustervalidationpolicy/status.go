@@ -17,6 +17,10 @@ limitations under the License.
 package clustervalidationpolicy
 
 import (
+	"fmt"
+	"strings"
+
+	//
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	//
@@ -41,3 +45,48 @@ func (r *ClusterValidationPolicyReconciler) UpdateConditionKubernetesApiCallFail
 
 	controller.UpdateCondition(&caPolicy.Status.Conditions, condition)
 }
+
+func (r *ClusterValidationPolicyReconciler) UpdateConditionNativeTranslated(caPolicy *v1alpha1.ClusterValidationPolicy, nativeName string) {
+
+	//
+	condition := controller.NewCondition(controller.ConditionTypeNativeTranslation, metav1.ConditionTrue,
+		controller.ConditionReasonNativeTranslatedType, fmt.Sprintf(controller.ConditionReasonNativeTranslatedMessage, nativeName))
+
+	controller.UpdateCondition(&caPolicy.Status.Conditions, condition)
+}
+
+func (r *ClusterValidationPolicyReconciler) UpdateConditionNativeTranslationIneligible(caPolicy *v1alpha1.ClusterValidationPolicy, issues []string) {
+
+	//
+	condition := controller.NewCondition(controller.ConditionTypeNativeTranslation, metav1.ConditionFalse,
+		controller.ConditionReasonNativeIneligibleType, fmt.Sprintf(controller.ConditionReasonNativeIneligibleMessage, strings.Join(issues, "; ")))
+
+	controller.UpdateCondition(&caPolicy.Status.Conditions, condition)
+}
+
+func (r *ClusterValidationPolicyReconciler) UpdateConditionNativeTranslationFailed(caPolicy *v1alpha1.ClusterValidationPolicy, err error) {
+
+	//
+	condition := controller.NewCondition(controller.ConditionTypeNativeTranslation, metav1.ConditionFalse,
+		controller.ConditionReasonNativeFailedType, fmt.Sprintf(controller.ConditionReasonNativeFailedMessage, err.Error()))
+
+	controller.UpdateCondition(&caPolicy.Status.Conditions, condition)
+}
+
+func (r *ClusterValidationPolicyReconciler) UpdateConditionInvalidPolicy(caPolicy *v1alpha1.ClusterValidationPolicy, err error) {
+
+	//
+	condition := controller.NewCondition(controller.ConditionTypeResourceSynced, metav1.ConditionFalse,
+		controller.ConditionReasonInvalidPolicyType, fmt.Sprintf(controller.ConditionReasonInvalidPolicyMessage, err.Error()))
+
+	controller.UpdateCondition(&caPolicy.Status.Conditions, condition)
+}
+
+func (r *ClusterValidationPolicyReconciler) UpdateConditionTestsFailed(caPolicy *v1alpha1.ClusterValidationPolicy, err error) {
+
+	//
+	condition := controller.NewCondition(controller.ConditionTypeResourceSynced, metav1.ConditionFalse,
+		controller.ConditionReasonTestsFailedType, fmt.Sprintf(controller.ConditionReasonTestsFailedMessage, err.Error()))
+
+	controller.UpdateCondition(&caPolicy.Status.Conditions, condition)
+}
@@ -63,6 +63,28 @@ var (
 func (r *ClusterValidationPolicyReconciler) ReconcileClusterValidationPolicy(ctx context.Context, eventType watch.EventType, resourceManifest *v1alpha1.ClusterValidationPolicy) (err error) {
 	logger := log.FromContext(ctx)
 
+	// Policies translated into native ValidatingAdmissionPolicy objects are not served by the webhook,
+	// so they are removed from the registry as if they were deleted.
+	// When native objects can not be synced, the policy is served by the webhook instead, so it's always enforced.
+	// The error is returned at the end to retry the translation later
+	var nativeErr error
+	registryEventType := eventType
+	if r.Options.EnableNativeTranslation && eventType == watch.Modified {
+		translated, translationErr := r.reconcileNativeTranslation(ctx, resourceManifest)
+		if translationErr != nil {
+			nativeErr = translationErr
+			r.UpdateConditionNativeTranslationFailed(resourceManifest, translationErr)
+		}
+		if translated {
+			registryEventType = watch.Deleted
+		}
+	}
+
+	// Deleted or invalid policies must not be served natively either
+	if r.Options.EnableNativeTranslation && eventType == watch.Deleted {
+		nativeErr = r.deleteNativeValidatingAdmissionPolicy(ctx, resourceManifest)
+	}
+
 	// Update the registry
 	var desiredWatchedTypes []string
 	for _, intercResourceGroup := range resourceManifest.Spec.InterceptedResources {
@@ -89,14 +111,14 @@ func (r *ClusterValidationPolicyReconciler) ReconcileClusterValidationPolicy(ctx
 			}, "/")
 
 			// Handle deletion requests
-			if eventType == watch.Deleted {
+			if registryEventType == watch.Deleted {
 				logger.Info(resourceDeletionMessage, "watcher", watchedType)
 				r.Dependencies.ClusterValidationPolicyRegistry.RemoveResource(watchedType, resourceManifest)
 				continue
 			}
 
 			// Handle creation/update requests
-			if eventType == watch.Modified {
+			if registryEventType == watch.Modified {
 				logger.Info(resourceUpdatedMessage, "watcher", watchedType)
 
 				// Avoid adding those already added.
@@ -143,7 +165,14 @@ func (r *ClusterValidationPolicyReconciler) ReconcileClusterValidationPolicy(ctx
 		}
 	}
 
-	return nil
+	// Report the registration of the policy in the status
+	resourceManifest.Status.Webhook = &v1alpha1.PolicyWebhookStatusT{
+		Registered:    len(desiredWatchedTypes) > 0,
+		Configuration: ValidatingWebhookConfigurationName,
+		Rules:         desiredWatchedTypes,
+	}
+
+	return nativeErr
 }
 
 // getValidatingWebhookConfiguration return a ValidatingWebhookConfiguration object that is built based on
@@ -204,7 +233,22 @@ func (r *ClusterValidationPolicyReconciler) getMergedValidatingWebhookConfigurat
 	tmpWebhookObj.Rules = currentVwcRules
 	tmpWebhookObj.TimeoutSeconds = &timeoutSecondsConverted
 
-	// Ignore sensitive namespaces to avoid breaking Kubernetes essential services or chicken-egg scenarios
+	// Ignore sensitive namespaces and resources meeting a special label
+	tmpWebhookObj.NamespaceSelector = r.getAdmissionNamespaceSelector()
+	tmpWebhookObj.ObjectSelector = r.getAdmissionObjectSelector()
+
+	sideEffectsClass := admissionregv1.SideEffectClass(admissionregv1.SideEffectClassNone)
+	tmpWebhookObj.SideEffects = &sideEffectsClass
+
+	// Replace the webhooks section in the ValidatingWebhookConfiguration
+	metaWebhookObj.Webhooks = []admissionregv1.ValidatingWebhook{tmpWebhookObj}
+
+	return metaWebhookObj, alreadyCreated, nil
+}
+
+// getAdmissionNamespaceSelector return the selector that ignores sensitive namespaces
+// to avoid breaking Kubernetes essential services or chicken-egg scenarios
+func (r *ClusterValidationPolicyReconciler) getAdmissionNamespaceSelector() *v1.LabelSelector {
 	selectedNamespaces := []string{}
 	if !strings.EqualFold(r.Options.ExcludedAdmissionNamespaces, "") {
 		selectedNamespaces = strings.Split(r.Options.ExcludedAdmissionNamespaces, ",")
@@ -213,30 +257,30 @@ func (r *ClusterValidationPolicyReconciler) getMergedValidatingWebhookConfigurat
 		selectedNamespaces = append(selectedNamespaces, r.Options.CurrentNamespace)
 	}
 
-	if len(selectedNamespaces) > 0 {
-		tmpWebhookObj.NamespaceSelector = &v1.LabelSelector{}
-		tmpWebhookObj.NamespaceSelector.MatchExpressions = []v1.LabelSelectorRequirement{{
+	if len(selectedNamespaces) == 0 {
+		return nil
+	}
+
+	return &v1.LabelSelector{
+		MatchExpressions: []v1.LabelSelectorRequirement{{
 			Key:      "kubernetes.io/metadata.name",
 			Operator: v1.LabelSelectorOpNotIn,
 			Values:   selectedNamespaces,
-		}}
+		}},
+	}
+}
+
+// getAdmissionObjectSelector return the selector that ignores admission for resources meeting a special label
+func (r *ClusterValidationPolicyReconciler) getAdmissionObjectSelector() *v1.LabelSelector {
+	if !r.Options.EnableSpecialLabels {
+		return nil
 	}
 
-	// Ignore admission for resources meeting a special label
-	if r.Options.EnableSpecialLabels {
-		tmpWebhookObj.ObjectSelector = &v1.LabelSelector{}
-		tmpWebhookObj.ObjectSelector.MatchExpressions = []v1.LabelSelectorRequirement{{
+	return &v1.LabelSelector{
+		MatchExpressions: []v1.LabelSelectorRequirement{{
 			Key:      controller.IgnoreAdmissionLabel,
 			Operator: v1.LabelSelectorOpNotIn,
 			Values:   []string{"true"},
-		}}
+		}},
 	}
-
-	sideEffectsClass := admissionregv1.SideEffectClass(admissionregv1.SideEffectClassNone)
-	tmpWebhookObj.SideEffects = &sideEffectsClass
-
-	// Replace the webhooks section in the ValidatingWebhookConfiguration
-	metaWebhookObj.Webhooks = []admissionregv1.ValidatingWebhook{tmpWebhookObj}
-
-	return metaWebhookObj, alreadyCreated, nil
 }
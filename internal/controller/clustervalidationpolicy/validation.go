@@ -0,0 +1,60 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clustervalidationpolicy
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	//
+	"github.com/freepik-company/admitik/api/v1alpha1"
+	"github.com/freepik-company/admitik/internal/controller"
+)
+
+// checkClusterValidationPolicy validates the fields of a policy that can not be validated by the CRD,
+// such as templates or enums, returning an error describing the broken ones
+func checkClusterValidationPolicy(resourceManifest *v1alpha1.ClusterValidationPolicy) error {
+	var errorList []error
+
+	failureAction := strings.ToLower(resourceManifest.Spec.FailureAction)
+	if failureAction != "" &&
+		failureAction != v1alpha1.ValidationFailureActionPermissive && failureAction != v1alpha1.ValidationFailureActionEnforce {
+		errorList = append(errorList, fmt.Errorf("failureAction: unknown value '%s'", resourceManifest.Spec.FailureAction))
+	}
+
+	errorList = append(errorList, controller.CheckSources(resourceManifest.Spec.Sources)...)
+	errorList = append(errorList, controller.CheckVariables(resourceManifest.Spec.Variables)...)
+	errorList = append(errorList, controller.CheckConditions(resourceManifest.Spec.Conditions)...)
+	errorList = append(errorList, controller.CheckTests(resourceManifest.Spec.Tests)...)
+
+	if resourceManifest.Spec.Program != nil {
+		errorList = append(errorList, controller.CheckTemplate("program",
+			resourceManifest.Spec.Program.Engine, resourceManifest.Spec.Program.Template))
+	}
+
+	// Message is optional for programs, as they can return their own messages
+	if resourceManifest.Spec.Program == nil && resourceManifest.Spec.Message.Template == "" {
+		errorList = append(errorList, fmt.Errorf("message: template is required when program is not defined"))
+	}
+	if resourceManifest.Spec.Message.Template != "" {
+		errorList = append(errorList, controller.CheckTemplate("message",
+			resourceManifest.Spec.Message.Engine, resourceManifest.Spec.Message.Template))
+	}
+
+	return errors.Join(errorList...)
+}
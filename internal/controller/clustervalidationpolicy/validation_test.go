@@ -0,0 +1,93 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clustervalidationpolicy
+
+import (
+	"strings"
+	"testing"
+
+	//
+	"github.com/freepik-company/admitik/api/v1alpha1"
+)
+
+func TestCheckClusterValidationPolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(spec *v1alpha1.ClusterValidationPolicySpec)
+
+		// expectedErr is a part of the expected error message. Empty when no error is expected
+		expectedErr string
+	}{
+		{
+			name:   "valid policy",
+			modify: func(spec *v1alpha1.ClusterValidationPolicySpec) {},
+		},
+		{
+			name: "empty failureAction",
+			modify: func(spec *v1alpha1.ClusterValidationPolicySpec) {
+				spec.FailureAction = ""
+			},
+		},
+		{
+			name: "failureAction is case insensitive",
+			modify: func(spec *v1alpha1.ClusterValidationPolicySpec) {
+				spec.FailureAction = "Permissive"
+			},
+		},
+		{
+			name: "unknown failureAction",
+			modify: func(spec *v1alpha1.ClusterValidationPolicySpec) {
+				spec.FailureAction = "audit"
+			},
+			expectedErr: "failureAction: unknown value 'audit'",
+		},
+		{
+			name: "message is required without program",
+			modify: func(spec *v1alpha1.ClusterValidationPolicySpec) {
+				spec.Message = v1alpha1.MessageT{}
+			},
+			expectedErr: "message: template is required when program is not defined",
+		},
+		{
+			name: "message is optional with program",
+			modify: func(spec *v1alpha1.ClusterValidationPolicySpec) {
+				spec.Message = v1alpha1.MessageT{}
+				spec.Program = &v1alpha1.ProgramT{Engine: "cel", Template: `{"allowed": true}`}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			policy := getTestNativePolicy("example")
+			test.modify(&policy.Spec)
+
+			err := checkClusterValidationPolicy(policy)
+
+			if test.expectedErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), test.expectedErr) {
+				t.Errorf("expected error containing '%s', got: %v", test.expectedErr, err)
+			}
+		})
+	}
+}
@@ -36,6 +36,7 @@ const (
 	ClusterValidationPolicyResourceType = "ClusterValidationPolicy"
 	ClusterMutationPolicyResourceType   = "ClusterMutationPolicy"
 	ClusterGenerationPolicyResourceType = "ClusterGenerationPolicy"
+	TemplateLibraryResourceType         = "TemplateLibrary"
 
 	//
 	ResourceNotFoundError         = "%s '%s' resource not found. Ignoring since object must be deleted."
@@ -49,6 +50,9 @@ const (
 
 	//
 	IgnoreAdmissionLabel = "admitik.dev/ignore-admission"
+
+	// MutatedByAnnotation represents the annotation that records the policies that changed an object
+	MutatedByAnnotation = "admitik.dev/mutated-by"
 )
 
 // GetWebhookClientConfig return a WebhookClientConfig filled according to if the remote server
@@ -141,3 +145,36 @@ func UpdateWithRetry(
 		return client.Update(ctx, object)
 	})
 }
+
+// UpdateStatusWithRetry fetches the object, applies a mutation, and updates its status subresource
+// with retry-on-conflict using exponential backoff
+func UpdateStatusWithRetry(
+	ctx context.Context,
+	client client.Client,
+	object client.Object,
+	mutate func(obj client.Object) error) error {
+
+	key := types.NamespacedName{
+		Namespace: object.GetNamespace(),
+		Name:      object.GetName(),
+	}
+
+	reasonableBackoff := wait.Backoff{
+		Steps:    5,
+		Duration: 200 * time.Millisecond,
+		Factor:   2.0,
+		Jitter:   0.2,
+	}
+
+	return retry.RetryOnConflict(reasonableBackoff, func() error {
+		if err := client.Get(ctx, key, object); err != nil {
+			return err
+		}
+
+		if err := mutate(object); err != nil {
+			return err
+		}
+
+		return client.Status().Update(ctx, object)
+	})
+}
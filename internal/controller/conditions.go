@@ -25,10 +25,33 @@ const (
 	// ConditionTypeResourceSynced indicates that the target was synced or not
 	ConditionTypeResourceSynced = "ResourceSynced"
 
+	// ConditionTypeNativeTranslation indicates whether the policy is served by a native Kubernetes object or not
+	ConditionTypeNativeTranslation = "NativeTranslation"
+
 	// Kubernetes error type
 	ConditionReasonKubernetesApiCallErrorType    = "KubernetesApiCallError"
 	ConditionReasonKubernetesApiCallErrorMessage = "Call to Kubernetes API failed. More info in logs."
 
+	// Templates error type
+	ConditionReasonInvalidTemplatesType    = "InvalidTemplates"
+	ConditionReasonInvalidTemplatesMessage = "Some templates can not be parsed: %s"
+
+	// Policy error type
+	ConditionReasonInvalidPolicyType    = "InvalidPolicy"
+	ConditionReasonInvalidPolicyMessage = "Policy is invalid and it will not be evaluated: %s"
+
+	// Policy tests error type
+	ConditionReasonTestsFailedType    = "TestsFailed"
+	ConditionReasonTestsFailedMessage = "Previous version of the policy, if already served by this replica, is kept active, as %s"
+
+	// Native translation types
+	ConditionReasonNativeTranslatedType    = "Translated"
+	ConditionReasonNativeTranslatedMessage = "Policy is served by ValidatingAdmissionPolicy '%s'"
+	ConditionReasonNativeIneligibleType    = "Ineligible"
+	ConditionReasonNativeIneligibleMessage = "Policy is served by the webhook as it can not be translated: %s"
+	ConditionReasonNativeFailedType        = "SyncFailed"
+	ConditionReasonNativeFailedMessage     = "Policy is served by the webhook as native objects can not be synced: %s"
+
 	// Success
 	ConditionReasonTargetSynced        = "TargetSynced"
 	ConditionReasonTargetSyncedMessage = "Target was successfully synced"
@@ -39,6 +39,7 @@ import (
 	//
 	"github.com/freepik-company/admitik/api/v1alpha1"
 	"github.com/freepik-company/admitik/internal/globals"
+	policyCountersRegistry "github.com/freepik-company/admitik/internal/registry/policycounters"
 	policyStore "github.com/freepik-company/admitik/internal/registry/policystore"
 	resourceInformerRegistry "github.com/freepik-company/admitik/internal/registry/resourceinformer"
 	resourceObserverRegistry "github.com/freepik-company/admitik/internal/registry/resourceobserver"
@@ -82,6 +83,7 @@ type ObservedResourceControllerDependencies struct {
 	SourcesRegistry                 *sourcesRegistry.SourcesRegistry
 	ResourceInformerRegistry        *resourceInformerRegistry.ResourceInformerRegistry
 	ResourceObserverRegistry        *resourceObserverRegistry.ResourceObserverRegistry
+	PolicyCountersRegistry          *policyCountersRegistry.PolicyCountersRegistry
 }
 
 // ObservedResourceController represents the controller that triggers parallel threads.
@@ -167,6 +169,7 @@ func (r *ObservedResourceController) Start(ctx context.Context) error {
 		ClusterGenerationPolicyRegistry: r.Dependencies.ClusterGenerationPolicyRegistry,
 		SourcesRegistry:                 r.Dependencies.SourcesRegistry,
 		ResourceObserverRegistry:        r.Dependencies.ResourceObserverRegistry,
+		PolicyCountersRegistry:          r.Dependencies.PolicyCountersRegistry,
 	})
 
 	// Start cleaner for dead informers
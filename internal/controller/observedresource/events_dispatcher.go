@@ -26,6 +26,7 @@ import (
 	//
 	"github.com/freepik-company/admitik/api/v1alpha1"
 	"github.com/freepik-company/admitik/internal/globals"
+	policyCountersRegistry "github.com/freepik-company/admitik/internal/registry/policycounters"
 	policyStore "github.com/freepik-company/admitik/internal/registry/policystore"
 	resourceObserverRegistry "github.com/freepik-company/admitik/internal/registry/resourceobserver"
 	sourcesRegistry "github.com/freepik-company/admitik/internal/registry/sources"
@@ -41,6 +42,7 @@ type EventDispatcherDependencies struct {
 	ClusterGenerationPolicyRegistry *policyStore.PolicyStore[*v1alpha1.ClusterGenerationPolicy]
 	SourcesRegistry                 *sourcesRegistry.SourcesRegistry
 	ResourceObserverRegistry        *resourceObserverRegistry.ResourceObserverRegistry
+	PolicyCountersRegistry          *policyCountersRegistry.PolicyCountersRegistry
 
 	//
 }
@@ -101,6 +103,7 @@ func (d *EventDispatcher) getInitializedProcessors() (processorsMap map[string]P
 	processors[ObserverTypeClusterGenerationPolicies] = NewGenerationProcessor(GenerationProcessorDependencies{
 		ClusterGenerationPolicyRegistry: d.dependencies.ClusterGenerationPolicyRegistry,
 		SourcesRegistry:                 d.dependencies.SourcesRegistry,
+		PolicyCountersRegistry:          d.dependencies.PolicyCountersRegistry,
 		KubeAvailableResourceList:       &d.kubeAvailableResourceList,
 	})
 
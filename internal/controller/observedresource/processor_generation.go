@@ -31,7 +31,9 @@ import (
 	//
 	"github.com/freepik-company/admitik/api/v1alpha1"
 	"github.com/freepik-company/admitik/internal/common"
+	"github.com/freepik-company/admitik/internal/controller"
 	"github.com/freepik-company/admitik/internal/globals"
+	policyCountersRegistry "github.com/freepik-company/admitik/internal/registry/policycounters"
 	policyStore "github.com/freepik-company/admitik/internal/registry/policystore"
 	sourcesRegistry "github.com/freepik-company/admitik/internal/registry/sources"
 	"github.com/freepik-company/admitik/internal/template"
@@ -40,6 +42,7 @@ import (
 type GenerationProcessorDependencies struct {
 	ClusterGenerationPolicyRegistry *policyStore.PolicyStore[*v1alpha1.ClusterGenerationPolicy]
 	SourcesRegistry                 *sourcesRegistry.SourcesRegistry
+	PolicyCountersRegistry          *policyCountersRegistry.PolicyCountersRegistry
 
 	//
 	KubeAvailableResourceList *[]GVKR
@@ -70,6 +73,11 @@ func (p *GenerationProcessor) Process(resourceType string, eventType watch.Event
 
 	if commonTemplateInjectedObject.Operation == common.NormalizedOperationUpdate {
 		commonTemplateInjectedObject.OldObject = object[1]
+
+		err = commonTemplateInjectedObject.PopulateChanges()
+		if err != nil {
+			logger.Info(fmt.Sprintf("failed computing changes between objects: %s", err.Error()))
+		}
 	}
 
 	//
@@ -88,6 +96,16 @@ func (p *GenerationProcessor) Process(resourceType string, eventType watch.Event
 
 		specificTemplateInjectedObject := commonTemplateInjectedObject
 		specificTemplateInjectedObject.Sources = tmpFetchedPolicySources
+		specificTemplateInjectedObject.Vars = make(map[string]any)
+
+		// Evaluate declared variables. They are available for conditions and later templates.
+		// Conditions can not be trusted without them, so nothing is generated by this policy
+		varsErr := common.PopulateVariables(policyObj.Spec.Variables, &specificTemplateInjectedObject)
+		if varsErr != nil {
+			logger.Info(fmt.Sprintf("failed evaluating variables: %s", varsErr.Error()))
+			p.accountPolicyEvaluation(controller.ClusterGenerationPolicyResourceType, policyObj.Name, true)
+			continue
+		}
 
 		//Evaluate template conditions
 		conditionsPassed, condErr := common.IsPassingConditions(policyObj.Spec.Conditions, &specificTemplateInjectedObject)
@@ -95,8 +113,11 @@ func (p *GenerationProcessor) Process(resourceType string, eventType watch.Event
 			logger.Info(fmt.Sprintf("failed evaluating conditions: %s", condErr.Error()))
 		}
 
+		evaluationFailed := condErr != nil
+
 		// Conditions are not met, skip generating the resource
 		if !conditionsPassed {
+			p.accountPolicyEvaluation(controller.ClusterGenerationPolicyResourceType, policyObj.Name, evaluationFailed)
 			continue
 		}
 
@@ -196,6 +217,7 @@ func (p *GenerationProcessor) Process(resourceType string, eventType watch.Event
 			goto updateResource
 		}
 
+		p.accountPolicyEvaluation(controller.ClusterGenerationPolicyResourceType, policyObj.Name, evaluationFailed)
 		continue
 
 	updateResource:
@@ -220,9 +242,12 @@ func (p *GenerationProcessor) Process(resourceType string, eventType watch.Event
 			kubeEventMessage = "Object update after template failed. More info in controller logs."
 			goto createKubeEvent
 		}
+
+		p.accountPolicyEvaluation(controller.ClusterGenerationPolicyResourceType, policyObj.Name, evaluationFailed)
 		continue
 
 	createKubeEvent:
+		p.accountPolicyEvaluation(controller.ClusterGenerationPolicyResourceType, policyObj.Name, true)
 		err = common.CreateKubeEvent(globals.Application.Context, "default", "resources-controller",
 			object[0], *policyObj, kubeEventAction, kubeEventMessage)
 		if err != nil {
@@ -230,3 +255,12 @@ func (p *GenerationProcessor) Process(resourceType string, eventType watch.Event
 		}
 	}
 }
+
+// accountPolicyEvaluation stores the result of evaluating a policy, to be flushed later into its status.
+// Generation policies are never violated, they just generate or fail
+func (p *GenerationProcessor) accountPolicyEvaluation(kind, name string, failed bool) {
+	if p.dependencies.PolicyCountersRegistry == nil {
+		return
+	}
+	p.dependencies.PolicyCountersRegistry.AddEvaluation(kind, name, false, failed)
+}
@@ -0,0 +1,244 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policystatus
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	//
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	//
+	"github.com/freepik-company/admitik/api/v1alpha1"
+	"github.com/freepik-company/admitik/internal/controller"
+	policyCountersRegistry "github.com/freepik-company/admitik/internal/registry/policycounters"
+	sourcesRegistry "github.com/freepik-company/admitik/internal/registry/sources"
+)
+
+const (
+	//
+	controllerName = "policystatus"
+
+	//
+	controllerContextFinishedMessage = "Controller finished by context"
+	policyListError                  = "Failed listing %s resources: %s"
+	policyStatusUpdateError          = "Failed updating runtime status of %s '%s': %s"
+)
+
+// PolicyStatusControllerOptions represents available options that can be passed to PolicyStatusController on start
+type PolicyStatusControllerOptions struct {
+	// Duration to wait between status updates
+	FlushInterval time.Duration
+}
+
+type PolicyStatusControllerDependencies struct {
+	Context *context.Context
+
+	// Elected is closed when this replica becomes the leader. Only the leader writes the state of the sources,
+	// as each replica has its own informers. When it's nil, the replica is considered the leader
+	Elected <-chan struct{}
+
+	//
+	PolicyCountersRegistry *policyCountersRegistry.PolicyCountersRegistry
+	SourcesRegistry        *sourcesRegistry.SourcesRegistry
+}
+
+// PolicyStatusController represents a controller that periodically writes the runtime status of the policies:
+// counters collected in memory by evaluators, and the state of the informers behind their sources.
+// This is done out of the reconciliation loop, as it changes without changes in the policies
+type PolicyStatusController struct {
+	// Following interface is just needed to register this controller into Controller Runtime manager and let it
+	// launch the controller across all the Admitik replicas or just in the elected leader.
+	manager.LeaderElectionRunnable
+
+	//
+	Client client.Client
+
+	Options      PolicyStatusControllerOptions
+	Dependencies PolicyStatusControllerDependencies
+}
+
+// runtimeStatusPolicyI represents a policy with a runtime status
+type runtimeStatusPolicyI interface {
+	client.Object
+	GetSources() []v1alpha1.SourceGroupT
+	GetRuntimeStatus() *v1alpha1.PolicyRuntimeStatusT
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable.
+// All the replicas evaluate policies, so all of them flush their counters.
+// Counters are added to the ones already written, so the status aggregates the whole fleet
+func (r *PolicyStatusController) NeedLeaderElection() bool {
+	return false
+}
+
+// Start launches the PolicyStatusController and keeps it alive.
+// Pending counters are flushed one last time on application's context death
+func (r *PolicyStatusController) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithValues("controller", controllerName)
+	logger.Info("Starting Controller")
+
+	ticker := time.NewTicker(r.Options.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info(controllerContextFinishedMessage)
+
+			// Context is dead at this point, so a short-lived one is used for the last flush
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			r.flush(flushCtx)
+			cancel()
+			return nil
+		case <-ticker.C:
+			r.flush(ctx)
+		}
+	}
+}
+
+// isLeader checks whether this replica is the elected leader
+func (r *PolicyStatusController) isLeader() bool {
+	if r.Dependencies.Elected == nil {
+		return true
+	}
+
+	select {
+	case <-r.Dependencies.Elected:
+		return true
+	default:
+		return false
+	}
+}
+
+// flush writes the runtime status of all the policies that changed since the last time.
+// Counters collected by this replica are added to the written ones, while the state of the sources
+// is only written by the leader, so replicas do not overwrite each other's view
+func (r *PolicyStatusController) flush(ctx context.Context) {
+	logger := log.FromContext(ctx).WithValues("controller", controllerName)
+
+	leader := r.isLeader()
+	counters := r.Dependencies.PolicyCountersRegistry.PopAll()
+
+	policyLists := map[string]client.ObjectList{
+		controller.ClusterValidationPolicyResourceType: &v1alpha1.ClusterValidationPolicyList{},
+		controller.ClusterMutationPolicyResourceType:   &v1alpha1.ClusterMutationPolicyList{},
+		controller.ClusterGenerationPolicyResourceType: &v1alpha1.ClusterGenerationPolicyList{},
+	}
+
+	for kind, policyList := range policyLists {
+		err := r.Client.List(ctx, policyList)
+		if err != nil {
+			logger.Info(fmt.Sprintf(policyListError, kind, err.Error()))
+			r.restoreCounters(kind, counters)
+			continue
+		}
+
+		for _, policy := range getPolicies(policyList) {
+			key := policyCountersRegistry.PolicyKeyT{Kind: kind, Name: policy.GetName()}
+			policyCounters := counters[key]
+			delete(counters, key)
+
+			var sourcesStatus []v1alpha1.PolicySourceStatusT
+			sourcesChanged := false
+			if leader {
+				sourcesStatus = r.getSourcesStatus(policy.GetSources())
+				sourcesChanged = !reflect.DeepEqual(sourcesStatus, policy.GetRuntimeStatus().Sources)
+			}
+
+			// Avoid updating policies with nothing new
+			if policyCounters == (policyCountersRegistry.CountersT{}) && !sourcesChanged {
+				continue
+			}
+
+			err = controller.UpdateStatusWithRetry(ctx, r.Client, policy, func(object client.Object) error {
+				runtimeStatus := object.(runtimeStatusPolicyI).GetRuntimeStatus()
+				if leader {
+					runtimeStatus.Sources = sourcesStatus
+				}
+				runtimeStatus.Counters.Evaluations += policyCounters.Evaluations
+				runtimeStatus.Counters.Violations += policyCounters.Violations
+				runtimeStatus.Counters.Errors += policyCounters.Errors
+				runtimeStatus.Counters.NonIdempotent += policyCounters.NonIdempotent
+
+				now := metav1.Now()
+				runtimeStatus.Counters.LastFlushTime = &now
+				return nil
+			})
+
+			// Give the counters back to try again later
+			if err != nil {
+				logger.Info(fmt.Sprintf(policyStatusUpdateError, kind, policy.GetName(), err.Error()))
+				r.Dependencies.PolicyCountersRegistry.Add(key, policyCounters)
+			}
+		}
+	}
+
+	// Remaining counters belong to deleted policies, so they are discarded
+}
+
+// restoreCounters gives back the counters of a kind of policies to the registry, to try again later
+func (r *PolicyStatusController) restoreCounters(kind string, counters map[policyCountersRegistry.PolicyKeyT]policyCountersRegistry.CountersT) {
+	for key, policyCounters := range counters {
+		if key.Kind == kind {
+			r.Dependencies.PolicyCountersRegistry.Add(key, policyCounters)
+		}
+	}
+}
+
+// getSourcesStatus return the state of the informers behind a list of sources
+func (r *PolicyStatusController) getSourcesStatus(sources []v1alpha1.SourceGroupT) (result []v1alpha1.PolicySourceStatusT) {
+	for _, source := range sources {
+		resourceType := strings.Join([]string{source.Group, source.Version, source.Resource}, "/")
+
+		result = append(result, v1alpha1.PolicySourceStatusT{
+			GroupVersionResource: source.GroupVersionResource,
+			Started:              r.Dependencies.SourcesRegistry.IsStarted(resourceType),
+			Synced:               r.Dependencies.SourcesRegistry.IsSynced(resourceType),
+			Items:                len(r.Dependencies.SourcesRegistry.GetResources(resourceType)),
+		})
+	}
+
+	return result
+}
+
+// getPolicies return the items of a list of policies as objects with runtime status
+func getPolicies(policyList client.ObjectList) (result []runtimeStatusPolicyI) {
+	switch typedList := policyList.(type) {
+	case *v1alpha1.ClusterValidationPolicyList:
+		for index := range typedList.Items {
+			result = append(result, &typedList.Items[index])
+		}
+	case *v1alpha1.ClusterMutationPolicyList:
+		for index := range typedList.Items {
+			result = append(result, &typedList.Items[index])
+		}
+	case *v1alpha1.ClusterGenerationPolicyList:
+		for index := range typedList.Items {
+			result = append(result, &typedList.Items[index])
+		}
+	}
+
+	return result
+}
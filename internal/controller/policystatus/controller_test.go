@@ -0,0 +1,224 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policystatus
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	//
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	//
+	"github.com/freepik-company/admitik/api/v1alpha1"
+	"github.com/freepik-company/admitik/internal/controller"
+	policyCountersRegistry "github.com/freepik-company/admitik/internal/registry/policycounters"
+	sourcesRegistry "github.com/freepik-company/admitik/internal/registry/sources"
+)
+
+// getTestClient return a fake client that already contains the given policies
+func getTestClient(t *testing.T, funcs interceptor.Funcs, policies ...client.Object) client.Client {
+	scheme := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(policies...).
+		WithStatusSubresource(policies...).
+		WithInterceptorFuncs(funcs).
+		Build()
+}
+
+// getTestController return a controller for a replica, flushing its own counters into the given client
+func getTestController(kubeClient client.Client, leader bool) *PolicyStatusController {
+	elected := make(chan struct{})
+	if leader {
+		close(elected)
+	}
+
+	return &PolicyStatusController{
+		Client: kubeClient,
+		Dependencies: PolicyStatusControllerDependencies{
+			Elected:                elected,
+			PolicyCountersRegistry: policyCountersRegistry.NewPolicyCountersRegistry(),
+			SourcesRegistry:        sourcesRegistry.NewSourcesRegistry(),
+		},
+	}
+}
+
+// getTestPolicy return a ClusterValidationPolicy with a source, whose status was written by another replica
+func getTestPolicy() *v1alpha1.ClusterValidationPolicy {
+	policy := &v1alpha1.ClusterValidationPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "example"},
+		Spec: v1alpha1.ClusterValidationPolicySpec{
+			Sources: []v1alpha1.SourceGroupT{{
+				GroupVersionResource: metav1.GroupVersionResource{Version: "v1", Resource: "configmaps"},
+			}},
+		},
+	}
+
+	policy.Status.Sources = []v1alpha1.PolicySourceStatusT{{
+		GroupVersionResource: metav1.GroupVersionResource{Version: "v1", Resource: "configmaps"},
+		Started:              true,
+		Synced:               true,
+		Items:                7,
+	}}
+	policy.Status.Counters = v1alpha1.PolicyCountersT{Evaluations: 10, Violations: 2}
+
+	return policy
+}
+
+// getTestPolicyStatus return the runtime status written for the test policy
+func getTestPolicyStatus(t *testing.T, kubeClient client.Client) v1alpha1.PolicyRuntimeStatusT {
+	policy := &v1alpha1.ClusterValidationPolicy{}
+	err := kubeClient.Get(context.Background(), client.ObjectKey{Name: "example"}, policy)
+	if err != nil {
+		t.Fatalf("unexpected error getting the policy: %v", err)
+	}
+	return policy.Status.PolicyRuntimeStatusT
+}
+
+func TestFlushAddsCountersOfAllReplicas(t *testing.T) {
+	kubeClient := getTestClient(t, interceptor.Funcs{}, getTestPolicy())
+
+	replicas := []*PolicyStatusController{
+		getTestController(kubeClient, true),
+		getTestController(kubeClient, false),
+	}
+
+	replicas[0].Dependencies.PolicyCountersRegistry.AddEvaluation(controller.ClusterValidationPolicyResourceType, "example", true, false)
+	replicas[1].Dependencies.PolicyCountersRegistry.AddEvaluation(controller.ClusterValidationPolicyResourceType, "example", false, true)
+	replicas[1].Dependencies.PolicyCountersRegistry.AddEvaluation(controller.ClusterValidationPolicyResourceType, "example", false, false)
+
+	for _, replica := range replicas {
+		replica.flush(context.Background())
+	}
+
+	counters := getTestPolicyStatus(t, kubeClient).Counters
+	if counters.Evaluations != 13 || counters.Violations != 3 || counters.Errors != 1 {
+		t.Errorf("expected counters to be added to the written ones, got %+v", counters)
+	}
+	if counters.LastFlushTime == nil {
+		t.Errorf("expected last flush time to be set")
+	}
+
+	// Flushed counters are not written twice
+	for _, replica := range replicas {
+		replica.flush(context.Background())
+	}
+
+	counters = getTestPolicyStatus(t, kubeClient).Counters
+	if counters.Evaluations != 13 {
+		t.Errorf("expected counters not to change without new evaluations, got %+v", counters)
+	}
+}
+
+func TestFlushSourcesOnlyFromLeader(t *testing.T) {
+	tests := []struct {
+		name   string
+		leader bool
+
+		expectedItems int
+	}{
+		{
+			name:          "followers keep the state written by the leader",
+			leader:        false,
+			expectedItems: 7,
+		},
+		{
+			name:          "leader writes its own state",
+			leader:        true,
+			expectedItems: 0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			kubeClient := getTestClient(t, interceptor.Funcs{}, getTestPolicy())
+			replica := getTestController(kubeClient, test.leader)
+			replica.Dependencies.PolicyCountersRegistry.AddEvaluation(controller.ClusterValidationPolicyResourceType, "example", false, false)
+
+			replica.flush(context.Background())
+
+			status := getTestPolicyStatus(t, kubeClient)
+			if len(status.Sources) != 1 || status.Sources[0].Items != test.expectedItems {
+				t.Errorf("expected sources with %d items, got %+v", test.expectedItems, status.Sources)
+			}
+			if status.Counters.Evaluations != 11 {
+				t.Errorf("expected counters to be written, got %+v", status.Counters)
+			}
+		})
+	}
+}
+
+func TestFlushSkipsPoliciesWithNothingNew(t *testing.T) {
+	policy := getTestPolicy()
+	policy.Status.Sources = nil
+	policy.Spec.Sources = nil
+
+	updates := 0
+	kubeClient := getTestClient(t, interceptor.Funcs{
+		SubResourceUpdate: func(ctx context.Context, c client.Client, subResourceName string, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+			updates++
+			return c.SubResource(subResourceName).Update(ctx, obj, opts...)
+		},
+	}, policy)
+
+	getTestController(kubeClient, true).flush(context.Background())
+	getTestController(kubeClient, false).flush(context.Background())
+
+	if updates != 0 {
+		t.Errorf("expected no status updates, got %d", updates)
+	}
+}
+
+func TestFlushRestoresCountersOnFailure(t *testing.T) {
+	kubeClient := getTestClient(t, interceptor.Funcs{
+		SubResourceUpdate: func(ctx context.Context, c client.Client, subResourceName string, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+			return errors.New("status is not available")
+		},
+	}, getTestPolicy())
+
+	replica := getTestController(kubeClient, false)
+	replica.Dependencies.PolicyCountersRegistry.AddEvaluation(controller.ClusterValidationPolicyResourceType, "example", true, false)
+
+	// Counters of deleted policies are discarded
+	replica.Dependencies.PolicyCountersRegistry.AddEvaluation(controller.ClusterValidationPolicyResourceType, "deleted", false, false)
+
+	replica.flush(context.Background())
+
+	counters := replica.Dependencies.PolicyCountersRegistry.PopAll()
+	expectedCounters := map[policyCountersRegistry.PolicyKeyT]policyCountersRegistry.CountersT{
+		{Kind: controller.ClusterValidationPolicyResourceType, Name: "example"}: {Evaluations: 1, Violations: 1},
+	}
+
+	if len(counters) != len(expectedCounters) {
+		t.Fatalf("expected counters %v, got %v", expectedCounters, counters)
+	}
+	for key, expected := range expectedCounters {
+		if counters[key] != expected {
+			t.Errorf("expected counters %+v for '%s', got %+v", expected, key.Name, counters[key])
+		}
+	}
+}
@@ -215,6 +215,7 @@ func (r *SourcesController) launchInformerForType(resourceType sourcesRegistry.R
 	_ = r.Dependencies.SourcesRegistry.SetStarted(resourceType, true)
 	defer func() {
 		_ = r.Dependencies.SourcesRegistry.SetStarted(resourceType, false)
+		_ = r.Dependencies.SourcesRegistry.SetSynced(resourceType, false)
 	}()
 
 	// Extract GVR + Namespace + Name from watched type:
@@ -286,6 +287,13 @@ func (r *SourcesController) launchInformerForType(resourceType sourcesRegistry.R
 		return
 	}
 
+	// Flag the informer as synced once the initial listing is done
+	go func() {
+		if cache.WaitForCacheSync(stopCh, kubeInformer.HasSynced) {
+			_ = r.Dependencies.SourcesRegistry.SetSynced(resourceType, true)
+		}
+	}()
+
 	kubeInformer.Run(stopCh)
 }
 
@@ -0,0 +1,164 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package templatelibrary
+
+import (
+	"context"
+	"fmt"
+
+	//
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	controllerRuntimeController "sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	//
+	"github.com/freepik-company/admitik/api/v1alpha1"
+	"github.com/freepik-company/admitik/internal/controller"
+	templateLibraryRegistry "github.com/freepik-company/admitik/internal/registry/templatelibrary"
+)
+
+type TemplateLibraryControllerOptions struct{}
+
+type TemplateLibraryControllerDependencies struct {
+	TemplateLibraryRegistry *templateLibraryRegistry.TemplateLibraryRegistry
+
+	// Channels watched by policy controllers. Policies referencing a library are sent through them
+	// once the registry is up-to-date
+	ClusterValidationPolicyEvents chan<- event.GenericEvent
+	ClusterMutationPolicyEvents   chan<- event.GenericEvent
+	ClusterGenerationPolicyEvents chan<- event.GenericEvent
+}
+
+// TemplateLibraryReconciler reconciles a TemplateLibrary object
+type TemplateLibraryReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	//
+	Options      TemplateLibraryControllerOptions
+	Dependencies TemplateLibraryControllerDependencies
+}
+
+// +kubebuilder:rbac:groups=admitik.dev,resources=templatelibraries,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=admitik.dev,resources=templatelibraries/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=admitik.dev,resources=templatelibraries/finalizers,verbs=update
+// +kubebuilder:rbac:groups=admitik.dev,resources=clustervalidationpolicies;clustermutationpolicies;clustergenerationpolicies,verbs=get;list;watch
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.20.2/pkg/reconcile
+func (r *TemplateLibraryReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	logger := log.FromContext(ctx)
+
+	// 1. Get the content of the resource
+	objectManifest := &v1alpha1.TemplateLibrary{}
+	err = r.Get(ctx, req.NamespacedName, objectManifest)
+
+	// 2. Check the existence inside the cluster
+	if err != nil {
+
+		// 2.1 It does NOT exist: manage removal
+		if err = client.IgnoreNotFound(err); err == nil {
+			logger.Info(fmt.Sprintf(controller.ResourceNotFoundError, controller.TemplateLibraryResourceType, req.Name))
+			return result, err
+		}
+
+		// 2.2 Failed to get the resource, requeue the request
+		logger.Info(fmt.Sprintf(controller.ResourceRetrievalError, controller.TemplateLibraryResourceType, req.Name, err.Error()))
+		return result, err
+	}
+
+	// 3. Check if the resource instance is marked to be deleted: indicated by the deletion timestamp being set
+	if !objectManifest.DeletionTimestamp.IsZero() {
+		if controllerutil.ContainsFinalizer(objectManifest, controller.ResourceFinalizer) {
+			// Delete library from the registry
+			err = r.ReconcileTemplateLibrary(ctx, watch.Deleted, objectManifest)
+			if err != nil {
+				logger.Info(fmt.Sprintf(controller.ResourceReconcileError, controller.TemplateLibraryResourceType, req.Name, err.Error()))
+				return result, err
+			}
+
+			// Remove the finalizers on the resource
+			err = controller.UpdateWithRetry(ctx, r.Client, objectManifest, func(object client.Object) error {
+				controllerutil.RemoveFinalizer(object, controller.ResourceFinalizer)
+				return nil
+			})
+			if err != nil {
+				logger.Info(fmt.Sprintf(controller.ResourceFinalizersUpdateError, controller.TemplateLibraryResourceType, req.Name, err.Error()))
+			}
+		}
+		result = ctrl.Result{}
+		err = nil
+		return result, err
+	}
+
+	// 4. Add finalizer to the resource
+	if !controllerutil.ContainsFinalizer(objectManifest, controller.ResourceFinalizer) {
+		err = controller.UpdateWithRetry(ctx, r.Client, objectManifest, func(object client.Object) error {
+			controllerutil.AddFinalizer(objectManifest, controller.ResourceFinalizer)
+			return nil
+		})
+		if err != nil {
+			return result, err
+		}
+	}
+
+	// 5. Update the status before the requeue
+	defer func() {
+		desiredStatus := objectManifest.Status
+
+		statusErr := controller.UpdateStatusWithRetry(ctx, r.Client, objectManifest, func(object client.Object) error {
+			object.(*v1alpha1.TemplateLibrary).Status = desiredStatus
+			return nil
+		})
+		if statusErr != nil {
+			logger.Info(fmt.Sprintf(controller.ResourceConditionUpdateError, controller.TemplateLibraryResourceType, req.Name, statusErr.Error()))
+		}
+	}()
+
+	// 6. The resource already exists: manage the update
+	err = r.ReconcileTemplateLibrary(ctx, watch.Modified, objectManifest)
+	if err != nil {
+		r.UpdateConditionInvalidTemplates(objectManifest, err)
+		logger.Info(fmt.Sprintf(controller.ResourceReconcileError, controller.TemplateLibraryResourceType, req.Name, err.Error()))
+		return result, nil
+	}
+
+	// 7. Success, update the status
+	r.UpdateConditionSuccess(objectManifest)
+
+	return result, err
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *TemplateLibraryReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.TemplateLibrary{}).
+		WithEventFilter(predicate.GenerationChangedPredicate{}).
+		WithOptions(controllerRuntimeController.Options{
+			NeedLeaderElection: pointer.Bool(false),
+		}).
+		Complete(r)
+}
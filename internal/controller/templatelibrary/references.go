@@ -0,0 +1,211 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package templatelibrary
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+
+	//
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	//
+	"github.com/freepik-company/admitik/api/v1alpha1"
+	"github.com/freepik-company/admitik/internal/controller"
+	"github.com/freepik-company/admitik/internal/template"
+)
+
+// getLibraryReferences return the names policies can use to reference the items of a library:
+// '{library}/{name}' for each item, and the names of the blocks defined inside Go templates
+func getLibraryReferences(library *v1alpha1.TemplateLibrary) (references []string) {
+	if library == nil {
+		return references
+	}
+
+	for _, item := range library.Spec.Gotmpl {
+		references = append(references, library.Name+"/"+item.Name)
+	}
+
+	for _, block := range getLibraryGotmplBlocks(library) {
+		references = append(references, block.name)
+	}
+
+	for _, item := range library.Spec.Starlark {
+		references = append(references, library.Name+"/"+item.Name)
+	}
+
+	return references
+}
+
+// libraryGotmplBlockT represents a block defined inside an item of a library
+type libraryGotmplBlockT struct {
+	name string
+	item string
+}
+
+// getLibraryGotmplBlocks return the blocks defined inside the Go templates of a library, in order.
+// Broken items are skipped, as they are reported on their own
+func getLibraryGotmplBlocks(library *v1alpha1.TemplateLibrary) (blocks []libraryGotmplBlockT) {
+	for _, item := range library.Spec.Gotmpl {
+		itemName := library.Name + "/" + item.Name
+
+		parsedTemplate, err := template.ParseLibraryTemplateGotmpl(itemName, item.Template)
+		if err != nil {
+			continue
+		}
+
+		blockNames := []string{}
+		for _, block := range parsedTemplate.Templates() {
+			if block.Name() != itemName {
+				blockNames = append(blockNames, block.Name())
+			}
+		}
+		slices.Sort(blockNames)
+
+		for _, blockName := range blockNames {
+			blocks = append(blocks, libraryGotmplBlockT{name: blockName, item: item.Name})
+		}
+	}
+
+	return blocks
+}
+
+// specReferencesAny checks whether some string inside a policy's spec contains one of the references
+// between the quotes accepted by template engines
+func specReferencesAny(spec any, references []string) bool {
+	if len(references) == 0 {
+		return false
+	}
+
+	specBytes, err := json.Marshal(spec)
+	if err != nil {
+		return false
+	}
+
+	var specObject any
+	if err = json.Unmarshal(specBytes, &specObject); err != nil {
+		return false
+	}
+
+	return walkStrings(specObject, func(value string) bool {
+		for _, reference := range references {
+			for _, quote := range []string{`"`, `'`, "`"} {
+				if strings.Contains(value, quote+reference+quote) {
+					return true
+				}
+			}
+		}
+		return false
+	})
+}
+
+// walkStrings calls the matcher with each string inside a decoded JSON object.
+// It stops and returns true as soon as the matcher does
+func walkStrings(object any, matcher func(string) bool) bool {
+	switch typedObject := object.(type) {
+	case string:
+		return matcher(typedObject)
+	case map[string]any:
+		for _, value := range typedObject {
+			if walkStrings(value, matcher) {
+				return true
+			}
+		}
+	case []any:
+		for _, value := range typedObject {
+			if walkStrings(value, matcher) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// enqueueReferencingPolicies asks policy controllers to reconcile the policies referencing some of the given names.
+// It's called once the registry is up-to-date, so policies are validated and tested against the new libraries
+func (r *TemplateLibraryReconciler) enqueueReferencingPolicies(ctx context.Context, references []string) error {
+	if len(references) == 0 {
+		return nil
+	}
+
+	validationPolicies := &v1alpha1.ClusterValidationPolicyList{}
+	if err := r.List(ctx, validationPolicies); err != nil {
+		return err
+	}
+	for i := range validationPolicies.Items {
+		if !specReferencesAny(validationPolicies.Items[i].Spec, references) {
+			continue
+		}
+		err := sendPolicyEvent(ctx, r.Dependencies.ClusterValidationPolicyEvents,
+			controller.ClusterValidationPolicyResourceType, &validationPolicies.Items[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	mutationPolicies := &v1alpha1.ClusterMutationPolicyList{}
+	if err := r.List(ctx, mutationPolicies); err != nil {
+		return err
+	}
+	for i := range mutationPolicies.Items {
+		if !specReferencesAny(mutationPolicies.Items[i].Spec, references) {
+			continue
+		}
+		err := sendPolicyEvent(ctx, r.Dependencies.ClusterMutationPolicyEvents,
+			controller.ClusterMutationPolicyResourceType, &mutationPolicies.Items[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	generationPolicies := &v1alpha1.ClusterGenerationPolicyList{}
+	if err := r.List(ctx, generationPolicies); err != nil {
+		return err
+	}
+	for i := range generationPolicies.Items {
+		if !specReferencesAny(generationPolicies.Items[i].Spec, references) {
+			continue
+		}
+		err := sendPolicyEvent(ctx, r.Dependencies.ClusterGenerationPolicyEvents,
+			controller.ClusterGenerationPolicyResourceType, &generationPolicies.Items[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sendPolicyEvent sends a policy to the channel watched by its controller
+func sendPolicyEvent(ctx context.Context, events chan<- event.GenericEvent, kind string, object client.Object) error {
+	if events == nil {
+		return nil
+	}
+
+	log.FromContext(ctx).Info(policyEnqueuedMessage, "kind", kind, "policy", object.GetName())
+
+	select {
+	case events <- event.GenericEvent{Object: object}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
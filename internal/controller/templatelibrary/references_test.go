@@ -0,0 +1,89 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package templatelibrary
+
+import (
+	"testing"
+
+	//
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	//
+	"github.com/freepik-company/admitik/api/v1alpha1"
+)
+
+func TestSpecReferencesAny(t *testing.T) {
+	library := &v1alpha1.TemplateLibrary{
+		ObjectMeta: metav1.ObjectMeta{Name: "shared"},
+		Spec: v1alpha1.TemplateLibrarySpec{
+			Gotmpl: []v1alpha1.LibraryTemplateT{
+				{Name: "helpers", Template: `{{- define "team.label" -}}team{{- end -}}`},
+			},
+			Starlark: []v1alpha1.LibraryTemplateT{
+				{Name: "naming", Template: `def prefix(name): return "x-" + name`},
+			},
+		},
+	}
+	references := getLibraryReferences(library)
+
+	tests := []struct {
+		name     string
+		template string
+		expected bool
+	}{
+		{
+			name:     "gotmpl block included by name",
+			template: `{{ include "team.label" . }}`,
+			expected: true,
+		},
+		{
+			name:     "gotmpl item rendered by library path",
+			template: "{{ template `shared/helpers` . }}",
+			expected: true,
+		},
+		{
+			name:     "starlark module loaded",
+			template: `load('shared/naming', 'prefix')`,
+			expected: true,
+		},
+		{
+			name:     "block of another library",
+			template: `{{ include "other.label" . }}`,
+			expected: false,
+		},
+		{
+			name:     "reference name without quotes",
+			template: `team.label is mentioned in a comment`,
+			expected: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			spec := v1alpha1.ClusterValidationPolicySpec{
+				Conditions: []v1alpha1.ConditionT{
+					{Name: "check", Engine: "gotmpl", Key: test.template, Value: "true"},
+				},
+			}
+
+			result := specReferencesAny(spec, references)
+			if result != test.expected {
+				t.Errorf("expected %v, got %v", test.expected, result)
+			}
+		})
+	}
+}
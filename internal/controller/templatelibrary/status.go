@@ -0,0 +1,46 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package templatelibrary
+
+import (
+	"fmt"
+
+	//
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	//
+	"github.com/freepik-company/admitik/api/v1alpha1"
+	"github.com/freepik-company/admitik/internal/controller"
+)
+
+func (r *TemplateLibraryReconciler) UpdateConditionSuccess(library *v1alpha1.TemplateLibrary) {
+
+	//
+	condition := controller.NewCondition(controller.ConditionTypeResourceSynced, metav1.ConditionTrue,
+		controller.ConditionReasonTargetSynced, controller.ConditionReasonTargetSyncedMessage)
+
+	controller.UpdateCondition(&library.Status.Conditions, condition)
+}
+
+func (r *TemplateLibraryReconciler) UpdateConditionInvalidTemplates(library *v1alpha1.TemplateLibrary, err error) {
+
+	//
+	condition := controller.NewCondition(controller.ConditionTypeResourceSynced, metav1.ConditionFalse,
+		controller.ConditionReasonInvalidTemplatesType, fmt.Sprintf(controller.ConditionReasonInvalidTemplatesMessage, err.Error()))
+
+	controller.UpdateCondition(&library.Status.Conditions, condition)
+}
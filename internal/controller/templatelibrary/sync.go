@@ -0,0 +1,125 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package templatelibrary
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	//
+	"k8s.io/apimachinery/pkg/watch"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	//
+	"github.com/freepik-company/admitik/api/v1alpha1"
+	"github.com/freepik-company/admitik/internal/template"
+)
+
+const (
+
+	//
+	resourceUpdatedMessage  = "A TemplateLibrary was modified: will be updated into the internal registry"
+	resourceDeletionMessage = "A TemplateLibrary was deleted: will be deleted from internal registry"
+	policyEnqueuedMessage   = "A policy referencing the TemplateLibrary will be reconciled"
+)
+
+// ReconcileTemplateLibrary keeps internal TemplateLibrary resources' registry up-to-date.
+// Libraries are stored even when some of their items are broken, as the engines skip them on evaluation.
+// This way, a typo does not break the rest of items already used by policies
+func (r *TemplateLibraryReconciler) ReconcileTemplateLibrary(ctx context.Context, eventType watch.EventType, resourceManifest *v1alpha1.TemplateLibrary) (err error) {
+	logger := log.FromContext(ctx)
+
+	// Policies referencing items that existed before this change are reconciled too,
+	// as removed or renamed items break them
+	previousLibrary, _ := r.Dependencies.TemplateLibraryRegistry.GetLibrary(resourceManifest.Name)
+	references := append(getLibraryReferences(previousLibrary), getLibraryReferences(resourceManifest)...)
+
+	// Handle deletion requests
+	if eventType == watch.Deleted {
+		logger.Info(resourceDeletionMessage, "library", resourceManifest.Name)
+		r.Dependencies.TemplateLibraryRegistry.RemoveLibrary(resourceManifest.Name)
+		return r.enqueueReferencingPolicies(ctx, references)
+	}
+
+	// Handle creation/update requests
+	logger.Info(resourceUpdatedMessage, "library", resourceManifest.Name)
+	r.Dependencies.TemplateLibraryRegistry.AddOrUpdateLibrary(resourceManifest)
+
+	err = r.enqueueReferencingPolicies(ctx, references)
+	if err != nil {
+		return err
+	}
+
+	return errors.Join(
+		checkTemplateLibrary(resourceManifest),
+		checkDuplicatedGotmplBlocks(resourceManifest, r.Dependencies.TemplateLibraryRegistry.GetLibraries()),
+	)
+}
+
+// checkTemplateLibrary parses all the items of a library, returning an error describing the broken ones
+func checkTemplateLibrary(resourceManifest *v1alpha1.TemplateLibrary) error {
+	var errorList []error
+
+	for _, item := range resourceManifest.Spec.Gotmpl {
+		_, err := template.ParseLibraryTemplateGotmpl(resourceManifest.Name+"/"+item.Name, item.Template)
+		if err != nil {
+			errorList = append(errorList, fmt.Errorf("gotmpl '%s': %s", item.Name, err.Error()))
+		}
+	}
+
+	for _, item := range resourceManifest.Spec.Starlark {
+		err := template.ParseLibraryModuleStarlark(resourceManifest.Name+"/"+item.Name, item.Template)
+		if err != nil {
+			errorList = append(errorList, fmt.Errorf("starlark '%s': %s", item.Name, err.Error()))
+		}
+	}
+
+	return errors.Join(errorList...)
+}
+
+// checkDuplicatedGotmplBlocks return an error describing the blocks of a library that are defined more than once,
+// in the library itself or in other ones. Blocks share a single namespace, so only one of the definitions is used:
+// the one from the last '{library}/{name}' item in alphabetical order
+func checkDuplicatedGotmplBlocks(resourceManifest *v1alpha1.TemplateLibrary, libraries []*v1alpha1.TemplateLibrary) error {
+	var errorList []error
+
+	definedBlocks := map[string]string{}
+	for _, block := range getLibraryGotmplBlocks(resourceManifest) {
+		if previousItem, found := definedBlocks[block.name]; found {
+			errorList = append(errorList, fmt.Errorf("gotmpl '%s': block '%s' is also defined in item '%s'",
+				block.item, block.name, previousItem))
+			continue
+		}
+		definedBlocks[block.name] = block.item
+	}
+
+	for _, library := range libraries {
+		if library.Name == resourceManifest.Name {
+			continue
+		}
+
+		for _, block := range getLibraryGotmplBlocks(library) {
+			if item, found := definedBlocks[block.name]; found {
+				errorList = append(errorList, fmt.Errorf("gotmpl '%s': block '%s' is also defined in library '%s'",
+					item, block.name, library.Name))
+			}
+		}
+	}
+
+	return errors.Join(errorList...)
+}
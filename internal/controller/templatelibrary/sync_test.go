@@ -0,0 +1,122 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package templatelibrary
+
+import (
+	"strings"
+	"testing"
+
+	//
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	//
+	"github.com/freepik-company/admitik/api/v1alpha1"
+)
+
+func getTestGotmplLibrary(name string, templates ...string) *v1alpha1.TemplateLibrary {
+	library := &v1alpha1.TemplateLibrary{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	for index, templateString := range templates {
+		library.Spec.Gotmpl = append(library.Spec.Gotmpl, v1alpha1.LibraryTemplateT{
+			Name:     "item" + string(rune('a'+index)),
+			Template: templateString,
+		})
+	}
+	return library
+}
+
+func TestCheckDuplicatedGotmplBlocks(t *testing.T) {
+	tests := []struct {
+		name      string
+		library   *v1alpha1.TemplateLibrary
+		libraries []*v1alpha1.TemplateLibrary
+
+		// expectedErrs are parts of the expected error message. Empty when no error is expected
+		expectedErrs []string
+	}{
+		{
+			name:    "blocks with different names",
+			library: getTestGotmplLibrary("shared", `{{ define "team.label" }}team{{ end }}`),
+			libraries: []*v1alpha1.TemplateLibrary{
+				getTestGotmplLibrary("other", `{{ define "owner.label" }}owner{{ end }}`),
+			},
+		},
+		{
+			name:    "block defined in another library",
+			library: getTestGotmplLibrary("shared", `{{ define "team.label" }}team{{ end }}`),
+			libraries: []*v1alpha1.TemplateLibrary{
+				getTestGotmplLibrary("other", `{{ define "team.label" }}other{{ end }}`),
+			},
+			expectedErrs: []string{"gotmpl 'itema': block 'team.label' is also defined in library 'other'"},
+		},
+		{
+			name: "block defined twice in the same library",
+			library: getTestGotmplLibrary("shared",
+				`{{ define "team.label" }}team{{ end }}`,
+				`{{ define "team.label" }}again{{ end }}`,
+			),
+			expectedErrs: []string{"gotmpl 'itemb': block 'team.label' is also defined in item 'itema'"},
+		},
+		{
+			name:    "previous version of the same library is ignored",
+			library: getTestGotmplLibrary("shared", `{{ define "team.label" }}team{{ end }}`),
+			libraries: []*v1alpha1.TemplateLibrary{
+				getTestGotmplLibrary("shared", `{{ define "team.label" }}previous{{ end }}`),
+			},
+		},
+		{
+			name:    "broken items are ignored",
+			library: getTestGotmplLibrary("shared", `{{ define "team.label" }}team{{ end }}`),
+			libraries: []*v1alpha1.TemplateLibrary{
+				getTestGotmplLibrary("other", `{{ define "team.label" }}{{ .broken `),
+			},
+		},
+		{
+			name:    "every duplicated block is reported",
+			library: getTestGotmplLibrary("shared", `{{ define "team.label" }}team{{ end }}{{ define "owner.label" }}owner{{ end }}`),
+			libraries: []*v1alpha1.TemplateLibrary{
+				getTestGotmplLibrary("first", `{{ define "owner.label" }}first{{ end }}`),
+				getTestGotmplLibrary("second", `{{ define "team.label" }}second{{ end }}`),
+			},
+			expectedErrs: []string{
+				"block 'owner.label' is also defined in library 'first'",
+				"block 'team.label' is also defined in library 'second'",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := checkDuplicatedGotmplBlocks(test.library, test.libraries)
+
+			if len(test.expectedErrs) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("expected error containing %v, got nil", test.expectedErrs)
+			}
+			for _, expectedErr := range test.expectedErrs {
+				if !strings.Contains(err.Error(), expectedErr) {
+					t.Errorf("expected error containing '%s', got: %v", expectedErr, err)
+				}
+			}
+		})
+	}
+}
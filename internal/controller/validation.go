@@ -0,0 +1,123 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	//
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+
+	//
+	"github.com/freepik-company/admitik/api/v1alpha1"
+	"github.com/freepik-company/admitik/internal/template"
+)
+
+// CheckTemplate parses a template of some field, returning an error describing what is broken
+func CheckTemplate(field string, engine string, templateString string) error {
+	err := template.ValidateTemplate(engine, templateString)
+	if err != nil {
+		return fmt.Errorf("%s: %s", field, err.Error())
+	}
+	return nil
+}
+
+// CheckSources compiles the regular expressions used in sources' filters
+func CheckSources(sourceList []v1alpha1.SourceGroupT) (errorList []error) {
+	for index, source := range sourceList {
+		if source.Filters == nil {
+			continue
+		}
+
+		checkRegex := func(filterName string, regex *v1alpha1.SourceGroupFiltersRegexT) {
+			if regex == nil {
+				return
+			}
+
+			_, err := regexp.Compile(regex.Expression)
+			if err != nil {
+				errorList = append(errorList, fmt.Errorf("source %d %s filter: %s", index, filterName, err.Error()))
+			}
+		}
+
+		if source.Filters.Namespace != nil {
+			checkRegex("namespace", source.Filters.Namespace.MatchRegex)
+		}
+		if source.Filters.Name != nil {
+			checkRegex("name", source.Filters.Name.MatchRegex)
+		}
+	}
+
+	return errorList
+}
+
+// CheckVariables parses the templates of the variables
+func CheckVariables(variableList []v1alpha1.VariableT) (errorList []error) {
+	for _, variable := range variableList {
+		err := CheckTemplate(fmt.Sprintf("variable '%s'", variable.Name), variable.Engine, variable.Template)
+		if err != nil {
+			errorList = append(errorList, err)
+		}
+	}
+	return errorList
+}
+
+// CheckConditions parses the templates of the conditions
+func CheckConditions(conditionList []v1alpha1.ConditionT) (errorList []error) {
+	for _, condition := range conditionList {
+		err := CheckTemplate(fmt.Sprintf("condition '%s'", condition.Name), condition.Engine, condition.Key)
+		if err != nil {
+			errorList = append(errorList, err)
+		}
+	}
+	return errorList
+}
+
+// CheckTests validates the fixtures of the tests. Their outcome is checked later, running them
+func CheckTests(testList []v1alpha1.PolicyTestT) (errorList []error) {
+	operations := []admissionregv1.OperationType{
+		admissionregv1.Create, admissionregv1.Update, admissionregv1.Delete, admissionregv1.Connect}
+
+	for _, test := range testList {
+		if test.Operation != "" && !slices.Contains(operations, test.Operation) {
+			errorList = append(errorList, fmt.Errorf("test '%s': unknown operation '%s'", test.Name, test.Operation))
+		}
+
+		if test.Operation == admissionregv1.Update && test.OldObject == "" {
+			errorList = append(errorList, fmt.Errorf("test '%s': oldObject is required for UPDATE operations", test.Name))
+		}
+	}
+	return errorList
+}
+
+// CheckTestResults returns an error naming the failed tests, if any
+func CheckTestResults(resultList []v1alpha1.PolicyTestResultT) error {
+	var failedTests []string
+	for _, result := range resultList {
+		if !result.Passed {
+			failedTests = append(failedTests, fmt.Sprintf("'%s' (%s)", result.Name, result.Message))
+		}
+	}
+
+	if len(failedTests) > 0 {
+		return fmt.Errorf("%d of %d tests failed: %s", len(failedTests), len(resultList), strings.Join(failedTests, ", "))
+	}
+	return nil
+}
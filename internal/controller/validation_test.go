@@ -0,0 +1,176 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	//
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+
+	//
+	"github.com/freepik-company/admitik/api/v1alpha1"
+)
+
+// validationTestT represents a check over some fields of a policy
+type validationTestT struct {
+	name  string
+	check func() []error
+
+	// expectedErrs are parts of the expected error messages, in order. Empty when no error is expected
+	expectedErrs []string
+}
+
+// runValidationTests runs the checks, comparing the errors with the expected ones
+func runValidationTests(t *testing.T, tests []validationTestT) {
+	t.Helper()
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			errorList := test.check()
+
+			if len(errorList) != len(test.expectedErrs) {
+				t.Fatalf("expected errors %v, got: %v", test.expectedErrs, errors.Join(errorList...))
+			}
+			for i, expectedErr := range test.expectedErrs {
+				if !strings.Contains(errorList[i].Error(), expectedErr) {
+					t.Errorf("expected error containing '%s', got: %v", expectedErr, errorList[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCheckSources(t *testing.T) {
+	regexFilters := func(namespaceRegex, nameRegex string) []v1alpha1.SourceGroupT {
+		return []v1alpha1.SourceGroupT{{
+			Filters: &v1alpha1.SourceGroupFiltersT{
+				Namespace: &v1alpha1.SourceGroupFiltersNamespaceT{
+					MatchRegex: &v1alpha1.SourceGroupFiltersRegexT{Expression: namespaceRegex},
+				},
+				Name: &v1alpha1.SourceGroupFiltersNameT{
+					MatchRegex: &v1alpha1.SourceGroupFiltersRegexT{Expression: nameRegex},
+				},
+			},
+		}}
+	}
+
+	runValidationTests(t, []validationTestT{
+		{
+			name:  "sources without filters",
+			check: func() []error { return CheckSources([]v1alpha1.SourceGroupT{{}}) },
+		},
+		{
+			name:  "valid expressions",
+			check: func() []error { return CheckSources(regexFilters("^team-.*$", "web")) },
+		},
+		{
+			name:         "broken expressions are reported per filter",
+			check:        func() []error { return CheckSources(regexFilters("team-(", "web[")) },
+			expectedErrs: []string{"source 0 namespace filter", "source 0 name filter"},
+		},
+	})
+}
+
+func TestCheckConditionsAndVariables(t *testing.T) {
+	runValidationTests(t, []validationTestT{
+		{
+			name: "valid templates",
+			check: func() []error {
+				return append(
+					CheckConditions([]v1alpha1.ConditionT{{Name: "replicas", Engine: "cel", Key: "object.spec.replicas > 1", Value: "true"}}),
+					CheckVariables([]v1alpha1.VariableT{{Name: "owner", Engine: "gotmpl", Template: "{{ .object.metadata.name }}"}})...)
+			},
+		},
+		{
+			name: "broken condition",
+			check: func() []error {
+				return CheckConditions([]v1alpha1.ConditionT{{Name: "replicas", Engine: "cel", Key: "object.spec.(", Value: "true"}})
+			},
+			expectedErrs: []string{"condition 'replicas'"},
+		},
+		{
+			name: "broken variable",
+			check: func() []error {
+				return CheckVariables([]v1alpha1.VariableT{{Name: "owner", Engine: "gotmpl", Template: "{{ .object "}})
+			},
+			expectedErrs: []string{"variable 'owner'"},
+		},
+		{
+			name: "unknown engine",
+			check: func() []error {
+				return CheckVariables([]v1alpha1.VariableT{{Name: "owner", Engine: "mustache", Template: "{{ owner }}"}})
+			},
+			expectedErrs: []string{"variable 'owner'"},
+		},
+	})
+}
+
+func TestCheckTests(t *testing.T) {
+	runValidationTests(t, []validationTestT{
+		{
+			name: "known operations",
+			check: func() []error {
+				return CheckTests([]v1alpha1.PolicyTestT{
+					{Name: "default"},
+					{Name: "create", Operation: admissionregv1.Create},
+					{Name: "update", Operation: admissionregv1.Update, OldObject: "kind: Pod"},
+					{Name: "delete", Operation: admissionregv1.Delete},
+					{Name: "connect", Operation: admissionregv1.Connect},
+				})
+			},
+		},
+		{
+			name: "unknown operation",
+			check: func() []error {
+				return CheckTests([]v1alpha1.PolicyTestT{{Name: "patch", Operation: "PATCH"}})
+			},
+			expectedErrs: []string{"test 'patch': unknown operation 'PATCH'"},
+		},
+		{
+			name: "operations are case sensitive",
+			check: func() []error {
+				return CheckTests([]v1alpha1.PolicyTestT{{Name: "create", Operation: "create"}})
+			},
+			expectedErrs: []string{"test 'create': unknown operation 'create'"},
+		},
+		{
+			name: "update without old object",
+			check: func() []error {
+				return CheckTests([]v1alpha1.PolicyTestT{{Name: "update", Operation: admissionregv1.Update}})
+			},
+			expectedErrs: []string{"test 'update': oldObject is required for UPDATE operations"},
+		},
+	})
+}
+
+func TestCheckTestResults(t *testing.T) {
+	err := CheckTestResults([]v1alpha1.PolicyTestResultT{{Name: "first", Passed: true}})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	err = CheckTestResults([]v1alpha1.PolicyTestResultT{
+		{Name: "first", Passed: true},
+		{Name: "second", Passed: false, Message: "expected allowed"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "1 of 2 tests failed: 'second' (expected allowed)") {
+		t.Errorf("expected error naming the failed test, got: %v", err)
+	}
+}
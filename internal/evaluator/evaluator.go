@@ -0,0 +1,286 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package evaluator
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	//
+	"github.com/wI2L/jsondiff"
+
+	//
+	"github.com/freepik-company/admitik/api/v1alpha1"
+	"github.com/freepik-company/admitik/internal/common"
+	"github.com/freepik-company/admitik/internal/strategicmerge"
+	"github.com/freepik-company/admitik/internal/template"
+)
+
+// EvaluatorDependencies represents the dependencies needed by the Evaluator to work
+type EvaluatorDependencies struct {
+	StrategicMergePatcher *strategicmerge.StrategicMergePatcher
+}
+
+// Evaluator evaluates policies against the data of a request.
+// It's shared by the admission server and the policy tests, so both behave exactly the same
+type Evaluator struct {
+	dependencies EvaluatorDependencies
+}
+
+// ValidationResultT represents the outcome of evaluating a ClusterValidationPolicy
+type ValidationResultT struct {
+	// Allowed is true when the object meets the policy, whatever its failureAction is
+	Allowed  bool
+	Message  string
+	Warnings []string
+
+	// Errors represents the failures that happened during the evaluation.
+	// They don't stop it, but broken parts are considered empty or unmet
+	Errors []error
+}
+
+// MutationResultT represents the outcome of evaluating a ClusterMutationPolicy
+type MutationResultT struct {
+	// ConditionsPassed is false when the policy does not apply to the object
+	ConditionsPassed bool
+
+	// Allowed is false when a program rejects the object
+	Allowed  bool
+	Message  string
+	Warnings []string
+
+	// JsonPatchOperations represents the operations to go from the original object to the patched one
+	JsonPatchOperations jsondiff.Patch
+	PatchedObject       []byte
+
+	// Errors represents the failures that happened during the evaluation
+	Errors []error
+
+	// AbortReason is set when the patch could not be computed, with a short explanation for humans.
+	// In that case, the object is not patched by this policy
+	AbortReason string
+}
+
+// NewEvaluator return a new Evaluator
+func NewEvaluator(deps EvaluatorDependencies) *Evaluator {
+	return &Evaluator{
+		dependencies: deps,
+	}
+}
+
+// EvaluateValidation evaluates a ClusterValidationPolicy against the injected data.
+// Sources must be already present in the injected data, as they come from different places
+func (e *Evaluator) EvaluateValidation(policy *v1alpha1.ClusterValidationPolicy, injectedData *template.PolicyEvaluationDataT) (result *ValidationResultT) {
+	result = &ValidationResultT{}
+
+	// Evaluate declared variables. They are available for conditions and later templates.
+	// Conditions can not be trusted without them, so the evaluation fails as a whole, and failureAction decides
+	err := common.PopulateVariables(policy.Spec.Variables, injectedData)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Errorf("failed evaluating variables: %s", err.Error()))
+		result.Message = "Reason unavailable: variables failed. More info in controller logs."
+		return result
+	}
+
+	// Evaluate template conditions
+	conditionsPassed, err := common.IsPassingConditions(policy.Spec.Conditions, injectedData)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Errorf("failed evaluating conditions: %s", err.Error()))
+	}
+
+	// Conditions are met, skip rejection
+	if conditionsPassed && policy.Spec.Program == nil {
+		result.Allowed = true
+		return result
+	}
+
+	// Conditions are met, but the program has the last word
+	if conditionsPassed {
+		decision, programErr := common.EvaluateProgram(policy.Spec.Program, injectedData)
+		if programErr != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("failed evaluating program: %s", programErr.Error()))
+			result.Message = "Reason unavailable: program failed. More info in controller logs."
+		} else {
+			result.Warnings = decision.Warnings
+			if decision.IsAllowed() {
+				result.Allowed = true
+				return result
+			}
+			result.Message = decision.Message
+		}
+	}
+
+	// When some condition is not met, evaluate message's template
+	if result.Message == "" {
+		result.Message, err = template.EvaluateTemplate(policy.Spec.Message.Engine, policy.Spec.Message.Template, injectedData)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("failed parsing message template: %s", err.Error()))
+			result.Message = "Reason unavailable: message template failed. More info in controller logs."
+		}
+	}
+
+	return result
+}
+
+// EvaluateMutation evaluates a ClusterMutationPolicy against the injected data, patching the given object.
+// Sources must be already present in the injected data, as they come from different places
+func (e *Evaluator) EvaluateMutation(policy *v1alpha1.ClusterMutationPolicy, injectedData *template.PolicyEvaluationDataT,
+	objectToPatch []byte) (result *MutationResultT) {
+	result = &MutationResultT{Allowed: true}
+
+	// Evaluate declared variables. They are available for conditions and later templates.
+	// Conditions and patches can not be trusted without them, so the object is not patched by this policy
+	err := common.PopulateVariables(policy.Spec.Variables, injectedData)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Errorf("failed evaluating variables: %s", err.Error()))
+		result.AbortReason = "Variables failed. More info in controller logs."
+		return result
+	}
+
+	// Evaluate template conditions
+	result.ConditionsPassed, err = common.IsPassingConditions(policy.Spec.Conditions, injectedData)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Errorf("failed evaluating conditions: %s", err.Error()))
+	}
+
+	// Conditions are not met, skip patching the resource
+	if !result.ConditionsPassed {
+		return result
+	}
+
+	// Program, when defined, returns the whole decision replacing the patch templates
+	if policy.Spec.Program != nil {
+		decision, programErr := common.EvaluateProgram(policy.Spec.Program, injectedData)
+		if programErr != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("failed evaluating program: %s", programErr.Error()))
+			result.AbortReason = "Program failed. More info in controller logs."
+			return result
+		}
+
+		result.Warnings = decision.Warnings
+
+		// Programs are able to reject the object
+		if !decision.IsAllowed() {
+			result.Allowed = false
+			result.Message = decision.Message
+			return result
+		}
+
+		parsedPatchType, parsedPatch, patchErr := decision.GetPatch()
+		if patchErr != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("failed reading program patch: %s", patchErr.Error()))
+			result.AbortReason = "Program patch is invalid. More info in controller logs."
+			return result
+		}
+
+		// Nothing to patch
+		if len(parsedPatch) == 0 {
+			return result
+		}
+
+		result.JsonPatchOperations, result.PatchedObject, err = e.GenerateJsonPatchOperations(objectToPatch, parsedPatchType, parsedPatch)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("failed generating canonical jsonPatch operations for Kube API server: %s", err.Error()))
+			result.AbortReason = "Generated patch is invalid. More info in controller logs."
+			result.JsonPatchOperations, result.PatchedObject = nil, nil
+		}
+
+		return result
+	}
+
+	// Patches are applied in order, each one over the result of the previous one.
+	// Any failure aborts the whole policy, so the object is never partially patched by it
+	var jsonPatchOperations jsondiff.Patch
+	var patchesApplied bool
+	patchedObject := objectToPatch
+
+	for patchIndex, patch := range getPolicyPatches(policy) {
+		patchName := getPatchName(patchIndex, &patch)
+
+		patchConditionsPassed, condErr := common.IsPassingConditions(patch.Conditions, injectedData)
+		if condErr != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("failed evaluating conditions of %s: %s", patchName, condErr.Error()))
+			result.AbortReason = "Patch conditions failed. More info in controller logs."
+			return result
+		}
+
+		if !patchConditionsPassed {
+			continue
+		}
+
+		parsedPatch, templateErr := renderPatch(&patch, injectedData)
+		if templateErr != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("failed parsing template of %s: %s", patchName, templateErr.Error()))
+			result.AbortReason = "Patch template failed. More info in controller logs."
+			return result
+		}
+
+		tmpJsonPatchOperations, tmpPatchedObject, patchErr := e.GenerateJsonPatchOperations(patchedObject, patch.Type, parsedPatch)
+		if patchErr != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("failed generating canonical jsonPatch operations of %s for Kube API server: %s", patchName, patchErr.Error()))
+			result.AbortReason = "Generated patch is invalid. More info in controller logs."
+			return result
+		}
+
+		jsonPatchOperations = append(jsonPatchOperations, tmpJsonPatchOperations...)
+		patchedObject = tmpPatchedObject
+		patchesApplied = true
+	}
+
+	if patchesApplied {
+		result.JsonPatchOperations = jsonPatchOperations
+		result.PatchedObject = patchedObject
+	}
+
+	return result
+}
+
+// renderPatch evaluates the template of a patch, returning the patch ready to be applied.
+// CEL based types return structured values, as in MutatingAdmissionPolicy, so they are encoded as JSON
+func renderPatch(patch *v1alpha1.PatchT, injectedData *template.PolicyEvaluationDataT) (parsedPatch []byte, err error) {
+	switch strings.ToLower(patch.Type) {
+	case v1alpha1.MutationPatchTypeApplyConfiguration, v1alpha1.MutationPatchTypeCelJsonPatch:
+		patchOutput, err := template.EvaluateExpressionCel(patch.Template, injectedData)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(patchOutput)
+	default:
+		parsedPatchString, err := template.EvaluateTemplate(patch.Engine, patch.Template, injectedData)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(parsedPatchString), nil
+	}
+}
+
+// getPolicyPatches return the patches of a policy in the order they are applied: 'patch' first, then 'patches'
+func getPolicyPatches(policy *v1alpha1.ClusterMutationPolicy) (patches []v1alpha1.PatchT) {
+	if policy.Spec.Patch.Template != "" {
+		patches = append(patches, policy.Spec.Patch)
+	}
+	return append(patches, policy.Spec.Patches...)
+}
+
+// getPatchName return a name to identify a patch in messages
+func getPatchName(index int, patch *v1alpha1.PatchT) string {
+	if patch.Name != "" {
+		return fmt.Sprintf("patch '%s'", patch.Name)
+	}
+	return fmt.Sprintf("patch %d", index)
+}
@@ -0,0 +1,235 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package evaluator
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	//
+	"github.com/freepik-company/admitik/api/v1alpha1"
+	"github.com/freepik-company/admitik/internal/template"
+)
+
+// testMutationObject is the object patched by the mutation tests
+const testMutationObject = `{"apiVersion":"v1","kind":"Pod","metadata":{"name":"example","labels":{"team":"a"}}}`
+
+func getTestMutationInjectedData(t *testing.T) *template.PolicyEvaluationDataT {
+	injectedData := &template.PolicyEvaluationDataT{}
+	injectedData.Initialize()
+
+	err := json.Unmarshal([]byte(testMutationObject), &injectedData.Object)
+	if err != nil {
+		t.Fatalf("failed decoding test object: %s", err.Error())
+	}
+	return injectedData
+}
+
+func TestEvaluateMutationPatches(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch v1alpha1.PatchT
+
+		patches []v1alpha1.PatchT
+
+		// expectedLabels are the labels of the patched object. Nil when the object is not expected to be patched
+		expectedLabels map[string]any
+
+		// expectedPaths are the paths of the operations returned, in order
+		expectedPaths []string
+
+		// expectedAbortReason is a part of the expected abort reason. Empty when the policy is not expected to abort
+		expectedAbortReason string
+	}{
+		{
+			name:  "patch is applied before patches",
+			patch: v1alpha1.PatchT{Type: "jsonmerge", Engine: "plain", Template: `{"metadata":{"labels":{"order":"first"}}}`},
+			patches: []v1alpha1.PatchT{
+				{Type: "jsonmerge", Engine: "plain", Template: `{"metadata":{"labels":{"order":"second"}}}`},
+			},
+			expectedLabels: map[string]any{"team": "a", "order": "second"},
+			expectedPaths:  []string{"/metadata/labels/order", "/metadata/labels/order"},
+		},
+		{
+			name: "each patch is applied over the result of the previous one",
+			patches: []v1alpha1.PatchT{
+				{Type: "jsonpatch", Engine: "plain", Template: `[{"op":"add","path":"/metadata/labels/tier","value":"back"}]`},
+				{Type: "jsonpatch", Engine: "plain", Template: `[{"op":"replace","path":"/metadata/labels/tier","value":"front"}]`},
+			},
+			expectedLabels: map[string]any{"team": "a", "tier": "front"},
+			expectedPaths:  []string{"/metadata/labels/tier", "/metadata/labels/tier"},
+		},
+		{
+			name: "patches with unmet conditions are skipped",
+			patches: []v1alpha1.PatchT{
+				{
+					Type: "jsonmerge", Engine: "plain", Template: `{"metadata":{"labels":{"skipped":"true"}}}`,
+					Conditions: []v1alpha1.ConditionT{{Name: "never", Engine: "cel", Key: `object.metadata.name == "other"`, Value: "true"}},
+				},
+				{Type: "jsonmerge", Engine: "plain", Template: `{"metadata":{"labels":{"applied":"true"}}}`},
+			},
+			expectedLabels: map[string]any{"team": "a", "applied": "true"},
+			expectedPaths:  []string{"/metadata/labels/applied"},
+		},
+		{
+			name: "patch without type is applied as jsonpatch",
+			patches: []v1alpha1.PatchT{
+				{Engine: "plain", Template: `[{"op":"remove","path":"/metadata/labels/team"}]`},
+			},
+			expectedLabels: map[string]any{},
+			expectedPaths:  []string{"/metadata/labels/team"},
+		},
+		{
+			name: "failing patch aborts the whole policy",
+			patches: []v1alpha1.PatchT{
+				{Type: "jsonmerge", Engine: "plain", Template: `{"metadata":{"labels":{"applied":"true"}}}`},
+				{Type: "jsonpatch", Engine: "plain", Template: `[{"op":"remove","path":"/metadata/labels/missing"}]`},
+			},
+			expectedAbortReason: "Generated patch is invalid",
+		},
+		{
+			name: "failing template aborts the whole policy",
+			patches: []v1alpha1.PatchT{
+				{Type: "jsonmerge", Engine: "plain", Template: `{"metadata":{"labels":{"applied":"true"}}}`},
+				{Type: "jsonmerge", Engine: "gotmpl", Template: `{{ .broken `},
+			},
+			expectedAbortReason: "Patch template failed",
+		},
+		{
+			name: "keys with slashes are escaped in the operations",
+			patches: []v1alpha1.PatchT{
+				{Type: "jsonmerge", Engine: "plain", Template: `{"metadata":{"labels":{"example.com/owner":"a","tilde~key":"b"}}}`},
+			},
+			expectedLabels: map[string]any{"team": "a", "example.com/owner": "a", "tilde~key": "b"},
+			expectedPaths:  []string{"/metadata/labels/example.com~1owner", "/metadata/labels/tilde~0key"},
+		},
+		{
+			name: "escaped keys built in CEL are applied",
+			patches: []v1alpha1.PatchT{
+				{Type: "celjsonpatch", Template: `[JSONPatch{op: "add", path: "/metadata/labels/" + jsonpatch.escapeKey("example.com/owner"), value: "a"}]`},
+			},
+			expectedLabels: map[string]any{"team": "a", "example.com/owner": "a"},
+			expectedPaths:  []string{"/metadata/labels/example.com~1owner"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			policy := &v1alpha1.ClusterMutationPolicy{
+				Spec: v1alpha1.ClusterMutationPolicySpec{
+					Patch:   test.patch,
+					Patches: test.patches,
+				},
+			}
+
+			result := NewEvaluator(EvaluatorDependencies{}).EvaluateMutation(policy, getTestMutationInjectedData(t), []byte(testMutationObject))
+
+			if test.expectedAbortReason != "" {
+				if !strings.Contains(result.AbortReason, test.expectedAbortReason) {
+					t.Fatalf("expected abort reason containing '%s', got '%s'", test.expectedAbortReason, result.AbortReason)
+				}
+				if result.PatchedObject != nil || result.JsonPatchOperations != nil {
+					t.Errorf("expected the object not to be patched, got: %s", string(result.PatchedObject))
+				}
+				return
+			}
+
+			if result.AbortReason != "" {
+				t.Fatalf("unexpected abort: %s, errors: %v", result.AbortReason, result.Errors)
+			}
+
+			patchedObject := map[string]any{}
+			err := json.Unmarshal(result.PatchedObject, &patchedObject)
+			if err != nil {
+				t.Fatalf("failed decoding patched object: %s", err.Error())
+			}
+
+			labels, _ := patchedObject["metadata"].(map[string]any)["labels"].(map[string]any)
+			if len(labels) != len(test.expectedLabels) {
+				t.Errorf("expected labels %v, got %v", test.expectedLabels, labels)
+			}
+			for key, value := range test.expectedLabels {
+				if labels[key] != value {
+					t.Errorf("expected labels %v, got %v", test.expectedLabels, labels)
+				}
+			}
+
+			var paths []string
+			for _, operation := range result.JsonPatchOperations {
+				paths = append(paths, operation.Path)
+			}
+			if strings.Join(paths, ",") != strings.Join(test.expectedPaths, ",") {
+				t.Errorf("expected operation paths %v, got %v", test.expectedPaths, paths)
+			}
+		})
+	}
+}
+
+func TestEvaluateFailedVariablesAbort(t *testing.T) {
+	variables := []v1alpha1.VariableT{
+		{Name: "team", Engine: "cel", Template: `object.metadata.labels.team`},
+		{Name: "broken", Engine: "cel", Template: `object.spec.missing`},
+	}
+
+	// Conditions and templates are met or valid, so only the failed variable can stop them
+	conditions := []v1alpha1.ConditionT{{Name: "always", Engine: "cel", Key: `vars.team == "a"`, Value: "true"}}
+
+	t.Run("validation is rejected without evaluating later templates", func(t *testing.T) {
+		policy := &v1alpha1.ClusterValidationPolicy{
+			Spec: v1alpha1.ClusterValidationPolicySpec{
+				Variables:  variables,
+				Conditions: conditions,
+				Message:    v1alpha1.MessageT{Engine: "gotmpl", Template: `message from template`},
+			},
+		}
+
+		result := NewEvaluator(EvaluatorDependencies{}).EvaluateValidation(policy, getTestMutationInjectedData(t))
+
+		if result.Allowed {
+			t.Errorf("expected the object to be rejected")
+		}
+		if !strings.Contains(result.Message, "variables failed") {
+			t.Errorf("expected message about failed variables, got '%s'", result.Message)
+		}
+		if len(result.Errors) != 1 || !strings.Contains(result.Errors[0].Error(), "failed variable 'broken'") {
+			t.Errorf("expected a single error about variable 'broken', got %v", result.Errors)
+		}
+	})
+
+	t.Run("mutation is aborted without patching the object", func(t *testing.T) {
+		policy := &v1alpha1.ClusterMutationPolicy{
+			Spec: v1alpha1.ClusterMutationPolicySpec{
+				Variables:  variables,
+				Conditions: conditions,
+				Patch:      v1alpha1.PatchT{Type: "jsonmerge", Engine: "plain", Template: `{"metadata":{"labels":{"applied":"true"}}}`},
+			},
+		}
+
+		result := NewEvaluator(EvaluatorDependencies{}).EvaluateMutation(policy, getTestMutationInjectedData(t), []byte(testMutationObject))
+
+		if !strings.Contains(result.AbortReason, "Variables failed") {
+			t.Errorf("expected abort reason about failed variables, got '%s'", result.AbortReason)
+		}
+		if result.ConditionsPassed {
+			t.Errorf("expected conditions not to be evaluated")
+		}
+		if result.PatchedObject != nil || result.JsonPatchOperations != nil {
+			t.Errorf("expected the object not to be patched, got: %s", string(result.PatchedObject))
+		}
+	})
+}
@@ -0,0 +1,243 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package evaluator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	//
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/wI2L/jsondiff"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utiljson "k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/kube-openapi/pkg/util/proto/validation"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+
+	//
+	"github.com/freepik-company/admitik/api/v1alpha1"
+	"github.com/freepik-company/admitik/internal/globals"
+)
+
+// GenerateJsonPatchOperations return a group of JsonPatch operations to mutate an object from its original
+// state to a final state. It's compatible with 'jsonpatch', 'jsonmerge', 'strategicmerge', 'applyconfiguration'
+// and 'celjsonpatch' patch types. CEL based types are expected to be already evaluated and encoded as JSON.
+func (e *Evaluator) GenerateJsonPatchOperations(objectToPatch []byte, patchType string, patch []byte) (jsonPatchOperations jsondiff.Patch, patchedObject []byte, err error) {
+	logger := log.FromContext(globals.Application.Context).
+		WithValues("evaluator", "mutation")
+
+	var patchedObjectBytes []byte
+	patchType = strings.ToLower(patchType)
+
+	// Apply user-defined patch to the entering object
+	switch patchType {
+	case v1alpha1.MutationPatchTypeMerge:
+		patchedObjectBytes, err = e.generateJsonMergePatch(objectToPatch, patch)
+		if err != nil {
+			return nil, nil, fmt.Errorf("jsonmerge patch failed: %v", err)
+		}
+	case v1alpha1.MutationPatchTypeStrategicMerge:
+		patchedObjectBytes, err = e.generateStrategicMergePatch(objectToPatch, patch)
+		if err != nil {
+			return nil, nil, fmt.Errorf("strategicmerge patch failed: %v", err)
+		}
+	case v1alpha1.MutationPatchTypeApplyConfiguration:
+		patchedObjectBytes, err = e.generateApplyConfigurationPatch(objectToPatch, patch)
+		if err != nil {
+			return nil, nil, fmt.Errorf("applyconfiguration patch failed: %v", err)
+		}
+	case v1alpha1.MutationPatchTypeCelJsonPatch:
+		patchedObjectBytes, err = e.generateJsonPatchPatch(objectToPatch, patch)
+		if err != nil {
+			return nil, nil, fmt.Errorf("celjsonpatch patch failed: %v", err)
+		}
+	default:
+		patchedObjectBytes, err = e.generateJsonPatchPatch(objectToPatch, patch)
+		if err != nil {
+			return nil, nil, fmt.Errorf("jsonpatch patch failed: %v", err)
+		}
+	}
+
+	// Calculate the difference for Kubernetes API
+	// Store only successful operations in the operations list
+	var tmpJsonPatchOperations jsondiff.Patch
+
+	tmpJsonPatchOperations, err = jsondiff.CompareJSON(objectToPatch, patchedObjectBytes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// DEBUG: log candidate object, already patched candidate for it and operations that will be applied.
+	// This is useful to identify issues in patching engines.
+	logger.V(5).Info("generated patch",
+		"type", patchType,
+		"candidate", string(objectToPatch),
+		"patchedCandidate", string(patchedObjectBytes),
+		"jsonPatchOperations", tmpJsonPatchOperations.String(),
+	)
+
+	return tmpJsonPatchOperations, patchedObjectBytes, nil
+}
+
+// generateJsonPatchPatch patches the object using JSONPath strategy and returns the resulting object
+// Patch must be expressed in JSON
+func (e *Evaluator) generateJsonPatchPatch(objectToPatch []byte, patch []byte) (patchedObjectBytes []byte, err error) {
+
+	var tmpPatch jsonpatch.Patch
+	tmpPatch, err = jsonpatch.DecodePatch(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	patchedObjectBytes, err = tmpPatch.Apply(objectToPatch)
+	if err != nil {
+		return nil, err
+	}
+
+	return patchedObjectBytes, nil
+}
+
+// generateJsonMergePatch patches the object using JSONMerge strategy and returns the resulting object
+// Patch must be expressed in YAML
+func (e *Evaluator) generateJsonMergePatch(objectToPatch []byte, patch []byte) (patchedObjectBytes []byte, err error) {
+
+	var tmpPatchBytes []byte
+	tmpPatchBytes, err = yaml.YAMLToJSON(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	patchedObjectBytes, err = jsonpatch.MergePatch(objectToPatch, tmpPatchBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	return patchedObjectBytes, nil
+}
+
+// generateStrategicMergePatch patches the object using StrategicMerge strategy and returns the resulting object
+// Patch must be expressed in YAML
+// TODO: Could we use official Kubernetes package for this?
+// Ref: https://github.com/kubernetes/kubernetes/blob/bd715a38d32561b45742b2d0cf0762b024107a31/cmd/kubeadm/app/util/patches/patches.go#L211-L216
+// Ref: SMP using OpenAPI schemas like this project: https://github.com/kubernetes/kubernetes/blob/bd715a38d32561b45742b2d0cf0762b024107a31/staging/src/k8s.io/apimachinery/pkg/util/strategicpatch/patch.go#L821
+func (e *Evaluator) generateStrategicMergePatch(objectToPatch []byte, patch []byte) (patchedObjectBytes []byte, err error) {
+
+	var tmpPatchBytes []byte
+	tmpPatchBytes, err = yaml.YAMLToJSON(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	//
+	tmpPatchObject := map[string]any{}
+	err = json.Unmarshal(tmpPatchBytes, &tmpPatchObject)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpOriginalObject := map[string]any{}
+	err = json.Unmarshal(objectToPatch, &tmpOriginalObject)
+	if err != nil {
+		return nil, err
+	}
+
+	// Take original GVK as default. Overwrite it when the patch specifies another.
+	desiredGvk, err := globals.GetObjectGVK(&tmpOriginalObject)
+	if err != nil {
+		return nil, err
+	}
+
+	patchGVK, err := globals.GetObjectGVK(&tmpPatchObject)
+	if err == nil {
+		desiredGvk = patchGVK
+	}
+
+	//
+	initialSchema := e.dependencies.StrategicMergePatcher.GetSchemaByGVK(schema.GroupVersionKind{
+		Group:   desiredGvk.Group,
+		Version: desiredGvk.Version,
+		Kind:    desiredGvk.Kind,
+	})
+
+	patchedObject := map[string]any{}
+	patchedObject, err = e.dependencies.StrategicMergePatcher.StrategicMerge(tmpOriginalObject, tmpPatchObject, initialSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	patchedObjectBytes, err = json.Marshal(patchedObject)
+	if err != nil {
+		return nil, err
+	}
+
+	return patchedObjectBytes, nil
+}
+
+// generateApplyConfigurationPatch merges an apply configuration into the object with server-side apply semantics
+// and returns the resulting object. Patch must be expressed in JSON
+func (e *Evaluator) generateApplyConfigurationPatch(objectToPatch []byte, patch []byte) (patchedObjectBytes []byte, err error) {
+
+	// Integers are kept as they are, as server-side apply distinguishes them from floats
+	tmpPatchObject := map[string]any{}
+	err = utiljson.Unmarshal(patch, &tmpPatchObject)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpOriginalObject := map[string]any{}
+	err = utiljson.Unmarshal(objectToPatch, &tmpOriginalObject)
+	if err != nil {
+		return nil, err
+	}
+
+	patchedObject, err := e.dependencies.StrategicMergePatcher.ApplyConfiguration(tmpOriginalObject, tmpPatchObject)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(patchedObject)
+}
+
+// ValidatePatchedObject validates a patched object offline, against the OpenAPI schema of its GVK
+// cached by the StrategicMergePatcher
+func (e *Evaluator) ValidatePatchedObject(patchedObject []byte) (err error) {
+
+	tmpObject := map[string]any{}
+	err = json.Unmarshal(patchedObject, &tmpObject)
+	if err != nil {
+		return err
+	}
+
+	objectGvk, err := globals.GetObjectGVK(&tmpObject)
+	if err != nil {
+		return err
+	}
+
+	objectSchema := e.dependencies.StrategicMergePatcher.GetSchemaByGVK(schema.GroupVersionKind{
+		Group:   objectGvk.Group,
+		Version: objectGvk.Version,
+		Kind:    objectGvk.Kind,
+	})
+	if objectSchema == nil {
+		return fmt.Errorf("schema not found for '%s/%s, Kind=%s'", objectGvk.Group, objectGvk.Version, objectGvk.Kind)
+	}
+
+	return errors.Join(validation.ValidateModel(tmpObject, objectSchema, objectGvk.Kind)...)
+}
@@ -0,0 +1,234 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package evaluator
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	//
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"sigs.k8s.io/yaml"
+
+	//
+	"github.com/freepik-company/admitik/api/v1alpha1"
+	"github.com/freepik-company/admitik/internal/common"
+	"github.com/freepik-company/admitik/internal/template"
+)
+
+// RunValidationPolicyTests evaluates the tests declared in a ClusterValidationPolicy, returning one result per test
+func (e *Evaluator) RunValidationPolicyTests(policy *v1alpha1.ClusterValidationPolicy) (results []v1alpha1.PolicyTestResultT) {
+	for _, test := range policy.Spec.Tests {
+
+		injectedData, err := getTestInjectedData(&test, len(policy.Spec.Sources))
+		if err != nil {
+			results = append(results, newTestResult(test.Name, []string{err.Error()}))
+			continue
+		}
+
+		evaluationResult := e.EvaluateValidation(policy, injectedData)
+
+		failures := getTestEvaluationFailures(evaluationResult.Errors)
+		failures = append(failures, checkTestDecision(&test.Expect, evaluationResult.Allowed, evaluationResult.Message)...)
+
+		results = append(results, newTestResult(test.Name, failures))
+	}
+
+	return results
+}
+
+// RunMutationPolicyTests evaluates the tests declared in a ClusterMutationPolicy, returning one result per test
+func (e *Evaluator) RunMutationPolicyTests(policy *v1alpha1.ClusterMutationPolicy) (results []v1alpha1.PolicyTestResultT) {
+	for _, test := range policy.Spec.Tests {
+
+		injectedData, err := getTestInjectedData(&test, len(policy.Spec.Sources))
+		if err != nil {
+			results = append(results, newTestResult(test.Name, []string{err.Error()}))
+			continue
+		}
+
+		objectToPatch, err := json.Marshal(injectedData.Object)
+		if err != nil {
+			results = append(results, newTestResult(test.Name, []string{fmt.Sprintf("failed encoding object: %s", err.Error())}))
+			continue
+		}
+
+		evaluationResult := e.EvaluateMutation(policy, injectedData, objectToPatch)
+
+		failures := getTestEvaluationFailures(evaluationResult.Errors)
+		failures = append(failures, checkTestDecision(&test.Expect, evaluationResult.Allowed, evaluationResult.Message)...)
+
+		// Policies not patching the object leave it as it was
+		patchedObject := evaluationResult.PatchedObject
+		if patchedObject == nil {
+			patchedObject = objectToPatch
+		}
+
+		if test.Expect.PatchedObject != "" {
+			failure, compareErr := checkTestPatchedObject(test.Expect.PatchedObject, patchedObject)
+			if compareErr != nil {
+				failure = compareErr.Error()
+			}
+			if failure != "" {
+				failures = append(failures, failure)
+			}
+		}
+
+		results = append(results, newTestResult(test.Name, failures))
+	}
+
+	return results
+}
+
+// getTestInjectedData return the data injected in templates for a test, built from its fixtures
+func getTestInjectedData(test *v1alpha1.PolicyTestT, sourcesCount int) (injectedData *template.PolicyEvaluationDataT, err error) {
+	injectedData = &template.PolicyEvaluationDataT{}
+	injectedData.Initialize()
+
+	operation := test.Operation
+	if operation == "" {
+		operation = admissionregv1.Create
+	}
+	injectedData.Operation = common.GetNormalizedOperation(operation)
+
+	err = yaml.Unmarshal([]byte(test.Object), &injectedData.Object)
+	if err != nil {
+		return nil, fmt.Errorf("failed decoding object: %s", err.Error())
+	}
+
+	if injectedData.Operation == common.NormalizedOperationUpdate {
+		err = yaml.Unmarshal([]byte(test.OldObject), &injectedData.OldObject)
+		if err != nil {
+			return nil, fmt.Errorf("failed decoding oldObject: %s", err.Error())
+		}
+
+		err = injectedData.PopulateChanges()
+		if err != nil {
+			return nil, fmt.Errorf("failed computing changes between objects: %s", err.Error())
+		}
+	}
+
+	// User is kept typed too, as some template functions (like 'can') need its whole data
+	if test.UserInfo != "" {
+		userInfo := authenticationv1.UserInfo{}
+		err = yaml.Unmarshal([]byte(test.UserInfo), &userInfo)
+		if err != nil {
+			return nil, fmt.Errorf("failed decoding userInfo: %s", err.Error())
+		}
+
+		err = yaml.Unmarshal([]byte(test.UserInfo), &injectedData.UserInfo)
+		if err != nil {
+			return nil, fmt.Errorf("failed decoding userInfo: %s", err.Error())
+		}
+
+		injectedData.GetRequestState().Requester = &userInfo
+	}
+
+	// Functions reaching Kubernetes only see the fixtures, so tests are repeatable and cheap on each reconcile
+	fixtures := &template.FixturesT{}
+	if test.Lookups != "" {
+		err = yaml.Unmarshal([]byte(test.Lookups), &fixtures.Objects)
+		if err != nil {
+			return nil, fmt.Errorf("failed decoding lookups: %s", err.Error())
+		}
+	}
+	for _, review := range test.AccessReviews {
+		fixtures.AccessReviews = append(fixtures.AccessReviews, template.AccessReviewFixtureT{
+			User:      review.User,
+			Verb:      review.Verb,
+			Group:     review.Group,
+			Resource:  review.Resource,
+			Namespace: review.Namespace,
+			Name:      review.Name,
+			Allowed:   review.Allowed,
+		})
+	}
+	injectedData.GetRequestState().Fixtures = fixtures
+
+	// Sources without fixtures are empty, as broken ones are during admission
+	for sourceIndex := 0; sourceIndex < sourcesCount; sourceIndex++ {
+		injectedData.Sources[sourceIndex] = []map[string]any{}
+
+		if sourceIndex >= len(test.Sources) {
+			continue
+		}
+
+		var sourceObjects []map[string]any
+		err = yaml.Unmarshal([]byte(test.Sources[sourceIndex]), &sourceObjects)
+		if err != nil {
+			return nil, fmt.Errorf("failed decoding objects of source %d: %s", sourceIndex, err.Error())
+		}
+		injectedData.Sources[sourceIndex] = sourceObjects
+	}
+
+	return injectedData, nil
+}
+
+// getTestEvaluationFailures return the errors happened during a test as failures.
+// Broken templates are the main thing tests must catch
+func getTestEvaluationFailures(evaluationErrors []error) (failures []string) {
+	for _, evaluationErr := range evaluationErrors {
+		failures = append(failures, evaluationErr.Error())
+	}
+	return failures
+}
+
+// checkTestDecision compares the decision of a policy with the expected one
+func checkTestDecision(expect *v1alpha1.PolicyTestExpectationT, allowed bool, message string) (failures []string) {
+	if expect.Allowed != nil && *expect.Allowed != allowed {
+		failures = append(failures, fmt.Sprintf("expected allowed to be %t, got %t", *expect.Allowed, allowed))
+	}
+
+	if expect.Message != "" && !strings.Contains(message, expect.Message) {
+		failures = append(failures, fmt.Sprintf("expected message to contain '%s', got '%s'", expect.Message, message))
+	}
+
+	return failures
+}
+
+// checkTestPatchedObject compares the patched object with the expected one, ignoring formatting differences
+func checkTestPatchedObject(expectedObject string, patchedObject []byte) (failure string, err error) {
+	var expected, got any
+
+	err = yaml.Unmarshal([]byte(expectedObject), &expected)
+	if err != nil {
+		return "", fmt.Errorf("failed decoding expected patchedObject: %s", err.Error())
+	}
+
+	err = json.Unmarshal(patchedObject, &got)
+	if err != nil {
+		return "", fmt.Errorf("failed decoding patched object: %s", err.Error())
+	}
+
+	if reflect.DeepEqual(expected, got) {
+		return "", nil
+	}
+
+	return fmt.Sprintf("patched object differs from the expected one, got: %s", string(patchedObject)), nil
+}
+
+// newTestResult return the result of a test from its failures
+func newTestResult(name string, failures []string) v1alpha1.PolicyTestResultT {
+	return v1alpha1.PolicyTestResultT{
+		Name:    name,
+		Passed:  len(failures) == 0,
+		Message: strings.Join(failures, "; "),
+	}
+}
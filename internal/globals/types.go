@@ -23,6 +23,11 @@ import (
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+
+	//
+	"github.com/freepik-company/admitik/internal/accessreview"
+	"github.com/freepik-company/admitik/internal/kubelookup"
+	templateLibraryRegistry "github.com/freepik-company/admitik/internal/registry/templatelibrary"
 )
 
 // ApplicationT TODO
@@ -34,4 +39,13 @@ type applicationT struct {
 	KubeDiscoveryClient *discovery.DiscoveryClient
 	KubeRawClient       *dynamic.DynamicClient
 	KubeRawCoreClient   *kubernetes.Clientset
+
+	// TemplateLibraryRegistry is read by template engines to resolve shared templates and modules
+	TemplateLibraryRegistry *templateLibraryRegistry.TemplateLibraryRegistry
+
+	// KubeLookupClient is used by template engines to read objects from Kubernetes on evaluation time
+	KubeLookupClient *kubelookup.KubeLookupClient
+
+	// AccessReviewClient is used by template engines to ask Kubernetes authorizer about users' permissions
+	AccessReviewClient *accessreview.AccessReviewClient
 }
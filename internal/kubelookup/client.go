@@ -0,0 +1,303 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelookup
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	//
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/restmapper"
+)
+
+const (
+	// callTimeout is the max time waited for Kubernetes on each lookup.
+	// It's kept low as lookups are done while the admission request is waiting
+	callTimeout = 3 * time.Second
+
+	// cacheMaxEntries is the max amount of results kept in cache. When reached, expired entries are removed,
+	// and the ones closer to expire are evicted if there is still no room
+	cacheMaxEntries = 1000
+
+	//
+	verbGet  = "get"
+	verbList = "list"
+)
+
+// NewKubeLookupClient return a new KubeLookupClient.
+// Kinds are resolved into resources using discovery, which is cached and refreshed when a kind is unknown
+func NewKubeLookupClient(ctx context.Context, options KubeLookupClientOptions,
+	client dynamic.Interface, discoveryClient discovery.DiscoveryInterface) (*KubeLookupClient, error) {
+
+	allowedRules, err := parseAllowedResources(options.AllowedResources)
+	if err != nil {
+		return nil, err
+	}
+
+	return &KubeLookupClient{
+		ctx:          ctx,
+		options:      options,
+		allowedRules: allowedRules,
+		client:       client,
+		mapper:       restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(discoveryClient)),
+		cache:        make(map[string]cacheEntryT),
+	}, nil
+}
+
+// parseAllowedResources parses the entries of the allowlist, following the pattern:
+// '[{namespace}/]{resource}[.{group}][:{verb}]'. Missing namespace and verb mean any of them.
+// Wildcards are accepted for all the parts, and a single '*' allows everything
+func parseAllowedResources(allowedResources []string) (rules []allowedResourceRuleT, err error) {
+
+	for _, allowedResource := range allowedResources {
+		allowedResource = strings.TrimSpace(allowedResource)
+		if allowedResource == "" {
+			continue
+		}
+
+		rule := allowedResourceRuleT{namespace: "*", verb: "*"}
+
+		resourcePart, verb, verbFound := strings.Cut(allowedResource, ":")
+		if verbFound {
+			rule.verb = verb
+		}
+
+		namespace, resourcePart, namespaceFound := strings.Cut(resourcePart, "/")
+		if !namespaceFound {
+			resourcePart = namespace
+		} else {
+			rule.namespace = namespace
+		}
+
+		rule.resource, rule.group, _ = strings.Cut(resourcePart, ".")
+		if resourcePart == "*" {
+			rule.group = "*"
+		}
+
+		if rule.namespace == "" || rule.resource == "" ||
+			(rule.verb != "*" && rule.verb != verbGet && rule.verb != verbList) {
+			return nil, fmt.Errorf("invalid allowed resource '%s': expected '[{namespace}/]{resource}[.{group}][:get|list]'",
+				allowedResource)
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// Get return an object from Kubernetes, following Helm's 'lookup' behavior:
+// an empty map is returned when the object does not exist
+func (c *KubeLookupClient) Get(counter *CallCounterT, apiVersion, kind, namespace, name string) (result map[string]any, err error) {
+
+	resource, namespaced, err := c.getResource(apiVersion, kind)
+	if err != nil {
+		return result, err
+	}
+
+	if !namespaced {
+		namespace = ""
+	}
+
+	err = c.checkAllowed(verbGet, resource, namespace)
+	if err != nil {
+		return result, err
+	}
+
+	cacheKey := strings.Join([]string{verbGet, resource.String(), namespace, name}, "/")
+	return c.getCachedOrFetch(counter, cacheKey, func(ctx context.Context) (map[string]any, error) {
+		object, err := c.client.Resource(resource).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return object.UnstructuredContent(), nil
+	})
+}
+
+// List return a list of objects from Kubernetes as a List object, containing them under 'items'.
+// Empty namespace means all the namespaces for namespaced resources
+func (c *KubeLookupClient) List(counter *CallCounterT, apiVersion, kind, namespace, labelSelector string) (result map[string]any, err error) {
+
+	resource, namespaced, err := c.getResource(apiVersion, kind)
+	if err != nil {
+		return result, err
+	}
+
+	if !namespaced {
+		namespace = ""
+	}
+
+	err = c.checkAllowed(verbList, resource, namespace)
+	if err != nil {
+		return result, err
+	}
+
+	cacheKey := strings.Join([]string{verbList, resource.String(), namespace, labelSelector}, "/")
+	return c.getCachedOrFetch(counter, cacheKey, func(ctx context.Context) (map[string]any, error) {
+		objectList, err := c.client.Resource(resource).Namespace(namespace).List(ctx, metav1.ListOptions{
+			LabelSelector: labelSelector,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return objectList.UnstructuredContent(), nil
+	})
+}
+
+// getResource resolves the resource for a kind
+func (c *KubeLookupClient) getResource(apiVersion, kind string) (resource schema.GroupVersionResource, namespaced bool, err error) {
+
+	groupVersion, err := schema.ParseGroupVersion(apiVersion)
+	if err != nil {
+		return resource, namespaced, fmt.Errorf("invalid apiVersion '%s': %s", apiVersion, err.Error())
+	}
+
+	mapping, err := c.mapper.RESTMapping(groupVersion.WithKind(kind).GroupKind(), groupVersion.Version)
+	if err != nil {
+		return resource, namespaced, fmt.Errorf("unable to find the resource for '%s' in '%s': %s", kind, apiVersion, err.Error())
+	}
+
+	namespaced = mapping.Scope.Name() == meta.RESTScopeNameNamespace
+	return mapping.Resource, namespaced, nil
+}
+
+// checkAllowed return an error when no entry of the allowlist allows the verb for the resource in the namespace
+func (c *KubeLookupClient) checkAllowed(verb string, resource schema.GroupVersionResource, namespace string) error {
+	if c.isAllowed(verb, resource, namespace) {
+		return nil
+	}
+
+	if namespace == "" {
+		return fmt.Errorf("lookups are not allowed to %s resource '%s' cluster-wide",
+			verb, resource.GroupResource().String())
+	}
+
+	return fmt.Errorf("lookups are not allowed to %s resource '%s' in namespace '%s'",
+		verb, resource.GroupResource().String(), namespace)
+}
+
+// isAllowed checks whether some entry of the allowlist allows the verb for the resource in the namespace.
+// Empty namespace means cluster-scoped resources, or all the namespaces for lists,
+// so it's only allowed by entries not restricted to a namespace, like RBAC's ClusterRoles
+func (c *KubeLookupClient) isAllowed(verb string, resource schema.GroupVersionResource, namespace string) bool {
+
+	for _, rule := range c.allowedRules {
+		if (rule.verb == "*" || rule.verb == verb) &&
+			(rule.namespace == "*" || rule.namespace == namespace) &&
+			(rule.resource == "*" || rule.resource == resource.Resource) &&
+			(rule.group == "*" || rule.group == resource.Group) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getCachedOrFetch return the result stored in cache for a key.
+// When missing or expired, it is fetched from Kubernetes, consuming the budget of the request
+func (c *KubeLookupClient) getCachedOrFetch(counter *CallCounterT, cacheKey string,
+	fetch func(ctx context.Context) (map[string]any, error)) (result map[string]any, err error) {
+
+	c.mu.Lock()
+	entry, found := c.cache[cacheKey]
+	c.mu.Unlock()
+
+	if found && time.Now().Before(entry.expiresAt) {
+		return runtime.DeepCopyJSON(entry.result), nil
+	}
+
+	if counter == nil {
+		counter = &CallCounterT{}
+	}
+
+	if !counter.add(c.options.MaxCallsPerRequest) {
+		return result, fmt.Errorf("lookup calls budget exceeded: %d calls per request", c.options.MaxCallsPerRequest)
+	}
+
+	ctx, cancel := context.WithTimeout(c.ctx, callTimeout)
+	defer cancel()
+
+	// Missing objects are cached too, as empty results
+	result, err = fetch(ctx)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		result = map[string]any{}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, found := c.cache[cacheKey]; !found && len(c.cache) >= cacheMaxEntries {
+		c.evictEntries()
+	}
+
+	c.cache[cacheKey] = cacheEntryT{
+		result:    result,
+		expiresAt: time.Now().Add(c.options.CacheTTL),
+	}
+
+	return runtime.DeepCopyJSON(result), nil
+}
+
+// evictEntries makes room in the cache for a new entry, removing the expired ones.
+// When all of them are still valid, the closest to expire is removed. It must be called with the lock already held
+func (c *KubeLookupClient) evictEntries() {
+	now := time.Now()
+	for key, entry := range c.cache {
+		if now.After(entry.expiresAt) {
+			delete(c.cache, key)
+		}
+	}
+
+	for len(c.cache) >= cacheMaxEntries {
+		var evictedKey string
+		var evictedExpiresAt time.Time
+		for key, entry := range c.cache {
+			if evictedKey == "" || entry.expiresAt.Before(evictedExpiresAt) {
+				evictedKey = key
+				evictedExpiresAt = entry.expiresAt
+			}
+		}
+		delete(c.cache, evictedKey)
+	}
+}
+
+// add increases the amount of calls, returning false when the limit is exceeded.
+// Zero limit means unlimited
+func (cc *CallCounterT) add(limit int) bool {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	if limit > 0 && cc.calls >= limit {
+		return false
+	}
+
+	cc.calls++
+	return true
+}
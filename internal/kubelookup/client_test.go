@@ -0,0 +1,432 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelookup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	//
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+var (
+	testConfigMapsResource = schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	testNamespacesResource = schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+	testDeploymentResource = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+)
+
+// getTestKubeLookupClient return a client backed by fake Kubernetes clients, containing a ConfigMap and a Namespace
+func getTestKubeLookupClient(t *testing.T, options KubeLookupClientOptions) *KubeLookupClient {
+	allowedRules, err := parseAllowedResources(options.AllowedResources)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}, meta.RESTScopeRoot)
+
+	configMap := &unstructured.Unstructured{}
+	configMap.SetAPIVersion("v1")
+	configMap.SetKind("ConfigMap")
+	configMap.SetNamespace("team-a")
+	configMap.SetName("settings")
+
+	namespace := &unstructured.Unstructured{}
+	namespace.SetAPIVersion("v1")
+	namespace.SetKind("Namespace")
+	namespace.SetName("team-a")
+
+	return &KubeLookupClient{
+		ctx:          context.Background(),
+		options:      options,
+		allowedRules: allowedRules,
+		client:       dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), configMap, namespace),
+		mapper:       mapper,
+		cache:        make(map[string]cacheEntryT),
+	}
+}
+
+func TestParseAllowedResources(t *testing.T) {
+	tests := []struct {
+		name             string
+		allowedResources []string
+		expected         []allowedResourceRuleT
+
+		// expectedErr is a part of the expected error message. Empty when no error is expected
+		expectedErr string
+	}{
+		{
+			name:             "core resource",
+			allowedResources: []string{"configmaps"},
+			expected:         []allowedResourceRuleT{{namespace: "*", resource: "configmaps", verb: "*"}},
+		},
+		{
+			name:             "resource with group, namespace and verb",
+			allowedResources: []string{" team-a/deployments.apps:list "},
+			expected:         []allowedResourceRuleT{{namespace: "team-a", resource: "deployments", group: "apps", verb: "list"}},
+		},
+		{
+			name:             "single wildcard allows everything",
+			allowedResources: []string{"*"},
+			expected:         []allowedResourceRuleT{{namespace: "*", resource: "*", group: "*", verb: "*"}},
+		},
+		{
+			name:             "empty entries are ignored",
+			allowedResources: []string{"", " "},
+		},
+		{
+			name:             "unknown verbs are rejected",
+			allowedResources: []string{"configmaps:delete"},
+			expectedErr:      "invalid allowed resource 'configmaps:delete'",
+		},
+		{
+			name:             "empty namespaces are rejected",
+			allowedResources: []string{"/configmaps"},
+			expectedErr:      "invalid allowed resource '/configmaps'",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rules, err := parseAllowedResources(test.allowedResources)
+
+			if test.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.expectedErr) {
+					t.Fatalf("expected error containing '%s', got: %v", test.expectedErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if fmt.Sprint(rules) != fmt.Sprint(test.expected) {
+				t.Errorf("expected rules %v, got %v", test.expected, rules)
+			}
+		})
+	}
+}
+
+func TestIsAllowed(t *testing.T) {
+	tests := []struct {
+		name             string
+		allowedResources []string
+		verb             string
+		resource         schema.GroupVersionResource
+		namespace        string
+		expected         bool
+	}{
+		{
+			name:     "empty allowlist disables lookups",
+			verb:     verbGet,
+			resource: testConfigMapsResource,
+			expected: false,
+		},
+		{
+			name:             "core resource in any namespace",
+			allowedResources: []string{"configmaps"},
+			verb:             verbGet,
+			resource:         testConfigMapsResource,
+			namespace:        "team-b",
+			expected:         true,
+		},
+		{
+			name:             "core entry does not match the same resource of other groups",
+			allowedResources: []string{"deployments"},
+			verb:             verbGet,
+			resource:         testDeploymentResource,
+			namespace:        "team-a",
+			expected:         false,
+		},
+		{
+			name:             "wildcard resource in a group",
+			allowedResources: []string{"*.apps"},
+			verb:             verbList,
+			resource:         testDeploymentResource,
+			namespace:        "team-a",
+			expected:         true,
+		},
+		{
+			name:             "wildcard group",
+			allowedResources: []string{"deployments.*"},
+			verb:             verbGet,
+			resource:         testDeploymentResource,
+			namespace:        "team-a",
+			expected:         true,
+		},
+		{
+			name:             "entry scoped to the namespace",
+			allowedResources: []string{"team-a/configmaps"},
+			verb:             verbGet,
+			resource:         testConfigMapsResource,
+			namespace:        "team-a",
+			expected:         true,
+		},
+		{
+			name:             "entry scoped to another namespace",
+			allowedResources: []string{"team-a/configmaps"},
+			verb:             verbGet,
+			resource:         testConfigMapsResource,
+			namespace:        "team-b",
+			expected:         false,
+		},
+		{
+			name:             "entry scoped to a namespace does not allow lists across all of them",
+			allowedResources: []string{"team-a/configmaps"},
+			verb:             verbList,
+			resource:         testConfigMapsResource,
+			namespace:        "",
+			expected:         false,
+		},
+		{
+			name:             "entry scoped to a namespace does not allow cluster-scoped resources",
+			allowedResources: []string{"team-a/namespaces"},
+			verb:             verbGet,
+			resource:         testNamespacesResource,
+			namespace:        "",
+			expected:         false,
+		},
+		{
+			name:             "entry scoped to the verb",
+			allowedResources: []string{"configmaps:get"},
+			verb:             verbGet,
+			resource:         testConfigMapsResource,
+			namespace:        "team-a",
+			expected:         true,
+		},
+		{
+			name:             "entry scoped to another verb",
+			allowedResources: []string{"configmaps:get"},
+			verb:             verbList,
+			resource:         testConfigMapsResource,
+			namespace:        "team-a",
+			expected:         false,
+		},
+		{
+			name:             "some entry matches",
+			allowedResources: []string{"secrets", "*/configmaps:list"},
+			verb:             verbList,
+			resource:         testConfigMapsResource,
+			namespace:        "",
+			expected:         true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := getTestKubeLookupClient(t, KubeLookupClientOptions{AllowedResources: test.allowedResources})
+
+			result := client.isAllowed(test.verb, test.resource, test.namespace)
+			if result != test.expected {
+				t.Errorf("expected %v, got %v", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestGetAndListScoping(t *testing.T) {
+	client := getTestKubeLookupClient(t, KubeLookupClientOptions{
+		AllowedResources: []string{"team-a/configmaps:get", "namespaces:list"},
+	})
+
+	object, err := client.Get(nil, "v1", "ConfigMap", "team-a", "settings")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if name, _, _ := unstructured.NestedString(object, "metadata", "name"); name != "settings" {
+		t.Errorf("expected ConfigMap 'settings', got: %v", object)
+	}
+
+	_, err = client.Get(nil, "v1", "ConfigMap", "team-b", "settings")
+	if err == nil || !strings.Contains(err.Error(), "not allowed to get resource 'configmaps' in namespace 'team-b'") {
+		t.Errorf("expected error about namespace 'team-b', got: %v", err)
+	}
+
+	_, err = client.List(nil, "v1", "ConfigMap", "team-a", "")
+	if err == nil || !strings.Contains(err.Error(), "not allowed to list resource 'configmaps'") {
+		t.Errorf("expected error about listing, got: %v", err)
+	}
+
+	// Cluster-scoped resources ignore the namespace
+	list, err := client.List(nil, "v1", "Namespace", "team-b", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if items, _, _ := unstructured.NestedSlice(list, "items"); len(items) != 1 {
+		t.Errorf("expected one Namespace, got: %v", list)
+	}
+}
+
+func TestGetCachedOrFetch(t *testing.T) {
+	t.Run("results are reused until they expire", func(t *testing.T) {
+		client := getTestKubeLookupClient(t, KubeLookupClientOptions{CacheTTL: time.Hour})
+
+		fetches := 0
+		fetch := func(ctx context.Context) (map[string]any, error) {
+			fetches++
+			return map[string]any{"fetches": int64(fetches)}, nil
+		}
+
+		first, _ := client.getCachedOrFetch(nil, "key", fetch)
+		second, _ := client.getCachedOrFetch(nil, "key", fetch)
+		if fetches != 1 || first["fetches"] != second["fetches"] {
+			t.Errorf("expected a single fetch, got %d", fetches)
+		}
+
+		// Results are copied, so callers can not change the cached ones
+		second["fetches"] = int64(100)
+		third, _ := client.getCachedOrFetch(nil, "key", fetch)
+		if third["fetches"] != int64(1) {
+			t.Errorf("expected cached result not to be modified, got %v", third)
+		}
+
+		client.cache["key"] = cacheEntryT{result: first, expiresAt: time.Now().Add(-time.Second)}
+		_, _ = client.getCachedOrFetch(nil, "key", fetch)
+		if fetches != 2 {
+			t.Errorf("expected expired result to be fetched again, got %d fetches", fetches)
+		}
+	})
+
+	t.Run("missing objects are cached as empty results", func(t *testing.T) {
+		client := getTestKubeLookupClient(t, KubeLookupClientOptions{CacheTTL: time.Hour})
+
+		fetches := 0
+		fetch := func(ctx context.Context) (map[string]any, error) {
+			fetches++
+			return nil, apierrors.NewNotFound(testConfigMapsResource.GroupResource(), "missing")
+		}
+
+		for range 2 {
+			result, err := client.getCachedOrFetch(nil, "key", fetch)
+			if err != nil || len(result) != 0 {
+				t.Fatalf("expected empty result, got %v, %v", result, err)
+			}
+		}
+		if fetches != 1 {
+			t.Errorf("expected a single fetch, got %d", fetches)
+		}
+	})
+
+	t.Run("other errors are not cached", func(t *testing.T) {
+		client := getTestKubeLookupClient(t, KubeLookupClientOptions{CacheTTL: time.Hour})
+
+		fetches := 0
+		fetch := func(ctx context.Context) (map[string]any, error) {
+			fetches++
+			return nil, errors.New("broken")
+		}
+
+		for range 2 {
+			_, err := client.getCachedOrFetch(nil, "key", fetch)
+			if err == nil || err.Error() != "broken" {
+				t.Fatalf("expected error 'broken', got %v", err)
+			}
+		}
+		if fetches != 2 {
+			t.Errorf("expected two fetches, got %d", fetches)
+		}
+	})
+
+	t.Run("cache size is bounded", func(t *testing.T) {
+		client := getTestKubeLookupClient(t, KubeLookupClientOptions{CacheTTL: time.Hour})
+
+		fetch := func(ctx context.Context) (map[string]any, error) {
+			return map[string]any{}, nil
+		}
+
+		for index := range cacheMaxEntries {
+			client.cache[fmt.Sprintf("key-%d", index)] = cacheEntryT{
+				result:    map[string]any{},
+				expiresAt: time.Now().Add(time.Hour + time.Duration(index)*time.Second),
+			}
+		}
+		client.cache["key-10"] = cacheEntryT{result: map[string]any{}, expiresAt: time.Now().Add(-time.Second)}
+
+		// Expired entries are removed first
+		_, _ = client.getCachedOrFetch(nil, "new-1", fetch)
+		if _, found := client.cache["key-10"]; found || len(client.cache) != cacheMaxEntries {
+			t.Errorf("expected expired entry to be evicted, got %d entries", len(client.cache))
+		}
+
+		// Then, the closest to expire
+		_, _ = client.getCachedOrFetch(nil, "new-2", fetch)
+		if _, found := client.cache["key-0"]; found || len(client.cache) != cacheMaxEntries {
+			t.Errorf("expected entry closest to expire to be evicted, got %d entries", len(client.cache))
+		}
+		if _, found := client.cache["new-2"]; !found {
+			t.Errorf("expected new entry to be cached")
+		}
+	})
+}
+
+func TestCallsBudget(t *testing.T) {
+	client := getTestKubeLookupClient(t, KubeLookupClientOptions{
+		AllowedResources:   []string{"configmaps"},
+		MaxCallsPerRequest: 2,
+	})
+
+	// Each call uses a different key, so none of them is served from cache
+	counter := &CallCounterT{}
+	for index := range 3 {
+		_, err := client.Get(counter, "v1", "ConfigMap", "team-a", fmt.Sprintf("settings-%d", index))
+
+		if index < 2 && err != nil {
+			t.Fatalf("unexpected error on call %d: %v", index, err)
+		}
+		if index == 2 && (err == nil || !strings.Contains(err.Error(), "budget exceeded: 2 calls per request")) {
+			t.Fatalf("expected budget error on call %d, got: %v", index, err)
+		}
+	}
+
+	// Cached results don't consume the budget
+	client.options.CacheTTL = time.Hour
+	cachedCounter := &CallCounterT{}
+	for range 3 {
+		_, err := client.Get(cachedCounter, "v1", "ConfigMap", "team-a", "settings")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// Budgets are per request
+	_, err := client.Get(&CallCounterT{}, "v1", "ConfigMap", "team-a", "other")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Zero means unlimited
+	client.options.MaxCallsPerRequest = 0
+	unlimitedCounter := &CallCounterT{}
+	for index := range 5 {
+		_, err := client.Get(unlimitedCounter, "v1", "ConfigMap", "team-a", fmt.Sprintf("unlimited-%d", index))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
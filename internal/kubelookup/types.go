@@ -0,0 +1,76 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelookup
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	//
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/dynamic"
+)
+
+// KubeLookupClientOptions represents the behavior of the lookups done from templates
+type KubeLookupClientOptions struct {
+	// AllowedResources is a list of resources that can be looked up, following kubectl style for resources,
+	// optionally scoped to a namespace and a verb, like RBAC rules: [{namespace}/]{resource}.{group}[:{verb}]
+	// Wildcards are allowed for all the parts. Empty list disables lookups
+	AllowedResources []string
+
+	// CacheTTL is the time a result is reused before asking Kubernetes again
+	CacheTTL time.Duration
+
+	// MaxCallsPerRequest is the max number of calls to Kubernetes done by the templates of a request.
+	// Zero means unlimited
+	MaxCallsPerRequest int
+}
+
+// KubeLookupClient performs read-only calls to Kubernetes on behalf of templates
+type KubeLookupClient struct {
+	ctx          context.Context
+	options      KubeLookupClientOptions
+	allowedRules []allowedResourceRuleT
+
+	client dynamic.Interface
+	mapper meta.RESTMapper
+
+	mu    sync.Mutex
+	cache map[string]cacheEntryT
+}
+
+// allowedResourceRuleT represents a parsed entry of the allowlist. Each part can be a wildcard
+type allowedResourceRuleT struct {
+	namespace string
+	resource  string
+	group     string
+	verb      string
+}
+
+// cacheEntryT represents a result stored in cache
+type cacheEntryT struct {
+	result    map[string]any
+	expiresAt time.Time
+}
+
+// CallCounterT counts the calls done to Kubernetes during the evaluation of a request.
+// It's shared by all the templates evaluated for the same request
+type CallCounterT struct {
+	mu    sync.Mutex
+	calls int
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	metricsNamespace = "admitik"
+)
+
+var (
+	// MutationNonIdempotentTotal represents the times a mutation produced changes
+	// when it was applied again over its own output
+	MutationNonIdempotentTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "mutation_non_idempotent_total",
+		Help:      "Times a ClusterMutationPolicy produced changes when it was applied again over its own output",
+	}, []string{"policy"})
+
+	// MutationConflictsTotal represents the times a mutation overwrote a path patched by a previous policy
+	MutationConflictsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "mutation_conflicts_total",
+		Help:      "Times a ClusterMutationPolicy overwrote a path patched by a previous one",
+	}, []string{"resource", "policy", "overwritten_policy"})
+
+	// MutationAuditedTotal represents the times a policy in audit mode would have changed an object
+	MutationAuditedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "mutation_audited_total",
+		Help:      "Times a ClusterMutationPolicy in audit mode would have changed or rejected an object",
+	}, []string{"policy", "action"})
+)
+
+// init registers the metrics in the registry served by Controller Runtime metrics server
+func init() {
+	metrics.Registry.MustRegister(
+		MutationNonIdempotentTotal,
+		MutationConflictsTotal,
+		MutationAuditedTotal,
+	)
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mutationconflicts
+
+import (
+	"sort"
+	"time"
+)
+
+// NewMutationConflictsRegistry return a new empty MutationConflictsRegistry
+func NewMutationConflictsRegistry() *MutationConflictsRegistry {
+	return &MutationConflictsRegistry{
+		conflicts: make(map[ConflictKeyT]ConflictT),
+	}
+}
+
+// AddConflict accounts a conflict between two policies on a path of a resource type
+func (r *MutationConflictsRegistry) AddConflict(key ConflictKeyT) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	conflict := r.conflicts[key]
+	conflict.ConflictKeyT = key
+	conflict.Count++
+	conflict.LastSeenTime = time.Now()
+	r.conflicts[key] = conflict
+}
+
+// GetConflicts return the conflicts of a resource type, sorted by path.
+// When the resource is empty, the conflicts of all the resource types are returned
+func (r *MutationConflictsRegistry) GetConflicts(resource string) []ConflictT {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := []ConflictT{}
+	for key, conflict := range r.conflicts {
+		if resource != "" && key.Resource != resource {
+			continue
+		}
+		result = append(result, conflict)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Resource != result[j].Resource {
+			return result[i].Resource < result[j].Resource
+		}
+		if result[i].Path != result[j].Path {
+			return result[i].Path < result[j].Path
+		}
+		return result[i].Policy < result[j].Policy
+	})
+
+	return result
+}
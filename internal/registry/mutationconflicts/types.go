@@ -0,0 +1,49 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mutationconflicts
+
+import (
+	"sync"
+	"time"
+)
+
+// ConflictKeyT identifies a path of a resource type written by a policy after another one patched it
+type ConflictKeyT struct {
+	// Resource represents the resource type, following the pattern {group}/{version}/{resource}
+	Resource string `json:"resource"`
+	Path     string `json:"path"`
+
+	// OverwrittenPolicy represents the policy that patched the path first
+	OverwrittenPolicy string `json:"overwrittenPolicy"`
+	Policy            string `json:"policy"`
+}
+
+// ConflictT represents a conflict between two policies, and the times it happened
+type ConflictT struct {
+	ConflictKeyT `json:",inline"`
+
+	Count        int64     `json:"count"`
+	LastSeenTime time.Time `json:"lastSeenTime"`
+}
+
+// MutationConflictsRegistry stores the conflicts between mutation policies detected on admission,
+// so they can be reported on demand
+type MutationConflictsRegistry struct {
+	mu sync.Mutex
+
+	conflicts map[ConflictKeyT]ConflictT
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policycounters
+
+// NewPolicyCountersRegistry return a new empty PolicyCountersRegistry
+func NewPolicyCountersRegistry() *PolicyCountersRegistry {
+	return &PolicyCountersRegistry{
+		counters: make(map[PolicyKeyT]CountersT),
+	}
+}
+
+// AddEvaluation accounts an evaluation of a policy.
+// Violated means the policy was not met, and failed means something broke during the evaluation
+func (r *PolicyCountersRegistry) AddEvaluation(kind, name string, violated, failed bool) {
+	delta := CountersT{Evaluations: 1}
+	if violated {
+		delta.Violations = 1
+	}
+	if failed {
+		delta.Errors = 1
+	}
+
+	r.Add(PolicyKeyT{Kind: kind, Name: name}, delta)
+}
+
+// AddNonIdempotent accounts a mutation of a policy that produced changes when it was applied again over its own output
+func (r *PolicyCountersRegistry) AddNonIdempotent(kind, name string) {
+	r.Add(PolicyKeyT{Kind: kind, Name: name}, CountersT{NonIdempotent: 1})
+}
+
+// Add increases the counters of a policy by the given amounts.
+// It's also used to give back counters that could not be flushed
+func (r *PolicyCountersRegistry) Add(key PolicyKeyT, delta CountersT) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	counters := r.counters[key]
+	counters.Evaluations += delta.Evaluations
+	counters.Violations += delta.Violations
+	counters.Errors += delta.Errors
+	counters.NonIdempotent += delta.NonIdempotent
+	r.counters[key] = counters
+}
+
+// PopAll return the counters of all the policies, resetting them in the registry
+func (r *PolicyCountersRegistry) PopAll() map[PolicyKeyT]CountersT {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := r.counters
+	r.counters = make(map[PolicyKeyT]CountersT)
+
+	return result
+}
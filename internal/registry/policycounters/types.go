@@ -0,0 +1,44 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policycounters
+
+import (
+	"sync"
+)
+
+// PolicyKeyT identifies a policy in the registry
+type PolicyKeyT struct {
+	Kind string
+	Name string
+}
+
+// CountersT represents the amount of events happened to a policy since the last flush
+type CountersT struct {
+	Evaluations int64
+	Violations  int64
+	Errors      int64
+
+	NonIdempotent int64
+}
+
+// PolicyCountersRegistry stores the counters of the policies in memory until they are flushed into their status.
+// This way, policies' status is not updated on each evaluation
+type PolicyCountersRegistry struct {
+	mu sync.Mutex
+
+	counters map[PolicyKeyT]CountersT
+}
@@ -103,6 +103,33 @@ func (m *SourcesRegistry) IsStarted(rt ResourceTypeName) bool {
 	return informer.Started
 }
 
+// SetSynced updates the 'synced' flag of an informer
+func (m *SourcesRegistry) SetSynced(rt ResourceTypeName, synced bool) error {
+	informer, exists := m.GetInformer(rt)
+	if !exists {
+		return errors.New("extra-resource informer not found")
+	}
+
+	informer.mu.Lock()
+	defer informer.mu.Unlock()
+
+	informer.Synced = synced
+	return nil
+}
+
+// IsSynced returns whether an informer of the provided resource type finished the initial listing or not
+func (m *SourcesRegistry) IsSynced(rt ResourceTypeName) bool {
+	informer, exists := m.GetInformer(rt)
+	if !exists {
+		return false
+	}
+
+	informer.mu.Lock()
+	defer informer.mu.Unlock()
+
+	return informer.Synced
+}
+
 // GetInformer return the informer attached to a resource type
 func (m *SourcesRegistry) GetInformer(rt ResourceTypeName) (informer *SourcesInformer, exists bool) {
 	m.mu.Lock()
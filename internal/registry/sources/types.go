@@ -29,6 +29,9 @@ type SourcesInformer struct {
 	Started    bool
 	StopSignal chan bool
 
+	// Synced is true when the informer finished the initial listing of resources
+	Synced bool
+
 	// ItemPool represents a pool of stored resources that are being collected by the watcher
 	ItemPool []*map[string]any
 }
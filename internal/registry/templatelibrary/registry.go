@@ -0,0 +1,129 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package templatelibrary
+
+import (
+	"slices"
+	"strings"
+
+	//
+	"github.com/freepik-company/admitik/api/v1alpha1"
+)
+
+// NewTemplateLibraryRegistry return a new empty TemplateLibraryRegistry
+func NewTemplateLibraryRegistry() *TemplateLibraryRegistry {
+	return &TemplateLibraryRegistry{
+		libraries: make(map[string]*v1alpha1.TemplateLibrary),
+	}
+}
+
+// AddOrUpdateLibrary add a library to the registry.
+// When the library already exists, updates it
+func (r *TemplateLibraryRegistry) AddOrUpdateLibrary(library *v1alpha1.TemplateLibrary) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.libraries[library.Name] = library
+	r.revision++
+}
+
+// GetLibrary return a library from the registry
+func (r *TemplateLibraryRegistry) GetLibrary(libraryName string) (library *v1alpha1.TemplateLibrary, found bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	library, found = r.libraries[libraryName]
+	return library, found
+}
+
+// GetLibraries return all the libraries from the registry, sorted by name
+func (r *TemplateLibraryRegistry) GetLibraries() (libraries []*v1alpha1.TemplateLibrary) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, library := range r.libraries {
+		libraries = append(libraries, library)
+	}
+
+	slices.SortFunc(libraries, func(a, b *v1alpha1.TemplateLibrary) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return libraries
+}
+
+// RemoveLibrary delete a library from the registry
+func (r *TemplateLibraryRegistry) RemoveLibrary(libraryName string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, found := r.libraries[libraryName]; !found {
+		return
+	}
+
+	delete(r.libraries, libraryName)
+	r.revision++
+}
+
+// GetRevision return a number that changes each time the libraries change
+func (r *TemplateLibraryRegistry) GetRevision() uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.revision
+}
+
+// GetGotmplTemplates return all the Go templates from all the libraries.
+// Returned map is indexed following the pattern: {library}/{name}
+func (r *TemplateLibraryRegistry) GetGotmplTemplates() map[string]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := make(map[string]string)
+	for libraryName, library := range r.libraries {
+		for _, item := range library.Spec.Gotmpl {
+			result[libraryName+"/"+item.Name] = item.Template
+		}
+	}
+
+	return result
+}
+
+// GetStarlarkModule return the code of a Starlark module.
+// Modules are referenced following the pattern: {library}/{name}
+func (r *TemplateLibraryRegistry) GetStarlarkModule(moduleName string) (code string, found bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	libraryName, itemName, valid := strings.Cut(moduleName, "/")
+	if !valid {
+		return code, false
+	}
+
+	library, libraryFound := r.libraries[libraryName]
+	if !libraryFound {
+		return code, false
+	}
+
+	for _, item := range library.Spec.Starlark {
+		if item.Name == itemName {
+			return item.Template, true
+		}
+	}
+
+	return code, false
+}
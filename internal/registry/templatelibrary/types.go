@@ -0,0 +1,35 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package templatelibrary
+
+import (
+	"sync"
+
+	//
+	"github.com/freepik-company/admitik/api/v1alpha1"
+)
+
+// TemplateLibraryRegistry stores TemplateLibrary resources to be consumed by template engines
+type TemplateLibraryRegistry struct {
+	mu sync.Mutex
+
+	// libraries are indexed by the name of the TemplateLibrary
+	libraries map[string]*v1alpha1.TemplateLibrary
+
+	// revision is increased on each change. Template engines use it to know when their caches are outdated
+	revision uint64
+}
@@ -25,6 +25,8 @@ import (
 
 	//
 	"github.com/freepik-company/admitik/internal/common"
+	"github.com/freepik-company/admitik/internal/metrics"
+	mutationConflictsRegistry "github.com/freepik-company/admitik/internal/registry/mutationconflicts"
 	"github.com/freepik-company/admitik/internal/template"
 )
 
@@ -50,5 +52,83 @@ func (s *HttpServer) populatePolicyDataFromAdmission(adReview *admissionv1.Admis
 		return fmt.Errorf("failed decoding JSON field 'request.object': %s", err.Error())
 	}
 
+	// Store the differences between both objects on updates
+	if injectedData.Operation == common.NormalizedOperationUpdate {
+		err = injectedData.PopulateChanges()
+		if err != nil {
+			return err
+		}
+	}
+
+	// Store the user performing the request.
+	// It's kept typed too, as some template functions (like 'can') need its whole data
+	userInfoBytes, err := json.Marshal(adReview.Request.UserInfo)
+	if err != nil {
+		return fmt.Errorf("failed encoding JSON field 'request.userInfo': %s", err.Error())
+	}
+
+	err = json.Unmarshal(userInfoBytes, &injectedData.UserInfo)
+	if err != nil {
+		return fmt.Errorf("failed decoding JSON field 'request.userInfo': %s", err.Error())
+	}
+
+	injectedData.GetRequestState().Requester = &adReview.Request.UserInfo
+
+	return nil
+}
+
+// updatePolicyDataObject replaces the object in the policy evaluation context with a patched version of it.
+// Changes are computed again on updates, so they reflect the patched object too
+func (s *HttpServer) updatePolicyDataObject(injectedData *template.PolicyEvaluationDataT, objectBytes []byte) (err error) {
+
+	// A new map is needed, as decoding into the current one would merge both objects
+	patchedObject := map[string]any{}
+	err = json.Unmarshal(objectBytes, &patchedObject)
+	if err != nil {
+		return fmt.Errorf("failed decoding patched object: %s", err.Error())
+	}
+	injectedData.Object = patchedObject
+
+	if injectedData.Operation == common.NormalizedOperationUpdate {
+		err = injectedData.PopulateChanges()
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
+
+// accountPolicyEvaluation stores the result of evaluating a policy, to be flushed later into its status
+func (s *HttpServer) accountPolicyEvaluation(kind, name string, violated, failed bool) {
+	if s.dependencies.PolicyCountersRegistry == nil {
+		return
+	}
+	s.dependencies.PolicyCountersRegistry.AddEvaluation(kind, name, violated, failed)
+}
+
+// accountNonIdempotentMutation stores a mutation that produced changes when it was applied again over its own output
+func (s *HttpServer) accountNonIdempotentMutation(kind, name string) {
+	metrics.MutationNonIdempotentTotal.WithLabelValues(name).Inc()
+
+	if s.dependencies.PolicyCountersRegistry == nil {
+		return
+	}
+	s.dependencies.PolicyCountersRegistry.AddNonIdempotent(kind, name)
+}
+
+// accountAuditedMutation stores an action that a policy in audit mode would have done over an object
+func (s *HttpServer) accountAuditedMutation(name, action string) {
+	metrics.MutationAuditedTotal.WithLabelValues(name, action).Inc()
+}
+
+// accountMutationConflicts stores the conflicts between mutation policies, to be reported on demand
+func (s *HttpServer) accountMutationConflicts(conflicts []mutationConflictsRegistry.ConflictKeyT) {
+	for _, conflict := range conflicts {
+		metrics.MutationConflictsTotal.WithLabelValues(conflict.Resource, conflict.Policy, conflict.OverwrittenPolicy).Inc()
+
+		if s.dependencies.MutationConflictsRegistry != nil {
+			s.dependencies.MutationConflictsRegistry.AddConflict(conflict)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admission
+
+import (
+	"strings"
+	"testing"
+
+	//
+	"github.com/freepik-company/admitik/internal/common"
+	"github.com/freepik-company/admitik/internal/template"
+)
+
+func TestUpdatePolicyDataObject(t *testing.T) {
+	tests := []struct {
+		name          string
+		operation     string
+		patchedObject string
+
+		// expectedLabels are the labels of 'object' after the update
+		expectedLabels map[string]any
+
+		// expectedChanges are the paths of the changes after the update. Only checked on updates
+		expectedChanges []string
+
+		// expectedErr is a part of the expected error message. Empty when no error is expected
+		expectedErr string
+	}{
+		{
+			name:           "object is replaced instead of merged",
+			operation:      common.NormalizedOperationCreate,
+			patchedObject:  `{"metadata":{"name":"example","labels":{"patched":"true"}}}`,
+			expectedLabels: map[string]any{"patched": "true"},
+		},
+		{
+			name:            "changes are computed again on updates",
+			operation:       common.NormalizedOperationUpdate,
+			patchedObject:   `{"metadata":{"name":"example","labels":{"team":"a","patched":"true"}}}`,
+			expectedLabels:  map[string]any{"team": "a", "patched": "true"},
+			expectedChanges: []string{"/metadata/labels/patched"},
+		},
+		{
+			name:          "broken object is reported",
+			operation:     common.NormalizedOperationCreate,
+			patchedObject: `{"metadata":`,
+			expectedErr:   "failed decoding patched object",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			injectedData := &template.PolicyEvaluationDataT{}
+			injectedData.Initialize()
+			injectedData.Operation = test.operation
+			injectedData.Object = map[string]any{
+				"metadata": map[string]any{"name": "example", "labels": map[string]any{"team": "a"}},
+			}
+			injectedData.OldObject = map[string]any{
+				"metadata": map[string]any{"name": "example", "labels": map[string]any{"team": "a"}},
+			}
+			injectedData.OriginalObject = injectedData.Object
+
+			err := (&HttpServer{}).updatePolicyDataObject(injectedData, []byte(test.patchedObject))
+			if test.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.expectedErr) {
+					t.Fatalf("expected error containing '%s', got: %v", test.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+
+			labels := injectedData.Object["metadata"].(map[string]any)["labels"].(map[string]any)
+			if len(labels) != len(test.expectedLabels) {
+				t.Errorf("expected labels %v, got %v", test.expectedLabels, labels)
+			}
+			for key, value := range test.expectedLabels {
+				if labels[key] != value {
+					t.Errorf("expected labels %v, got %v", test.expectedLabels, labels)
+				}
+			}
+
+			// Object as it came in the request is kept for templates
+			originalLabels := injectedData.ToMap()["originalObject"].(map[string]any)["metadata"].(map[string]any)["labels"].(map[string]any)
+			if len(originalLabels) != 1 || originalLabels["team"] != "a" {
+				t.Errorf("expected originalObject to be untouched, got labels %v", originalLabels)
+			}
+
+			if test.operation != common.NormalizedOperationUpdate {
+				return
+			}
+
+			var changedPaths []string
+			for _, change := range injectedData.Changes {
+				changedPaths = append(changedPaths, change.(map[string]any)["path"].(string))
+			}
+			if strings.Join(changedPaths, ",") != strings.Join(test.expectedChanges, ",") {
+				t.Errorf("expected changes %v, got %v", test.expectedChanges, changedPaths)
+			}
+		})
+	}
+}
@@ -18,13 +18,14 @@ package admission
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
 
 	//
-	jsonpatch "github.com/evanphx/json-patch/v5"
 	"github.com/wI2L/jsondiff"
 	admissionv1 "k8s.io/api/admission/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -32,15 +33,22 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/dynamic"
 	"sigs.k8s.io/controller-runtime/pkg/log"
-	"sigs.k8s.io/yaml"
 
 	//
 	"github.com/freepik-company/admitik/api/v1alpha1"
 	"github.com/freepik-company/admitik/internal/common"
+	"github.com/freepik-company/admitik/internal/controller"
 	"github.com/freepik-company/admitik/internal/globals"
+	mutationConflictsRegistry "github.com/freepik-company/admitik/internal/registry/mutationconflicts"
 	"github.com/freepik-company/admitik/internal/template"
 )
 
+const (
+	// mutatedByAuditAnnotation represents the key of the audit annotation recording the operations done by each policy.
+	// Kubernetes prefixes it with the webhook name, so a fixed short key keeps it valid for any policy name
+	mutatedByAuditAnnotation = "mutated-by"
+)
+
 // handleRequest handles the incoming requests
 func (s *HttpServer) handleMutationRequest(response http.ResponseWriter, request *http.Request) {
 	logger := log.FromContext(request.Context()).WithValues("controller", "admissionserver")
@@ -119,12 +127,26 @@ func (s *HttpServer) handleMutationRequest(response http.ResponseWriter, request
 		return
 	}
 
+	// Keep the object as it came, as 'object' is replaced by the patched one on each policy
+	commonTemplateInjectedObject.OriginalObject = commonTemplateInjectedObject.Object
+
 	// Loop over ClusterMutationPolicy objects collecting the patches to apply.
 	// Patches are calculated incrementally, applying patched over the previous patched object.
 	// At this point, some extra params will be added to the object that will be injected in template
 	jsonPatchOperations := jsondiff.Patch{}
 	patchedObjectBytes := requestObj.Request.Object.Raw
 
+	// Keep the policy that patched each path, to report policies overwriting the changes of previous ones
+	resourceType := fmt.Sprintf("%s/%s/%s",
+		requestObj.Request.Resource.Group,
+		requestObj.Request.Resource.Version,
+		requestObj.Request.Resource.Resource)
+	pathWriters := map[string]string{}
+
+	// Keep the policies that changed the object, to record them on the object and the audit logs
+	mutatingPolicies := []string{}
+	mutatingPoliciesSummaries := []string{}
+
 	cmPolicyList := s.dependencies.ClusterMutationPolicyRegistry.GetResources(resourcePattern)
 	for _, cmPolicyObj := range cmPolicyList {
 
@@ -140,50 +162,167 @@ func (s *HttpServer) handleMutationRequest(response http.ResponseWriter, request
 
 		specificTemplateInjectedObject := commonTemplateInjectedObject
 		specificTemplateInjectedObject.Sources = tmpFetchedPolicySources
+		specificTemplateInjectedObject.Vars = make(map[string]any)
+
+		// Evaluate the policy: variables, conditions, program and patch
+		evaluationResult := s.dependencies.Evaluator.EvaluateMutation(cmPolicyObj, &specificTemplateInjectedObject, patchedObjectBytes)
+		for _, evaluationErr := range evaluationResult.Errors {
+			logger.Info(evaluationErr.Error())
+		}
 
-		// Evaluate template conditions
-		conditionsPassed, condErr := common.IsPassingConditions(cmPolicyObj.Spec.Conditions, &specificTemplateInjectedObject)
-		if condErr != nil {
-			logger.Info("failed evaluating conditions", "error", condErr.Error())
+		reviewResponse.Response.Warnings = append(reviewResponse.Response.Warnings, evaluationResult.Warnings...)
+		s.accountPolicyEvaluation(controller.ClusterMutationPolicyResourceType, cmPolicyObj.Name,
+			!evaluationResult.Allowed, len(evaluationResult.Errors) > 0)
+
+		// Policy could not be evaluated, or its patch could not be computed, so the object is not patched by this policy
+		if evaluationResult.AbortReason != "" {
+			err = common.CreateKubeEvent(request.Context(), "default", "admission-server",
+				commonTemplateInjectedObject.Object, *cmPolicyObj, "MutationAborted", evaluationResult.AbortReason)
+			if err != nil {
+				logger.Info(fmt.Sprintf("failed creating Kubernetes event: %s", err.Error()))
+			}
+			continue
 		}
 
 		// Conditions are not met, skip patching the resource
-		if !conditionsPassed {
-			// TODO: Should we log, or throw an event, when conditions are not met?
+		// TODO: Should we log, or throw an event, when conditions are not met?
+		if !evaluationResult.ConditionsPassed {
 			continue
 		}
 
-		// When some condition is not met, evaluate patch's template and emit a response
-		var kubeEventAction string = "MutationAborted"
-		var kubeEventMessage string
+		// Policies in audit mode only report what they would do
+		auditMode := strings.EqualFold(cmPolicyObj.Spec.Mode, v1alpha1.MutationModeAudit)
 
-		var parsedPatch string
-		var tmpJsonPatchOperations jsondiff.Patch
-		var tmpPatchedObjectBytes []byte
+		// Programs are able to reject the object. First rejection causes early full rejection
+		if !evaluationResult.Allowed && auditMode {
+			auditMessage := fmt.Sprintf("object would be rejected by program: %s", evaluationResult.Message)
+			logger.Info(auditMessage, "mode", v1alpha1.MutationModeAudit)
+			s.accountAuditedMutation(cmPolicyObj.Name, "reject")
 
-		parsedPatch, err = template.EvaluateTemplate(cmPolicyObj.Spec.Patch.Engine, cmPolicyObj.Spec.Patch.Template, &specificTemplateInjectedObject)
-		if err != nil {
-			logger.Info(fmt.Sprintf("failed parsing patch template: %s", err.Error()))
-			kubeEventMessage = "Patch template failed. More info in controller logs."
-			goto createKubeEvent
+			err = common.CreateKubeEvent(request.Context(), "default", "admission-server",
+				commonTemplateInjectedObject.Object, *cmPolicyObj, "MutationAudited", auditMessage)
+			if err != nil {
+				logger.Info(fmt.Sprintf("failed creating Kubernetes event: %s", err.Error()))
+			}
+			continue
 		}
 
-		tmpJsonPatchOperations, tmpPatchedObjectBytes, err = s.generateJsonPatchOperations(patchedObjectBytes, cmPolicyObj.Spec.Patch.Type, []byte(parsedPatch))
+		if !evaluationResult.Allowed {
+			logger.Info(fmt.Sprintf("object rejected by program: %s", evaluationResult.Message))
+			reviewResponse.Response.Allowed = false
+			reviewResponse.Response.Result = &metav1.Status{
+				Code:    http.StatusForbidden,
+				Message: evaluationResult.Message,
+			}
+
+			err = common.CreateKubeEvent(request.Context(), "default", "admission-server",
+				commonTemplateInjectedObject.Object, *cmPolicyObj, "Rejected", evaluationResult.Message)
+			if err != nil {
+				logger.Info(fmt.Sprintf("failed creating Kubernetes event: %s", err.Error()))
+			}
+			return
+		}
+
+		// Nothing to patch
+		if evaluationResult.PatchedObject == nil {
+			continue
+		}
+
+		// Would-be operations are reported, but neither applied nor seen by following policies.
+		// Their result is not validated, as dry-run validation sends requests to Kubernetes
+		if auditMode {
+			if len(evaluationResult.JsonPatchOperations) > 0 {
+				auditMessage := fmt.Sprintf("object would be patched: %s", evaluationResult.JsonPatchOperations.String())
+				logger.Info(auditMessage, "mode", v1alpha1.MutationModeAudit)
+				s.accountAuditedMutation(cmPolicyObj.Name, "patch")
+
+				err = common.CreateKubeEvent(request.Context(), "default", "admission-server",
+					commonTemplateInjectedObject.Object, *cmPolicyObj, "MutationAudited", auditMessage)
+				if err != nil {
+					logger.Info(fmt.Sprintf("failed creating Kubernetes event: %s", err.Error()))
+				}
+			}
+			continue
+		}
+
+		// Policies producing invalid objects are dropped, so they don't break the whole request later
+		err = s.validateMutationResult(cmPolicyObj, requestObj.Request, evaluationResult.PatchedObject)
 		if err != nil {
-			logger.Info(fmt.Sprintf("failed generating canonical jsonPatch operations for Kube API server: %s", err.Error()))
-			kubeEventMessage = "Generated patch is invalid. More info in controller logs."
-			goto createKubeEvent
+			logger.Info(fmt.Sprintf("patched object is not valid. Dropping the patch: %s", err.Error()))
+
+			err = common.CreateKubeEvent(request.Context(), "default", "admission-server",
+				commonTemplateInjectedObject.Object, *cmPolicyObj, "MutationDropped",
+				fmt.Sprintf("Patched object is not valid: %s", err.Error()))
+			if err != nil {
+				logger.Info(fmt.Sprintf("failed creating Kubernetes event: %s", err.Error()))
+			}
+			continue
+		}
+
+		// Policies reinvoked by the webhook must be idempotent, or they would stack their changes on each pass
+		if strings.EqualFold(cmPolicyObj.Spec.ReinvocationPolicy, v1alpha1.MutationReinvocationPolicyIfNeeded) {
+			idempotencyChanges, idempotencyErr := s.checkMutationIdempotency(cmPolicyObj,
+				specificTemplateInjectedObject, evaluationResult.PatchedObject)
+			if idempotencyErr != nil {
+				logger.Info(fmt.Sprintf("failed checking idempotency of the patch: %s", idempotencyErr.Error()))
+			} else if len(idempotencyChanges) > 0 {
+				logger.Info("patch is not idempotent. Reinvocations will apply changes again",
+					"jsonPatchOperations", idempotencyChanges.String())
+				s.accountNonIdempotentMutation(controller.ClusterMutationPolicyResourceType, cmPolicyObj.Name)
+			}
+		}
+
+		// Policies with higher priority silently win, so their authors are warned about it
+		conflicts := detectMutationConflicts(pathWriters, resourceType, cmPolicyObj.Name, evaluationResult.JsonPatchOperations)
+		if len(conflicts) > 0 {
+			s.accountMutationConflicts(conflicts)
+
+			conflictMessages := []string{}
+			for _, conflict := range conflicts {
+				conflictMessages = append(conflictMessages,
+					fmt.Sprintf("'%s' patched by '%s'", conflict.Path, conflict.OverwrittenPolicy))
+			}
+			conflictMessage := fmt.Sprintf("Policy overwrites paths patched by previous policies: %s",
+				strings.Join(conflictMessages, ", "))
+			logger.Info(conflictMessage)
+
+			err = common.CreateKubeEvent(request.Context(), "default", "admission-server",
+				commonTemplateInjectedObject.Object, *cmPolicyObj, "MutationConflict", conflictMessage)
+			if err != nil {
+				logger.Info(fmt.Sprintf("failed creating Kubernetes event: %s", err.Error()))
+			}
 		}
 
-		patchedObjectBytes = tmpPatchedObjectBytes
-		jsonPatchOperations = append(jsonPatchOperations, tmpJsonPatchOperations...)
-		continue
+		patchedObjectBytes = evaluationResult.PatchedObject
+		jsonPatchOperations = append(jsonPatchOperations, evaluationResult.JsonPatchOperations...)
 
-	createKubeEvent:
-		err = common.CreateKubeEvent(request.Context(), "default", "admission-server",
-			commonTemplateInjectedObject.Object, *cmPolicyObj, kubeEventAction, kubeEventMessage)
+		if len(evaluationResult.JsonPatchOperations) > 0 {
+			mutatingPolicies = append(mutatingPolicies, fmt.Sprintf("%s:%d", cmPolicyObj.Name, cmPolicyObj.Generation))
+			mutatingPoliciesSummaries = append(mutatingPoliciesSummaries,
+				fmt.Sprintf("%s: %s", cmPolicyObj.Name, getPatchSummary(evaluationResult.JsonPatchOperations)))
+		}
+
+		// Following policies see the object as it was patched by the previous ones
+		err = s.updatePolicyDataObject(&commonTemplateInjectedObject, patchedObjectBytes)
 		if err != nil {
-			logger.Info(fmt.Sprintf("failed creating Kubernetes event: %s", err.Error()))
+			logger.Info(fmt.Sprintf("failed updating the object injected in templates: %s", err.Error()))
+		}
+	}
+
+	// Record the policies that changed the object on the object itself.
+	// Requests changed by no policy keep the existing record, as no-op updates and reinvocations are frequent
+	if s.options.MutationRecordAnnotation && len(mutatingPolicies) > 0 && len(patchedObjectBytes) > 0 {
+		annotationOperations, annotationErr := getMutatedByAnnotationOperations(patchedObjectBytes, mutatingPolicies)
+		if annotationErr != nil {
+			logger.Info(fmt.Sprintf("failed recording mutating policies in the object: %s", annotationErr.Error()))
+		} else {
+			jsonPatchOperations = append(jsonPatchOperations, annotationOperations...)
+		}
+	}
+
+	if len(mutatingPoliciesSummaries) > 0 {
+		reviewResponse.Response.AuditAnnotations = map[string]string{
+			mutatedByAuditAnnotation: strings.Join(mutatingPoliciesSummaries, "; "),
 		}
 	}
 
@@ -195,151 +334,188 @@ func (s *HttpServer) handleMutationRequest(response http.ResponseWriter, request
 	reviewResponse.Response.PatchType = &patchType
 }
 
-// generateJsonPatchOperations return a group of JsonPatch operations to mutate an object from its original
-// state to a final state. It's compatible with 'jsonpatch', 'jsonmerge' and 'strategicmerge' patch types.
-func (s *HttpServer) generateJsonPatchOperations(objectToPatch []byte, patchType string, patch []byte) (jsonPatchOperations jsondiff.Patch, patchedObject []byte, err error) {
-	logger := log.FromContext(*s.dependencies.Context).
-		WithValues("controller", "admissionserver", "action", "mutation")
-
-	var patchedObjectBytes []byte
-	patchType = strings.ToLower(patchType)
+// getMutatedByAnnotationOperations return the JsonPatch operations that set the annotation recording the policies
+// that changed the object. Policies are expressed as {name}:{generation}.
+// The annotation is never removed, so no operations are returned when no policy changed the object
+func getMutatedByAnnotationOperations(patchedObject []byte, mutatingPolicies []string) (jsondiff.Patch, error) {
 
-	// Apply user-defined patch to the entering object
-	switch patchType {
-	case v1alpha1.MutationPatchTypeMerge:
-		patchedObjectBytes, err = s.generateJsonMergePatch(objectToPatch, patch)
-		if err != nil {
-			return nil, nil, fmt.Errorf("jsonmerge patch failed: %v", err)
-		}
-	case v1alpha1.MutationPatchTypeStrategicMerge:
-		patchedObjectBytes, err = s.generateStrategicMergePatch(objectToPatch, patch)
-		if err != nil {
-			return nil, nil, fmt.Errorf("strategicmerge patch failed: %v", err)
-		}
-	default:
-		patchedObjectBytes, err = s.generateJsonPatchPatch(objectToPatch, patch)
-		if err != nil {
-			return nil, nil, fmt.Errorf("jsonpatch patch failed: %v", err)
-		}
+	if len(mutatingPolicies) == 0 {
+		return nil, nil
 	}
 
-	// Calculate the difference for Kubernetes API
-	// Store only successful operations in the operations list
-	var tmpJsonPatchOperations jsondiff.Patch
-
-	tmpJsonPatchOperations, err = jsondiff.CompareJSON(objectToPatch, patchedObjectBytes)
+	tmpObject := unstructured.Unstructured{}
+	err := json.Unmarshal(patchedObject, &tmpObject.Object)
 	if err != nil {
-		return nil, nil, err
+		return nil, fmt.Errorf("failed decoding patched object: %w", err)
 	}
 
-	// DEBUG: log candidate object, already patched candidate for it and operations that will be applied.
-	// This is useful to identify issues in patching engines.
-	logger.V(5).Info("generated patch",
-		"type", patchType,
-		"candidate", string(objectToPatch),
-		"patchedCandidate", string(patchedObjectBytes),
-		"jsonPatchOperations", tmpJsonPatchOperations.String(),
-	)
-
-	return tmpJsonPatchOperations, patchedObjectBytes, nil
-}
-
-// generateJsonPatchPatch patches the object using JSONPath strategy and returns the resulting object
-// Patch must be expressed in JSON
-func (s *HttpServer) generateJsonPatchPatch(objectToPatch []byte, patch []byte) (patchedObjectBytes []byte, err error) {
+	annotations := tmpObject.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[controller.MutatedByAnnotation] = strings.Join(mutatingPolicies, ",")
+	tmpObject.SetAnnotations(annotations)
 
-	var tmpPatch jsonpatch.Patch
-	tmpPatch, err = jsonpatch.DecodePatch(patch)
+	annotatedObject, err := json.Marshal(tmpObject.Object)
 	if err != nil {
 		return nil, err
 	}
 
-	patchedObjectBytes, err = tmpPatch.Apply(objectToPatch)
-	if err != nil {
-		return nil, err
+	return jsondiff.CompareJSON(patchedObject, annotatedObject)
+}
+
+// getPatchSummary return a short description of the operations done by a patch, to be stored in audit logs
+func getPatchSummary(jsonPatchOperations jsondiff.Patch) string {
+	operations := []string{}
+	for _, operation := range jsonPatchOperations {
+		operations = append(operations, fmt.Sprintf("%s %s", operation.Type, operation.Path))
 	}
 
-	return patchedObjectBytes, nil
+	return strings.Join(operations, ", ")
 }
 
-// generateJsonMergePatch patches the object using JSONMerge strategy and returns the resulting object
-// Patch must be expressed in YAML
-func (s *HttpServer) generateJsonMergePatch(objectToPatch []byte, patch []byte) (patchedObjectBytes []byte, err error) {
+// detectMutationConflicts return the paths patched by previous policies that are replaced or removed by a policy.
+// Replacing or removing an ancestor of a patched path overwrites it too, while adding siblings or children keeps it.
+// Writers of the paths are updated with the policy afterward
+func detectMutationConflicts(pathWriters map[string]string, resourceType, policyName string,
+	jsonPatchOperations jsondiff.Patch) (conflicts []mutationConflictsRegistry.ConflictKeyT) {
 
-	var tmpPatchBytes []byte
-	tmpPatchBytes, err = yaml.YAMLToJSON(patch)
-	if err != nil {
-		return nil, err
+	detectedConflicts := map[mutationConflictsRegistry.ConflictKeyT]struct{}{}
+	for _, operation := range jsonPatchOperations {
+		if operation.Type != jsondiff.OperationReplace && operation.Type != jsondiff.OperationRemove {
+			continue
+		}
+
+		for writtenPath, writer := range pathWriters {
+			if writer == policyName || !pathContains(operation.Path, writtenPath) {
+				continue
+			}
+
+			conflict := mutationConflictsRegistry.ConflictKeyT{
+				Resource:          resourceType,
+				Path:              writtenPath,
+				OverwrittenPolicy: writer,
+				Policy:            policyName,
+			}
+			if _, alreadyDetected := detectedConflicts[conflict]; alreadyDetected {
+				continue
+			}
+			detectedConflicts[conflict] = struct{}{}
+			conflicts = append(conflicts, conflict)
+		}
 	}
 
-	patchedObjectBytes, err = jsonpatch.MergePatch(objectToPatch, tmpPatchBytes)
-	if err != nil {
-		return nil, err
+	for _, operation := range jsonPatchOperations {
+		// Paths under a replaced or removed one are not the ones patched by their writers anymore
+		if operation.Type == jsondiff.OperationReplace || operation.Type == jsondiff.OperationRemove {
+			for writtenPath := range pathWriters {
+				if pathContains(operation.Path, writtenPath) {
+					delete(pathWriters, writtenPath)
+				}
+			}
+		}
+
+		// Items added or removed in the middle of an array shift the following ones,
+		// so their paths do not point to the items patched by their writers anymore
+		if operation.Type == jsondiff.OperationAdd || operation.Type == jsondiff.OperationRemove {
+			deleteShiftedArrayItems(pathWriters, operation.Path)
+		}
+		pathWriters[operation.Path] = policyName
 	}
 
-	return patchedObjectBytes, nil
+	return conflicts
 }
 
-// generateStrategicMergePatch patches the object using StrategicMerge strategy and returns the resulting object
-// Patch must be expressed in YAML
-// TODO: Could we use official Kubernetes package for this?
-// Ref: https://github.com/kubernetes/kubernetes/blob/bd715a38d32561b45742b2d0cf0762b024107a31/cmd/kubeadm/app/util/patches/patches.go#L211-L216
-// Ref: SMP using OpenAPI schemas like this project: https://github.com/kubernetes/kubernetes/blob/bd715a38d32561b45742b2d0cf0762b024107a31/staging/src/k8s.io/apimachinery/pkg/util/strategicpatch/patch.go#L821
-func (s *HttpServer) generateStrategicMergePatch(objectToPatch []byte, patch []byte) (patchedObjectBytes []byte, err error) {
+// deleteShiftedArrayItems deletes the written paths under the items of an array that are shifted
+// when an item is added or removed at the given path. Items appended with '-' shift nothing.
+// Maps with numeric keys can not be told apart from arrays, so their paths are deleted too
+func deleteShiftedArrayItems(pathWriters map[string]string, itemPath string) {
+	lastSeparator := strings.LastIndex(itemPath, "/")
+	if lastSeparator == -1 {
+		return
+	}
 
-	var tmpPatchBytes []byte
-	tmpPatchBytes, err = yaml.YAMLToJSON(patch)
-	if err != nil {
-		return nil, err
+	arrayPath := itemPath[:lastSeparator]
+	index, isArrayIndex := parseArrayIndex(itemPath[lastSeparator+1:])
+	if !isArrayIndex {
+		return
 	}
 
-	//
-	tmpPatchObject := map[string]any{}
-	err = json.Unmarshal(tmpPatchBytes, &tmpPatchObject)
-	if err != nil {
-		return nil, err
+	for writtenPath := range pathWriters {
+		relativePath, isUnderArray := strings.CutPrefix(writtenPath, arrayPath+"/")
+		if !isUnderArray {
+			continue
+		}
+
+		writtenSegment, _, _ := strings.Cut(relativePath, "/")
+		writtenIndex, isWrittenIndex := parseArrayIndex(writtenSegment)
+		if isWrittenIndex && writtenIndex >= index {
+			delete(pathWriters, writtenPath)
+		}
 	}
+}
 
-	tmpOriginalObject := map[string]any{}
-	err = json.Unmarshal(objectToPatch, &tmpOriginalObject)
-	if err != nil {
-		return nil, err
+// parseArrayIndex return the index represented by a segment of a JSON pointer, when it's an array index
+func parseArrayIndex(segment string) (int, bool) {
+	if segment == "" || strings.Trim(segment, "0123456789") != "" {
+		return 0, false
 	}
 
-	// Take original GVK as default. Overwrite it when the patch specifies another.
-	desiredGvk, err := globals.GetObjectGVK(&tmpOriginalObject)
+	index, err := strconv.Atoi(segment)
+	return index, err == nil
+}
+
+// pathContains checks whether a JSON pointer is the same as another one, or one of its ancestors
+func pathContains(ancestorPath, path string) bool {
+	return ancestorPath == path || strings.HasPrefix(path, ancestorPath+"/")
+}
+
+// checkMutationIdempotency evaluates a policy again over its own output, returning the changes done by this new pass.
+// Idempotent policies produce no changes
+func (s *HttpServer) checkMutationIdempotency(policy *v1alpha1.ClusterMutationPolicy,
+	injectedData template.PolicyEvaluationDataT, patched []byte) (jsondiff.Patch, error) {
+
+	// Injected data is a copy, so the object and the variables can be replaced safely
+	injectedData.Vars = make(map[string]any)
+	err := s.updatePolicyDataObject(&injectedData, patched)
 	if err != nil {
 		return nil, err
 	}
 
-	patchGVK, err := globals.GetObjectGVK(&tmpPatchObject)
-	if err == nil {
-		desiredGvk = patchGVK
+	evaluationResult := s.dependencies.Evaluator.EvaluateMutation(policy, &injectedData, patched)
+	if len(evaluationResult.Errors) > 0 {
+		return nil, errors.Join(evaluationResult.Errors...)
 	}
 
-	//
-	initialSchema := s.strategicMergePatcher.GetSchemaByGVK(schema.GroupVersionKind{
-		Group:   desiredGvk.Group,
-		Version: desiredGvk.Version,
-		Kind:    desiredGvk.Kind,
-	})
-
-	patchedObject := map[string]any{}
-	patchedObject, err = s.strategicMergePatcher.StrategicMerge(tmpOriginalObject, tmpPatchObject, initialSchema)
-	if err != nil {
-		return nil, err
+	return evaluationResult.JsonPatchOperations, nil
+}
+
+// validateMutationResult validates the object patched by a policy using the mode defined in the policy,
+// or the global one when the policy does not define it
+func (s *HttpServer) validateMutationResult(policy *v1alpha1.ClusterMutationPolicy,
+	req *admissionv1.AdmissionRequest, patched []byte) error {
+
+	validateResult := s.options.MutationValidateResult
+	if policy.Spec.ValidateResult != "" {
+		validateResult = policy.Spec.ValidateResult
 	}
 
-	patchedObjectBytes, err = json.Marshal(patchedObject)
-	if err != nil {
-		return nil, err
+	switch strings.ToLower(validateResult) {
+	case v1alpha1.MutationValidateResultDryRun:
+		// Dry-run requests go through the webhooks too, so they are not sent again to avoid endless loops
+		if req.DryRun != nil && *req.DryRun {
+			return nil
+		}
+		return dryRunPatchedObject(req, patched)
+
+	case v1alpha1.MutationValidateResultOpenapi:
+		return s.dependencies.Evaluator.ValidatePatchedObject(patched)
 	}
 
-	return patchedObjectBytes, nil
+	return nil
 }
 
 // dryRunPatchedObject use a dynamic client for validating the patched object through dry-run
-// TODO: Implement this DRY-RUN as a test before sending the patch to Kubernetes
 func dryRunPatchedObject(req *admissionv1.AdmissionRequest, patched []byte) error {
 	var err error
 
@@ -0,0 +1,871 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admission
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	//
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/wI2L/jsondiff"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/utils/ptr"
+
+	//
+	"github.com/freepik-company/admitik/api/v1alpha1"
+	"github.com/freepik-company/admitik/internal/common"
+	"github.com/freepik-company/admitik/internal/controller"
+	"github.com/freepik-company/admitik/internal/evaluator"
+	"github.com/freepik-company/admitik/internal/globals"
+	"github.com/freepik-company/admitik/internal/metrics"
+	policyStore "github.com/freepik-company/admitik/internal/registry/policystore"
+	sourcesRegistry "github.com/freepik-company/admitik/internal/registry/sources"
+	"github.com/freepik-company/admitik/internal/strategicmerge"
+	"github.com/freepik-company/admitik/internal/template"
+)
+
+// startTestDryRunServer starts a fake Kubernetes API accepting dry-run creations of pods, except those named 'invalid'.
+// The number of requests received is stored in the given counter
+func startTestDryRunServer(t *testing.T, requests *int) {
+	server := httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		*requests++
+
+		if request.URL.Query().Get("dryRun") != metav1.DryRunAll {
+			t.Errorf("expected a dry-run request, got: %s", request.URL.String())
+		}
+
+		body, _ := io.ReadAll(request.Body)
+		response.Header().Set("Content-Type", "application/json")
+
+		if strings.Contains(string(body), `"name":"invalid"`) {
+			response.WriteHeader(http.StatusUnprocessableEntity)
+			_ = json.NewEncoder(response).Encode(metav1.Status{
+				TypeMeta: metav1.TypeMeta{Kind: "Status", APIVersion: "v1"},
+				Status:   metav1.StatusFailure,
+				Reason:   metav1.StatusReasonInvalid,
+				Message:  "Pod \"invalid\" is invalid",
+				Code:     http.StatusUnprocessableEntity,
+			})
+			return
+		}
+
+		response.WriteHeader(http.StatusCreated)
+		_, _ = response.Write(body)
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := dynamic.NewForConfig(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatalf("failed creating dynamic client: %s", err.Error())
+	}
+
+	previousClient := globals.Application.KubeRawClient
+	globals.Application.KubeRawClient = client
+	t.Cleanup(func() { globals.Application.KubeRawClient = previousClient })
+}
+
+func TestValidateMutationResult(t *testing.T) {
+	tests := []struct {
+		name string
+
+		// defaultValidateResult is the value of the flag, and validateResult the one of the policy
+		defaultValidateResult string
+		validateResult        string
+
+		operation admissionv1.Operation
+		dryRun    bool
+		patched   string
+
+		// expectedRequests is the number of dry-run requests expected to reach Kubernetes
+		expectedRequests int
+
+		// expectedErr is a part of the expected error message. Empty when no error is expected
+		expectedErr string
+	}{
+		{
+			name:      "nothing is validated by default",
+			operation: admissionv1.Create,
+			patched:   `{"apiVersion":"v1","kind":"Pod","metadata":{"name":"invalid"}}`,
+		},
+		{
+			name:                  "policy value overrides the default one",
+			defaultValidateResult: v1alpha1.MutationValidateResultDryRun,
+			validateResult:        v1alpha1.MutationValidateResultNone,
+			operation:             admissionv1.Create,
+			patched:               `{"apiVersion":"v1","kind":"Pod","metadata":{"name":"invalid"}}`,
+		},
+		{
+			name:             "valid object passes the dry-run",
+			validateResult:   v1alpha1.MutationValidateResultDryRun,
+			operation:        admissionv1.Create,
+			patched:          `{"apiVersion":"v1","kind":"Pod","metadata":{"name":"valid","namespace":"default"}}`,
+			expectedRequests: 1,
+		},
+		{
+			name:             "invalid object fails the dry-run",
+			validateResult:   "DryRun",
+			operation:        admissionv1.Create,
+			patched:          `{"apiVersion":"v1","kind":"Pod","metadata":{"name":"invalid","namespace":"default"}}`,
+			expectedRequests: 1,
+			expectedErr:      "dry-run failed",
+		},
+		{
+			name:           "dry-run requests are not sent again",
+			validateResult: v1alpha1.MutationValidateResultDryRun,
+			operation:      admissionv1.Create,
+			dryRun:         true,
+			patched:        `{"apiVersion":"v1","kind":"Pod","metadata":{"name":"invalid","namespace":"default"}}`,
+		},
+		{
+			name:           "deletions are not sent to dry-run",
+			validateResult: v1alpha1.MutationValidateResultDryRun,
+			operation:      admissionv1.Delete,
+			patched:        `{"apiVersion":"v1","kind":"Pod","metadata":{"name":"invalid","namespace":"default"}}`,
+		},
+		{
+			name:           "objects without schema fail the openapi validation",
+			validateResult: v1alpha1.MutationValidateResultOpenapi,
+			operation:      admissionv1.Create,
+			patched:        `{"apiVersion":"example.com/v1","kind":"Unknown","metadata":{"name":"example"}}`,
+			expectedErr:    "schema not found",
+		},
+		{
+			name:           "broken objects fail the openapi validation",
+			validateResult: v1alpha1.MutationValidateResultOpenapi,
+			operation:      admissionv1.Create,
+			patched:        `{"apiVersion":`,
+			expectedErr:    "unexpected end of JSON input",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			requests := 0
+			startTestDryRunServer(t, &requests)
+
+			server := &HttpServer{
+				options: &AdmissionServerOptions{MutationValidateResult: test.defaultValidateResult},
+				dependencies: &AdmissionServerDependencies{
+					Evaluator: evaluator.NewEvaluator(evaluator.EvaluatorDependencies{
+						StrategicMergePatcher: &strategicmerge.StrategicMergePatcher{},
+					}),
+				},
+			}
+
+			policy := &v1alpha1.ClusterMutationPolicy{}
+			policy.Spec.ValidateResult = test.validateResult
+
+			req := &admissionv1.AdmissionRequest{
+				Operation: test.operation,
+				Namespace: "default",
+				Resource:  metav1.GroupVersionResource{Version: "v1", Resource: "pods"},
+				DryRun:    ptr.To(test.dryRun),
+			}
+
+			err := server.validateMutationResult(policy, req, []byte(test.patched))
+			if test.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.expectedErr) {
+					t.Errorf("expected error containing '%s', got: %v", test.expectedErr, err)
+				}
+			} else if err != nil {
+				t.Errorf("unexpected error: %s", err.Error())
+			}
+
+			if requests != test.expectedRequests {
+				t.Errorf("expected %d requests to Kubernetes, got %d", test.expectedRequests, requests)
+			}
+		})
+	}
+}
+
+func TestCheckMutationIdempotency(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch v1alpha1.PatchT
+
+		// expectedPaths are the paths of the changes done by the second pass. Empty for idempotent policies
+		expectedPaths []string
+
+		// expectedErr is a part of the expected error message. Empty when no error is expected
+		expectedErr string
+	}{
+		{
+			name:  "merging a label is idempotent",
+			patch: v1alpha1.PatchT{Type: "jsonmerge", Engine: "plain", Template: `{"metadata":{"labels":{"patched":"true"}}}`},
+		},
+		{
+			name:          "appending a container is not idempotent",
+			patch:         v1alpha1.PatchT{Type: "jsonpatch", Engine: "plain", Template: `[{"op":"add","path":"/spec/containers/-","value":{"name":"sidecar"}}]`},
+			expectedPaths: []string{"/spec/containers/-"},
+		},
+		{
+			name: "patches computed from the object are not idempotent when they grow it",
+			patch: v1alpha1.PatchT{Type: "jsonmerge", Engine: "gotmpl",
+				Template: `{"metadata":{"annotations":{"count":"{{ len .object.metadata.annotations }}"}}}`},
+			expectedPaths: []string{"/metadata/annotations/count"},
+		},
+		{
+			name:        "patches failing over their own output are reported",
+			patch:       v1alpha1.PatchT{Type: "jsonpatch", Engine: "plain", Template: `[{"op":"remove","path":"/metadata/labels/team"}]`},
+			expectedErr: "failed generating",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			object := `{"apiVersion":"v1","kind":"Pod","metadata":{"name":"example","labels":{"team":"a"},"annotations":{}},` +
+				`"spec":{"containers":[{"name":"app"}]}}`
+
+			injectedData := template.PolicyEvaluationDataT{}
+			injectedData.Initialize()
+			injectedData.Operation = common.NormalizedOperationCreate
+			if err := json.Unmarshal([]byte(object), &injectedData.Object); err != nil {
+				t.Fatalf("failed decoding test object: %s", err.Error())
+			}
+
+			policy := &v1alpha1.ClusterMutationPolicy{}
+			policy.Spec.Patch = test.patch
+
+			server := &HttpServer{
+				dependencies: &AdmissionServerDependencies{
+					Evaluator: evaluator.NewEvaluator(evaluator.EvaluatorDependencies{}),
+				},
+			}
+
+			// First pass, as the handler does
+			firstResult := server.dependencies.Evaluator.EvaluateMutation(policy, &injectedData, []byte(object))
+			if firstResult.AbortReason != "" {
+				t.Fatalf("unexpected abort on the first pass: %v", firstResult.Errors)
+			}
+
+			changes, err := server.checkMutationIdempotency(policy, injectedData, firstResult.PatchedObject)
+			if test.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.expectedErr) {
+					t.Fatalf("expected error containing '%s', got: %v", test.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+
+			var paths []string
+			for _, operation := range changes {
+				paths = append(paths, operation.Path)
+			}
+			if strings.Join(paths, ",") != strings.Join(test.expectedPaths, ",") {
+				t.Errorf("expected changes on %v, got %v", test.expectedPaths, paths)
+			}
+
+			// The data of the request is not modified by the check
+			if _, found := injectedData.Object["metadata"].(map[string]any)["labels"].(map[string]any)["patched"]; found {
+				t.Errorf("expected injected data of the request to be untouched")
+			}
+		})
+	}
+}
+
+func TestPathContains(t *testing.T) {
+	tests := []struct {
+		name         string
+		ancestorPath string
+		path         string
+		expected     bool
+	}{
+		{name: "same path", ancestorPath: "/metadata/labels", path: "/metadata/labels", expected: true},
+		{name: "ancestor path", ancestorPath: "/metadata", path: "/metadata/labels/team", expected: true},
+		{name: "descendant path", ancestorPath: "/metadata/labels/team", path: "/metadata/labels", expected: false},
+		{name: "sibling path", ancestorPath: "/metadata/labels", path: "/metadata/annotations", expected: false},
+		{name: "path sharing a prefix", ancestorPath: "/metadata/label", path: "/metadata/labels", expected: false},
+		{name: "root path", ancestorPath: "", path: "/spec", expected: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if result := pathContains(test.ancestorPath, test.path); result != test.expected {
+				t.Errorf("expected %t, got %t", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestDetectMutationConflicts(t *testing.T) {
+
+	// operation is a short way to define the operations of each policy in the table
+	type operation struct {
+		Type string
+		Path string
+	}
+
+	tests := []struct {
+		name string
+
+		// policies represents the operations done by each policy, in evaluation order
+		policies map[string][]operation
+		order    []string
+
+		// expectedConflicts represents the detected conflicts, as '{policy}>{overwrittenPolicy}:{path}'
+		expectedConflicts []string
+	}{
+		{
+			name: "different paths do not conflict",
+			policies: map[string][]operation{
+				"a": {{Type: "add", Path: "/metadata/labels/team"}},
+				"b": {{Type: "add", Path: "/metadata/labels/owner"}},
+			},
+			order: []string{"a", "b"},
+		},
+		{
+			name: "replacing the same path conflicts",
+			policies: map[string][]operation{
+				"a": {{Type: "add", Path: "/metadata/labels/team"}},
+				"b": {{Type: "replace", Path: "/metadata/labels/team"}},
+			},
+			order:             []string{"a", "b"},
+			expectedConflicts: []string{"b>a:/metadata/labels/team"},
+		},
+		{
+			name: "removing the same path conflicts",
+			policies: map[string][]operation{
+				"a": {{Type: "add", Path: "/metadata/labels/team"}},
+				"b": {{Type: "remove", Path: "/metadata/labels/team"}},
+			},
+			order:             []string{"a", "b"},
+			expectedConflicts: []string{"b>a:/metadata/labels/team"},
+		},
+		{
+			name: "replacing an ancestor conflicts",
+			policies: map[string][]operation{
+				"a": {{Type: "add", Path: "/metadata/labels/team"}},
+				"b": {{Type: "replace", Path: "/metadata/labels"}},
+			},
+			order:             []string{"a", "b"},
+			expectedConflicts: []string{"b>a:/metadata/labels/team"},
+		},
+		{
+			name: "adding a key under a patched map does not conflict",
+			policies: map[string][]operation{
+				"a": {{Type: "add", Path: "/metadata/annotations"}},
+				"b": {{Type: "add", Path: "/metadata/annotations/foo"}},
+			},
+			order: []string{"a", "b"},
+		},
+		{
+			name: "replacing a key under a patched map does not conflict",
+			policies: map[string][]operation{
+				"a": {{Type: "add", Path: "/metadata/annotations"}},
+				"b": {{Type: "replace", Path: "/metadata/annotations/foo"}},
+			},
+			order: []string{"a", "b"},
+		},
+		{
+			name: "appending to a patched list does not conflict",
+			policies: map[string][]operation{
+				"a": {{Type: "add", Path: "/spec/containers/-"}},
+				"b": {{Type: "add", Path: "/spec/containers/-"}},
+			},
+			order: []string{"a", "b"},
+		},
+		{
+			name: "paths patched by the same policy do not conflict",
+			policies: map[string][]operation{
+				"a": {{Type: "add", Path: "/metadata/labels/team"}, {Type: "replace", Path: "/metadata/labels"}},
+			},
+			order: []string{"a"},
+		},
+		{
+			name: "inserting an item shifts the paths patched under the following ones",
+			policies: map[string][]operation{
+				"a": {{Type: "replace", Path: "/spec/containers/0/image"}, {Type: "add", Path: "/spec/containers/1/env"}},
+				"b": {{Type: "add", Path: "/spec/containers/0"}},
+				"c": {{Type: "replace", Path: "/spec/containers/0/image"}, {Type: "remove", Path: "/spec/containers/1/env"}},
+			},
+			order: []string{"a", "b", "c"},
+		},
+		{
+			name: "removing an item shifts the paths patched under the following ones",
+			policies: map[string][]operation{
+				"a": {{Type: "replace", Path: "/spec/containers/1/image"}},
+				"b": {{Type: "remove", Path: "/spec/containers/0"}},
+				"c": {{Type: "replace", Path: "/spec/containers/1/image"}},
+			},
+			order: []string{"a", "b", "c"},
+		},
+		{
+			name: "items before the inserted one keep their writers",
+			policies: map[string][]operation{
+				"a": {{Type: "replace", Path: "/spec/containers/0/image"}},
+				"b": {{Type: "add", Path: "/spec/containers/1"}},
+				"c": {{Type: "replace", Path: "/spec/containers/0/image"}, {Type: "replace", Path: "/spec/containers/1"}},
+			},
+			order:             []string{"a", "b", "c"},
+			expectedConflicts: []string{"c>a:/spec/containers/0/image", "c>b:/spec/containers/1"},
+		},
+		{
+			name: "appending an item shifts nothing",
+			policies: map[string][]operation{
+				"a": {{Type: "replace", Path: "/spec/containers/0/image"}},
+				"b": {{Type: "add", Path: "/spec/containers/-"}},
+				"c": {{Type: "replace", Path: "/spec/containers/0/image"}},
+			},
+			order:             []string{"a", "b", "c"},
+			expectedConflicts: []string{"c>a:/spec/containers/0/image"},
+		},
+		{
+			name: "overwritten paths are not attributed to their previous writers anymore",
+			policies: map[string][]operation{
+				"a": {{Type: "add", Path: "/metadata/labels/team"}},
+				"b": {{Type: "replace", Path: "/metadata/labels"}},
+				"c": {{Type: "remove", Path: "/metadata/labels/team"}},
+				"d": {{Type: "remove", Path: "/metadata/labels"}},
+			},
+			order:             []string{"a", "b", "c", "d"},
+			expectedConflicts: []string{"b>a:/metadata/labels/team", "d>b:/metadata/labels", "d>c:/metadata/labels/team"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pathWriters := map[string]string{}
+
+			var conflicts []string
+			for _, policyName := range test.order {
+				var jsonPatchOperations jsondiff.Patch
+				for _, op := range test.policies[policyName] {
+					jsonPatchOperations = append(jsonPatchOperations, jsondiff.Operation{Type: op.Type, Path: op.Path})
+				}
+
+				for _, conflict := range detectMutationConflicts(pathWriters, "/v1/pods", policyName, jsonPatchOperations) {
+					if conflict.Resource != "/v1/pods" {
+						t.Errorf("unexpected resource on conflict: %s", conflict.Resource)
+					}
+					conflicts = append(conflicts,
+						fmt.Sprintf("%s>%s:%s", conflict.Policy, conflict.OverwrittenPolicy, conflict.Path))
+				}
+			}
+
+			// Writers are stored in a map, so conflicts of an operation are not ordered
+			slices.Sort(conflicts)
+			if strings.Join(conflicts, ",") != strings.Join(test.expectedConflicts, ",") {
+				t.Errorf("expected conflicts %v, got %v", test.expectedConflicts, conflicts)
+			}
+		})
+	}
+}
+
+func TestGetMutatedByAnnotationOperations(t *testing.T) {
+	tests := []struct {
+		name             string
+		annotations      map[string]string
+		mutatingPolicies []string
+
+		// expectedOperations represents the expected operations, as '{type} {path}'
+		expectedOperations []string
+	}{
+		{
+			name:               "policies are recorded on objects without annotations",
+			mutatingPolicies:   []string{"add-sidecar:2", "add-labels:1"},
+			expectedOperations: []string{"add /metadata/annotations"},
+		},
+		{
+			name:               "policies are recorded next to other annotations",
+			annotations:        map[string]string{"team": "a"},
+			mutatingPolicies:   []string{"add-sidecar:2"},
+			expectedOperations: []string{"add /metadata/annotations/admitik.dev~1mutated-by"},
+		},
+		{
+			name:               "previous records are rewritten",
+			annotations:        map[string]string{controller.MutatedByAnnotation: "old-policy:1"},
+			mutatingPolicies:   []string{"add-sidecar:2"},
+			expectedOperations: []string{"replace /metadata/annotations/admitik.dev~1mutated-by"},
+		},
+		{
+			name:        "previous records are kept when no policy changed the object",
+			annotations: map[string]string{controller.MutatedByAnnotation: "old-policy:1", "team": "a"},
+		},
+		{
+			name:        "objects without records are untouched when no policy changed them",
+			annotations: map[string]string{"team": "a"},
+		},
+		{
+			name:             "identical records are not rewritten",
+			annotations:      map[string]string{controller.MutatedByAnnotation: "add-sidecar:2"},
+			mutatingPolicies: []string{"add-sidecar:2"},
+		},
+		{
+			name: "policies with long names are recorded",
+			mutatingPolicies: []string{
+				strings.Repeat("a", 200) + ":1",
+			},
+			expectedOperations: []string{"add /metadata/annotations"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			object := map[string]any{
+				"apiVersion": "v1",
+				"kind":       "Pod",
+				"metadata":   map[string]any{"name": "example"},
+			}
+			if test.annotations != nil {
+				object["metadata"].(map[string]any)["annotations"] = test.annotations
+			}
+
+			objectBytes, err := json.Marshal(object)
+			if err != nil {
+				t.Fatalf("failed encoding test object: %s", err.Error())
+			}
+
+			operations, err := getMutatedByAnnotationOperations(objectBytes, test.mutatingPolicies)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+
+			var summary []string
+			for _, operation := range operations {
+				summary = append(summary, fmt.Sprintf("%s %s", operation.Type, operation.Path))
+			}
+			if strings.Join(summary, ",") != strings.Join(test.expectedOperations, ",") {
+				t.Fatalf("expected operations %v, got %v", test.expectedOperations, summary)
+			}
+
+			// The recorded value keeps the policies in evaluation order
+			if len(test.expectedOperations) > 0 {
+				value := operations[0].Value
+				if annotations, isMap := value.(map[string]any); isMap {
+					value = annotations[controller.MutatedByAnnotation]
+				}
+				if value != strings.Join(test.mutatingPolicies, ",") {
+					t.Errorf("expected recorded value '%s', got '%v'", strings.Join(test.mutatingPolicies, ","), value)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleMutationRequestAuditMode(t *testing.T) {
+
+	// Events and dry-run requests are sent to a fake Kubernetes API, counting them
+	var events, dryRuns int
+	eventsServer := httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		if request.Method == http.MethodPost && strings.HasSuffix(request.URL.Path, "/events") {
+			events++
+		}
+		if request.URL.Query().Get("dryRun") != "" {
+			dryRuns++
+		}
+		response.Header().Set("Content-Type", "application/json")
+		response.WriteHeader(http.StatusCreated)
+		_, _ = response.Write([]byte(`{"apiVersion":"events.k8s.io/v1","kind":"Event"}`))
+	}))
+	t.Cleanup(eventsServer.Close)
+
+	previousCoreClient := globals.Application.KubeRawCoreClient
+	coreClient, err := kubernetes.NewForConfig(&rest.Config{Host: eventsServer.URL})
+	if err != nil {
+		t.Fatalf("failed creating core client: %s", err.Error())
+	}
+	globals.Application.KubeRawCoreClient = coreClient
+	t.Cleanup(func() { globals.Application.KubeRawCoreClient = previousCoreClient })
+
+	previousClient := globals.Application.KubeRawClient
+	client, err := dynamic.NewForConfig(&rest.Config{Host: eventsServer.URL})
+	if err != nil {
+		t.Fatalf("failed creating dynamic client: %s", err.Error())
+	}
+	globals.Application.KubeRawClient = client
+	t.Cleanup(func() { globals.Application.KubeRawClient = previousClient })
+
+	labelPatch := func(label string) v1alpha1.PatchT {
+		return v1alpha1.PatchT{Type: "jsonmerge", Engine: "plain",
+			Template: fmt.Sprintf(`{"metadata":{"labels":{"%s":"true"}}}`, label)}
+	}
+
+	tests := []struct {
+		name     string
+		policies []v1alpha1.ClusterMutationPolicy
+
+		expectedAllowed bool
+
+		// expectedOperations represents the operations returned to Kubernetes, as '{path}={value}'
+		expectedOperations []string
+
+		// expectedAudited represents the increase of the audited metric by policy and action
+		expectedAudited map[[2]string]float64
+		expectedEvents  int
+		expectedDryRuns int
+	}{
+		{
+			name: "patches of policies in audit mode are reported but not applied",
+			policies: []v1alpha1.ClusterMutationPolicy{
+				{ObjectMeta: metav1.ObjectMeta{Name: "audit-patch"},
+					Spec: v1alpha1.ClusterMutationPolicySpec{Mode: v1alpha1.MutationModeAudit, Patch: labelPatch("audited")}},
+			},
+			expectedAllowed: true,
+			expectedAudited: map[[2]string]float64{{"audit-patch", "patch"}: 1},
+			expectedEvents:  1,
+		},
+		{
+			name: "patches of policies in audit mode are not validated",
+			policies: []v1alpha1.ClusterMutationPolicy{
+				{ObjectMeta: metav1.ObjectMeta{Name: "audit-dryrun"},
+					Spec: v1alpha1.ClusterMutationPolicySpec{Mode: v1alpha1.MutationModeAudit,
+						ValidateResult: v1alpha1.MutationValidateResultDryRun, Patch: labelPatch("audited")}},
+			},
+			expectedAllowed: true,
+			expectedAudited: map[[2]string]float64{{"audit-dryrun", "patch"}: 1},
+			expectedEvents:  1,
+		},
+		{
+			name: "patches of policies in enforce mode are validated",
+			policies: []v1alpha1.ClusterMutationPolicy{
+				{ObjectMeta: metav1.ObjectMeta{Name: "enforce-dryrun"},
+					Spec: v1alpha1.ClusterMutationPolicySpec{
+						ValidateResult: v1alpha1.MutationValidateResultDryRun, Patch: labelPatch("enforced")}},
+			},
+			expectedAllowed:    true,
+			expectedOperations: []string{"/metadata/labels/enforced=true"},
+			expectedDryRuns:    1,
+		},
+		{
+			name: "following policies do not see the patches of policies in audit mode",
+			policies: []v1alpha1.ClusterMutationPolicy{
+				{ObjectMeta: metav1.ObjectMeta{Name: "audit-before-enforce"},
+					Spec: v1alpha1.ClusterMutationPolicySpec{Priority: 1, Mode: v1alpha1.MutationModeAudit, Patch: labelPatch("audited")}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "enforce-after-audit"},
+					Spec: v1alpha1.ClusterMutationPolicySpec{Priority: 2, Patch: v1alpha1.PatchT{Type: "jsonmerge", Engine: "gotmpl",
+						Template: `{"metadata":{"labels":{"seen-audited":"{{ if .object.metadata.labels.audited }}yes{{ else }}no{{ end }}"}}}`}}},
+			},
+			expectedAllowed:    true,
+			expectedOperations: []string{"/metadata/labels/seen-audited=no"},
+			expectedAudited:    map[[2]string]float64{{"audit-before-enforce", "patch"}: 1},
+			expectedEvents:     1,
+		},
+		{
+			name: "rejections of policies in audit mode are reported but the object is allowed",
+			policies: []v1alpha1.ClusterMutationPolicy{
+				{ObjectMeta: metav1.ObjectMeta{Name: "audit-reject"},
+					Spec: v1alpha1.ClusterMutationPolicySpec{Mode: v1alpha1.MutationModeAudit,
+						Program: &v1alpha1.ProgramT{Engine: "plain", Template: `{"allowed": false, "message": "forbidden"}`}}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "enforce-after-reject"},
+					Spec: v1alpha1.ClusterMutationPolicySpec{Priority: 1, Patch: labelPatch("enforced")}},
+			},
+			expectedAllowed:    true,
+			expectedOperations: []string{"/metadata/labels/enforced=true"},
+			expectedAudited:    map[[2]string]float64{{"audit-reject", "reject"}: 1},
+			expectedEvents:     1,
+		},
+		{
+			name: "rejections of policies in enforce mode are not audited",
+			policies: []v1alpha1.ClusterMutationPolicy{
+				{ObjectMeta: metav1.ObjectMeta{Name: "enforce-reject"},
+					Spec: v1alpha1.ClusterMutationPolicySpec{
+						Program: &v1alpha1.ProgramT{Engine: "plain", Template: `{"allowed": false, "message": "forbidden"}`}}},
+			},
+			expectedAllowed: false,
+			expectedAudited: map[[2]string]float64{{"enforce-reject", "reject"}: 0},
+			expectedEvents:  1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			events = 0
+			dryRuns = 0
+
+			policyRegistry := policyStore.NewPolicyStore[*v1alpha1.ClusterMutationPolicy]()
+			for i := range test.policies {
+				policyRegistry.AddOrUpdateResource("/v1/pods/CREATE", &test.policies[i])
+			}
+
+			server := &HttpServer{
+				options: &AdmissionServerOptions{},
+				dependencies: &AdmissionServerDependencies{
+					ClusterMutationPolicyRegistry: policyRegistry,
+					SourcesRegistry:               sourcesRegistry.NewSourcesRegistry(),
+					Evaluator:                     evaluator.NewEvaluator(evaluator.EvaluatorDependencies{}),
+				},
+			}
+
+			auditedBefore := map[[2]string]float64{}
+			for key := range test.expectedAudited {
+				auditedBefore[key] = testutil.ToFloat64(metrics.MutationAuditedTotal.WithLabelValues(key[0], key[1]))
+			}
+
+			review := admissionv1.AdmissionReview{
+				Request: &admissionv1.AdmissionRequest{
+					UID:       "test",
+					Resource:  metav1.GroupVersionResource{Version: "v1", Resource: "pods"},
+					Operation: admissionv1.Create,
+					Object: runtime.RawExtension{
+						Raw: []byte(`{"apiVersion":"v1","kind":"Pod","metadata":{"name":"example","namespace":"default","labels":{"team":"a"}}}`),
+					},
+				},
+			}
+			reviewBytes, err := json.Marshal(review)
+			if err != nil {
+				t.Fatalf("failed encoding admission review: %s", err.Error())
+			}
+
+			recorder := httptest.NewRecorder()
+			server.handleMutationRequest(recorder, httptest.NewRequest(http.MethodPost, "/mutate", strings.NewReader(string(reviewBytes))))
+
+			reviewResponse := admissionv1.AdmissionReview{}
+			if err := json.Unmarshal(recorder.Body.Bytes(), &reviewResponse); err != nil {
+				t.Fatalf("failed decoding admission response: %s", err.Error())
+			}
+
+			if reviewResponse.Response.Allowed != test.expectedAllowed {
+				t.Errorf("expected allowed %t, got %t", test.expectedAllowed, reviewResponse.Response.Allowed)
+			}
+
+			var operations []string
+			if len(reviewResponse.Response.Patch) > 0 {
+				var jsonPatchOperations jsondiff.Patch
+				if err := json.Unmarshal(reviewResponse.Response.Patch, &jsonPatchOperations); err != nil {
+					t.Fatalf("failed decoding patch: %s", err.Error())
+				}
+				for _, operation := range jsonPatchOperations {
+					operations = append(operations, fmt.Sprintf("%s=%v", operation.Path, operation.Value))
+				}
+			}
+			if strings.Join(operations, ",") != strings.Join(test.expectedOperations, ",") {
+				t.Errorf("expected operations %v, got %v", test.expectedOperations, operations)
+			}
+
+			for key, expectedIncrease := range test.expectedAudited {
+				increase := testutil.ToFloat64(metrics.MutationAuditedTotal.WithLabelValues(key[0], key[1])) - auditedBefore[key]
+				if increase != expectedIncrease {
+					t.Errorf("expected audited metric of %v to increase by %v, got %v", key, expectedIncrease, increase)
+				}
+			}
+
+			if events != test.expectedEvents {
+				t.Errorf("expected %d events, got %d", test.expectedEvents, events)
+			}
+
+			if dryRuns != test.expectedDryRuns {
+				t.Errorf("expected %d dry-run requests, got %d", test.expectedDryRuns, dryRuns)
+			}
+		})
+	}
+}
+
+func TestHandleMutationRequestRecordAnnotation(t *testing.T) {
+	labelPatch := func(label string) v1alpha1.PatchT {
+		return v1alpha1.PatchT{Type: "jsonmerge", Engine: "plain",
+			Template: fmt.Sprintf(`{"metadata":{"labels":{"%s":"a"}}}`, label)}
+	}
+
+	tests := []struct {
+		name     string
+		policies []v1alpha1.ClusterMutationPolicy
+
+		// expectedOperations represents the operations returned to Kubernetes, as '{type} {path}'
+		expectedOperations []string
+	}{
+		{
+			name: "records are kept when no policy changed the object",
+			policies: []v1alpha1.ClusterMutationPolicy{
+				{ObjectMeta: metav1.ObjectMeta{Name: "noop", Generation: 1},
+					Spec: v1alpha1.ClusterMutationPolicySpec{Patch: labelPatch("team")}},
+			},
+		},
+		{
+			name: "records are replaced when some policy changed the object",
+			policies: []v1alpha1.ClusterMutationPolicy{
+				{ObjectMeta: metav1.ObjectMeta{Name: "noop", Generation: 1},
+					Spec: v1alpha1.ClusterMutationPolicySpec{Patch: labelPatch("team")}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "add-owner", Generation: 2},
+					Spec: v1alpha1.ClusterMutationPolicySpec{Priority: 1, Patch: labelPatch("owner")}},
+			},
+			expectedOperations: []string{
+				"add /metadata/labels/owner",
+				"replace /metadata/annotations/admitik.dev~1mutated-by",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			policyRegistry := policyStore.NewPolicyStore[*v1alpha1.ClusterMutationPolicy]()
+			for i := range test.policies {
+				policyRegistry.AddOrUpdateResource("/v1/pods/UPDATE", &test.policies[i])
+			}
+
+			server := &HttpServer{
+				options: &AdmissionServerOptions{MutationRecordAnnotation: true},
+				dependencies: &AdmissionServerDependencies{
+					ClusterMutationPolicyRegistry: policyRegistry,
+					SourcesRegistry:               sourcesRegistry.NewSourcesRegistry(),
+					Evaluator:                     evaluator.NewEvaluator(evaluator.EvaluatorDependencies{}),
+				},
+			}
+
+			object := `{"apiVersion":"v1","kind":"Pod","metadata":{"name":"example","namespace":"default",` +
+				`"labels":{"team":"a"},"annotations":{"admitik.dev/mutated-by":"previous:1"}}}`
+			review := admissionv1.AdmissionReview{
+				Request: &admissionv1.AdmissionRequest{
+					UID:       "test",
+					Resource:  metav1.GroupVersionResource{Version: "v1", Resource: "pods"},
+					Operation: admissionv1.Update,
+					Object:    runtime.RawExtension{Raw: []byte(object)},
+					OldObject: runtime.RawExtension{Raw: []byte(object)},
+				},
+			}
+			reviewBytes, err := json.Marshal(review)
+			if err != nil {
+				t.Fatalf("failed encoding admission review: %s", err.Error())
+			}
+
+			recorder := httptest.NewRecorder()
+			server.handleMutationRequest(recorder, httptest.NewRequest(http.MethodPost, "/mutate", strings.NewReader(string(reviewBytes))))
+
+			reviewResponse := admissionv1.AdmissionReview{}
+			if err := json.Unmarshal(recorder.Body.Bytes(), &reviewResponse); err != nil {
+				t.Fatalf("failed decoding admission response: %s", err.Error())
+			}
+
+			var operations []string
+			if len(reviewResponse.Response.Patch) > 0 {
+				var jsonPatchOperations jsondiff.Patch
+				if err := json.Unmarshal(reviewResponse.Response.Patch, &jsonPatchOperations); err != nil {
+					t.Fatalf("failed decoding patch: %s", err.Error())
+				}
+				for _, operation := range jsonPatchOperations {
+					operations = append(operations, fmt.Sprintf("%s %s", operation.Type, operation.Path))
+				}
+			}
+			if strings.Join(operations, ",") != strings.Join(test.expectedOperations, ",") {
+				t.Errorf("expected operations %v, got %v", test.expectedOperations, operations)
+			}
+		})
+	}
+}
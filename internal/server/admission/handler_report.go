@@ -0,0 +1,152 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admission
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+
+	//
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	//
+	"github.com/freepik-company/admitik/api/v1alpha1"
+	mutationConflictsRegistry "github.com/freepik-company/admitik/internal/registry/mutationconflicts"
+)
+
+// mutationConflictsReportT represents the report of the mutation policies overlapping on each resource type
+type mutationConflictsReportT struct {
+	Resources []mutationConflictsResourceReportT `json:"resources"`
+}
+
+// mutationConflictsResourceReportT represents the mutation policies intercepting a resource type,
+// and the conflicts between them detected on admission
+type mutationConflictsResourceReportT struct {
+	// Resource represents the resource type, following the pattern {group}/{version}/{resource}
+	Resource string `json:"resource"`
+
+	// Policies represents the policies intercepting the resource type, in evaluation order
+	Policies  []string                              `json:"policies"`
+	Conflicts []mutationConflictsRegistry.ConflictT `json:"conflicts,omitempty"`
+}
+
+// NewMutationConflictsReportHandler return the handler serving the report of overlapping mutation policies.
+// It's served by the metrics server instead of the admission one, as the latter has no authentication
+// and is reachable by anyone able to reach the webhooks Service
+func NewMutationConflictsReportHandler(dependencies *AdmissionServerDependencies) http.Handler {
+	httpServer := &HttpServer{
+		dependencies: dependencies,
+	}
+
+	return http.HandlerFunc(httpServer.handleMutationConflictsReport)
+}
+
+// handleMutationConflictsReport return the resource types intercepted by more than one ClusterMutationPolicy,
+// or with conflicts detected between policies. Query parameter 'resource' filters them by resource type
+func (s *HttpServer) handleMutationConflictsReport(response http.ResponseWriter, request *http.Request) {
+	logger := log.FromContext(request.Context()).WithValues("controller", "admissionserver")
+
+	if request.Method != http.MethodGet {
+		response.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	report := s.getMutationConflictsReport(request.URL.Query().Get("resource"))
+
+	responseBytes, err := json.Marshal(report)
+	if err != nil {
+		logger.Info(fmt.Sprintf("failed converting mutation conflicts report into valid JSON: %s", err.Error()))
+		response.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	response.Header().Set("Content-Type", "application/json")
+	response.WriteHeader(http.StatusOK)
+
+	_, err = response.Write(responseBytes)
+	if err != nil {
+		logger.Info(fmt.Sprintf("failed writing response to the client: %s", err.Error()))
+	}
+}
+
+// getMutationConflictsReport builds the report of overlapping mutation policies from the policies in the registry
+// and the conflicts detected on admission
+func (s *HttpServer) getMutationConflictsReport(resourceFilter string) (report mutationConflictsReportT) {
+
+	// Group the policies by resource type, as the registry keeps them by resource type and operation
+	policiesByResource := map[string][]*v1alpha1.ClusterMutationPolicy{}
+	for _, resourcePattern := range s.dependencies.ClusterMutationPolicyRegistry.GetCollectionNames() {
+
+		resourcePatternParts := strings.Split(resourcePattern, "/")
+		if len(resourcePatternParts) != 4 {
+			continue
+		}
+
+		resourceType := strings.Join(resourcePatternParts[:3], "/")
+		if resourceFilter != "" && resourceType != resourceFilter {
+			continue
+		}
+
+		for _, policy := range s.dependencies.ClusterMutationPolicyRegistry.GetResources(resourcePattern) {
+			if !slices.ContainsFunc(policiesByResource[resourceType], func(p *v1alpha1.ClusterMutationPolicy) bool {
+				return p.Name == policy.Name
+			}) {
+				policiesByResource[resourceType] = append(policiesByResource[resourceType], policy)
+			}
+		}
+	}
+
+	conflictsByResource := map[string][]mutationConflictsRegistry.ConflictT{}
+	if s.dependencies.MutationConflictsRegistry != nil {
+		for _, conflict := range s.dependencies.MutationConflictsRegistry.GetConflicts(resourceFilter) {
+			conflictsByResource[conflict.Resource] = append(conflictsByResource[conflict.Resource], conflict)
+		}
+	}
+
+	//
+	report.Resources = []mutationConflictsResourceReportT{}
+	for resourceType, policies := range policiesByResource {
+		if len(policies) < 2 && len(conflictsByResource[resourceType]) == 0 {
+			continue
+		}
+
+		// Policies are evaluated by priority (ascending order)
+		sort.SliceStable(policies, func(i, j int) bool {
+			return policies[i].Spec.Priority < policies[j].Spec.Priority
+		})
+
+		resourceReport := mutationConflictsResourceReportT{
+			Resource:  resourceType,
+			Conflicts: conflictsByResource[resourceType],
+		}
+		for _, policy := range policies {
+			resourceReport.Policies = append(resourceReport.Policies, policy.Name)
+		}
+
+		report.Resources = append(report.Resources, resourceReport)
+	}
+
+	sort.Slice(report.Resources, func(i, j int) bool {
+		return report.Resources[i].Resource < report.Resources[j].Resource
+	})
+
+	return report
+}
@@ -31,6 +31,7 @@ import (
 	//
 	"github.com/freepik-company/admitik/api/v1alpha1"
 	"github.com/freepik-company/admitik/internal/common"
+	"github.com/freepik-company/admitik/internal/controller"
 	"github.com/freepik-company/admitik/internal/template"
 )
 
@@ -133,26 +134,24 @@ func (s *HttpServer) handleValidationRequest(response http.ResponseWriter, reque
 
 		specificTemplateInjectedObject := commonTemplateInjectedObject
 		specificTemplateInjectedObject.Sources = tmpFetchedPolicySources
+		specificTemplateInjectedObject.Vars = make(map[string]any)
 
-		// Evaluate template conditions
-		conditionsPassed, condErr := common.IsPassingConditions(caPolicyObj.Spec.Conditions, &specificTemplateInjectedObject)
-		if condErr != nil {
-			logger.Info(fmt.Sprintf("failed evaluating conditions: %s", condErr.Error()))
+		// Evaluate the policy: variables, conditions, program and message
+		evaluationResult := s.dependencies.Evaluator.EvaluateValidation(caPolicyObj, &specificTemplateInjectedObject)
+		for _, evaluationErr := range evaluationResult.Errors {
+			logger.Info(evaluationErr.Error())
 		}
 
+		reviewResponse.Response.Warnings = append(reviewResponse.Response.Warnings, evaluationResult.Warnings...)
+		s.accountPolicyEvaluation(controller.ClusterValidationPolicyResourceType, caPolicyObj.Name,
+			!evaluationResult.Allowed, len(evaluationResult.Errors) > 0)
+
 		// Conditions are met, skip rejection
-		if conditionsPassed {
+		if evaluationResult.Allowed {
 			continue
 		}
 
-		// When some condition is not met, evaluate message's template and emit a response
-		var parsedMessage string
-		parsedMessage, err = template.EvaluateTemplate(caPolicyObj.Spec.Message.Engine, caPolicyObj.Spec.Message.Template, &specificTemplateInjectedObject)
-		if err != nil {
-			logger.Info(fmt.Sprintf("failed parsing message template: %s", err.Error()))
-			parsedMessage = "Reason unavailable: message template failed. More info in controller logs."
-		}
-
+		parsedMessage := evaluationResult.Message
 		reviewResponse.Response.Result.Message = parsedMessage
 
 		// When the policy is in Permissive mode, allow it anyway
@@ -18,39 +18,28 @@ package admission
 
 import (
 	"net/http"
-
-	//
-	"github.com/freepik-company/admitik/internal/globals"
-	"github.com/freepik-company/admitik/internal/strategicmerge"
 )
 
 // HttpServer represents a simple HTTP server
 type HttpServer struct {
 	*http.Server
 
+	//
+	options *AdmissionServerOptions
+
 	// Injected dependencies
 	dependencies *AdmissionServerDependencies
-
-	// Carried-stuff
-	strategicMergePatcher *strategicmerge.StrategicMergePatcher
 }
 
 // NewHttpServer creates a new HttpServer
-func NewHttpServer(dependencies *AdmissionServerDependencies) (*HttpServer, error) {
+func NewHttpServer(options *AdmissionServerOptions, dependencies *AdmissionServerDependencies) (*HttpServer, error) {
 
-	var err error
 	httpServer := &HttpServer{}
 	httpServer.Server = &http.Server{}
 
+	httpServer.options = options
 	httpServer.dependencies = dependencies
 
-	httpServer.strategicMergePatcher, err = strategicmerge.NewStrategicMergePatcher(&strategicmerge.StrategicMergePatcherDependencies{
-		DiscoveryClient: globals.Application.KubeDiscoveryClient,
-	})
-	if err != nil {
-		return nil, err
-	}
-
 	return httpServer, nil
 }
 
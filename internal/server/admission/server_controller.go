@@ -29,6 +29,9 @@ import (
 
 	//
 	"github.com/freepik-company/admitik/api/v1alpha1"
+	"github.com/freepik-company/admitik/internal/evaluator"
+	mutationConflictsRegistry "github.com/freepik-company/admitik/internal/registry/mutationconflicts"
+	policyCountersRegistry "github.com/freepik-company/admitik/internal/registry/policycounters"
 	policyStore "github.com/freepik-company/admitik/internal/registry/policystore"
 	sourcesRegistry "github.com/freepik-company/admitik/internal/registry/sources"
 )
@@ -37,6 +40,10 @@ const (
 	AdmissionServerValidationPath = "/validate"
 	AdmissionServerMutationPath   = "/mutate"
 
+	// MutationConflictsReportPath represents the path of the metrics server to get the report
+	// of overlapping mutation policies
+	MutationConflictsReportPath = "/reports/mutation-conflicts"
+
 	//
 	controllerContextFinishedMessage = "Controller finished by context"
 )
@@ -49,6 +56,9 @@ type AdmissionServerDependencies struct {
 	ClusterValidationPolicyRegistry *policyStore.PolicyStore[*v1alpha1.ClusterValidationPolicy]
 	ClusterMutationPolicyRegistry   *policyStore.PolicyStore[*v1alpha1.ClusterMutationPolicy]
 	SourcesRegistry                 *sourcesRegistry.SourcesRegistry
+	PolicyCountersRegistry          *policyCountersRegistry.PolicyCountersRegistry
+	MutationConflictsRegistry       *mutationConflictsRegistry.MutationConflictsRegistry
+	Evaluator                       *evaluator.Evaluator
 }
 
 // AdmissionServerOptions represents available options that can be passed
@@ -62,6 +72,13 @@ type AdmissionServerOptions struct {
 	//
 	TLSCertificate string
 	TLSPrivateKey  string
+
+	// MutationValidateResult represents the default way to validate the objects patched by
+	// ClusterMutationPolicy objects. Policies can override it
+	MutationValidateResult string
+
+	// MutationRecordAnnotation enables recording the policies that changed an object in one of its annotations
+	MutationRecordAnnotation bool
 }
 
 // AdmissionServer represents the server that process coming events against
@@ -99,7 +116,7 @@ func (as *AdmissionServer) Start(ctx context.Context) (err error) {
 	logger := log.FromContext(ctx).WithValues("controller", "admissionserver")
 
 	logger.Info("Starting Server", "address", as.options.ServerAddr, "port", as.options.ServerPort)
-	customServer, err := NewHttpServer(&as.dependencies)
+	customServer, err := NewHttpServer(&as.options, &as.dependencies)
 	if err != nil {
 		return err
 	}
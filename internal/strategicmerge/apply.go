@@ -0,0 +1,173 @@
+package strategicmerge
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	//
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/managedfields"
+	"k8s.io/kube-openapi/pkg/spec3"
+	"sigs.k8s.io/structured-merge-diff/v4/fieldpath"
+	smdschema "sigs.k8s.io/structured-merge-diff/v4/schema"
+	"sigs.k8s.io/structured-merge-diff/v4/typed"
+	"sigs.k8s.io/structured-merge-diff/v4/value"
+)
+
+// newTypeConverterFromOpenapiDocument returns a TypeConverter for the GVKs declared in an OpenAPI v3 document
+// expressed in JSON. TypeConverters know how every field is merged on server-side apply
+func newTypeConverterFromOpenapiDocument(documentBytes []byte) (managedfields.TypeConverter, error) {
+	document := &spec3.OpenAPI{}
+	err := json.Unmarshal(documentBytes, document)
+	if err != nil {
+		return nil, err
+	}
+
+	if document.Components == nil || len(document.Components.Schemas) == 0 {
+		return nil, fmt.Errorf("document has no schemas")
+	}
+
+	return managedfields.NewTypeConverter(document.Components.Schemas, false)
+}
+
+// getTypeConverter returns the TypeConverter of a GroupVersion, building it from its OpenAPI v3 document
+// the first time it's requested. TypeConverters are discarded when the models of their GroupVersion are refreshed
+func (r *StrategicMergePatcher) getTypeConverter(groupVersion schema.GroupVersion) (managedfields.TypeConverter, error) {
+	r.mu.RLock()
+	typeConverter, cached := r.typeConvertersByGroupVersion[groupVersion]
+	openapiGroupVersion, published := r.openApiGroupVersions[groupVersion]
+	r.mu.RUnlock()
+
+	if cached {
+		return typeConverter, nil
+	}
+
+	if !published {
+		return nil, fmt.Errorf("OpenAPI schemas not found for '%s'", groupVersion.String())
+	}
+
+	documentBytes, err := openapiGroupVersion.Schema(runtime.ContentTypeJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed getting OpenAPI document from Kubernetes for '%s': %v", groupVersion.String(), err.Error())
+	}
+
+	typeConverter, err = newTypeConverterFromOpenapiDocument(documentBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed building type converter for '%s': %v", groupVersion.String(), err.Error())
+	}
+
+	// Store it only when the document was not refreshed meanwhile
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.openApiURLsByGroupVersion[groupVersion] == openapiGroupVersion.ServerRelativeURL() {
+		r.typeConvertersByGroupVersion[groupVersion] = typeConverter
+	}
+
+	return typeConverter, nil
+}
+
+// ApplyConfiguration merges an apply configuration into an object with server-side apply semantics,
+// as MutatingAdmissionPolicy does: lists are merged by their 'x-kubernetes-list-map-keys', sets are joined
+// and atomic fields are replaced. Field ownership is not tracked, as the patch is not applied by a manager.
+// The apply configuration is expected to have the same GVK as the object, so it's taken from it when missing
+func (r *StrategicMergePatcher) ApplyConfiguration(original, patch map[string]interface{}) (map[string]interface{}, error) {
+	originalObject := &unstructured.Unstructured{Object: original}
+	patchObject := &unstructured.Unstructured{Object: patch}
+
+	gvk := originalObject.GroupVersionKind()
+	if patchObject.GetAPIVersion() == "" && patchObject.GetKind() == "" {
+		patchObject.SetGroupVersionKind(gvk)
+	}
+	if patchObject.GroupVersionKind() != gvk {
+		return nil, fmt.Errorf("apply configuration (%s) and object (%s) are not of the same GVK",
+			patchObject.GroupVersionKind().String(), gvk.String())
+	}
+
+	typeConverter, err := r.getTypeConverter(gvk.GroupVersion())
+	if err != nil {
+		return nil, err
+	}
+
+	patchTyped, err := typeConverter.ObjectToTyped(patchObject)
+	if err != nil {
+		return nil, fmt.Errorf("failed converting apply configuration to typed object: %v", err.Error())
+	}
+
+	atomicPaths := getAtomicPaths(nil, patchTyped.Schema(), patchTyped.TypeRef(), patchTyped.AsValue())
+	if len(atomicPaths) > 0 {
+		return nil, fmt.Errorf("atomic lists, maps or structs can not be mutated: %s", strings.Join(atomicPaths, ", "))
+	}
+
+	// Objects stored in Kubernetes may have duplicated items in their lists, so they are accepted as they are
+	originalTyped, err := typeConverter.ObjectToTyped(originalObject, typed.AllowDuplicates)
+	if err != nil {
+		return nil, fmt.Errorf("failed converting object to typed object: %v", err.Error())
+	}
+
+	mergedTyped, err := originalTyped.Merge(patchTyped)
+	if err != nil {
+		return nil, fmt.Errorf("failed merging apply configuration: %v", err.Error())
+	}
+
+	mergedObject, err := typeConverter.TypedToObject(mergedTyped)
+	if err != nil {
+		return nil, fmt.Errorf("failed converting typed object to object: %v", err.Error())
+	}
+
+	return runtime.DefaultUnstructuredConverter.ToUnstructured(mergedObject)
+}
+
+// getAtomicPaths returns the paths of the atomic lists, maps and structs found in a value.
+// Apply configurations can not contain them, as every field not set in them would be removed,
+// which is not obvious to the person who wants to modify a single field inside
+func getAtomicPaths(path []fieldpath.PathElement, typeSchema *smdschema.Schema, typeRef smdschema.TypeRef, typeValue value.Value) (result []string) {
+	atom, found := typeSchema.Resolve(typeRef)
+	if !found {
+		return result
+	}
+
+	if typeValue.IsMap() && atom.Map != nil {
+		if atom.Map.ElementRelationship == smdschema.Atomic {
+			result = append(result, getPathString(path))
+		}
+
+		typeValue.AsMap().Iterate(func(key string, itemValue value.Value) bool {
+			itemTypeRef := atom.Map.ElementType
+			if field, isField := atom.Map.FindField(key); isField {
+				itemTypeRef = field.Type
+			}
+
+			itemPath := append(append([]fieldpath.PathElement{}, path...), fieldpath.PathElement{FieldName: &key})
+			result = append(result, getAtomicPaths(itemPath, typeSchema, itemTypeRef, itemValue)...)
+			return true
+		})
+	}
+
+	if typeValue.IsList() && atom.List != nil {
+		if atom.List.ElementRelationship == smdschema.Atomic {
+			result = append(result, getPathString(path))
+		}
+
+		list := typeValue.AsList()
+		for index := 0; index < list.Length(); index++ {
+			itemPath := append(append([]fieldpath.PathElement{}, path...), fieldpath.PathElement{Index: &index})
+			result = append(result, getAtomicPaths(itemPath, typeSchema, atom.List.ElementType, list.At(index))...)
+		}
+	}
+
+	return result
+}
+
+// getPathString returns a readable representation of a path
+// Example: [spec, containers, 0] -> '.spec.containers[0]'
+func getPathString(path []fieldpath.PathElement) string {
+	var builder strings.Builder
+	for _, element := range path {
+		builder.WriteString(element.String())
+	}
+	return builder.String()
+}
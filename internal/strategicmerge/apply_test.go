@@ -0,0 +1,329 @@
+package strategicmerge
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	//
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/managedfields"
+	"sigs.k8s.io/yaml"
+)
+
+// testApplyOpenapiDocument is a reduced OpenAPI v3 document with every kind of list and map
+// that server-side apply merges differently
+const testApplyOpenapiDocument = `
+openapi: 3.0.0
+info:
+  title: Kubernetes CRD Swagger
+  version: v0.1.0
+paths: {}
+components:
+  schemas:
+    dev.example.v1.Widget:
+      type: object
+      x-kubernetes-group-version-kind:
+        - group: example.dev
+          version: v1
+          kind: Widget
+      properties:
+        apiVersion:
+          type: string
+        kind:
+          type: string
+        metadata:
+          $ref: '#/components/schemas/io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta'
+        spec:
+          type: object
+          properties:
+            replicas:
+              type: integer
+            ports:
+              type: array
+              x-kubernetes-list-type: map
+              x-kubernetes-list-map-keys:
+                - port
+                - protocol
+              items:
+                type: object
+                properties:
+                  port:
+                    type: integer
+                  protocol:
+                    type: string
+                  name:
+                    type: string
+            tags:
+              type: array
+              x-kubernetes-list-type: set
+              items:
+                type: string
+            args:
+              type: array
+              x-kubernetes-list-type: atomic
+              items:
+                type: string
+            selector:
+              type: object
+              x-kubernetes-map-type: atomic
+              additionalProperties:
+                type: string
+    io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta:
+      type: object
+      properties:
+        name:
+          type: string
+        labels:
+          type: object
+          additionalProperties:
+            type: string
+`
+
+// getTestApplyPatcher return a StrategicMergePatcher whose TypeConverters are built from the test document
+func getTestApplyPatcher(t *testing.T) *StrategicMergePatcher {
+	t.Helper()
+
+	documentBytes, err := yaml.YAMLToJSON([]byte(testApplyOpenapiDocument))
+	if err != nil {
+		t.Fatalf("failed encoding OpenAPI document: %v", err)
+	}
+
+	typeConverter, err := newTypeConverterFromOpenapiDocument(documentBytes)
+	if err != nil {
+		t.Fatalf("failed building type converter: %v", err)
+	}
+
+	return &StrategicMergePatcher{
+		typeConvertersByGroupVersion: map[schema.GroupVersion]managedfields.TypeConverter{
+			{Group: "example.dev", Version: "v1"}: typeConverter,
+		},
+	}
+}
+
+func TestApplyConfiguration(t *testing.T) {
+	original := `
+apiVersion: example.dev/v1
+kind: Widget
+metadata:
+  name: example
+  labels:
+    team: platform
+spec:
+  replicas: 1
+  ports:
+    - port: 80
+      protocol: TCP
+      name: http
+    - port: 80
+      protocol: UDP
+      name: dns
+  tags:
+    - blue
+  args:
+    - --verbose
+  selector:
+    app: example
+`
+
+	tests := []struct {
+		name     string
+		original string
+		patch    string
+		expected string
+
+		// expectedErr is a part of the expected error message. Empty when no error is expected
+		expectedErr string
+	}{
+		{
+			name:     "maps are merged and scalars are replaced",
+			original: original,
+			patch: `
+metadata:
+  labels:
+    owner: someone
+spec:
+  replicas: 3
+`,
+			expected: `
+apiVersion: example.dev/v1
+kind: Widget
+metadata:
+  name: example
+  labels:
+    team: platform
+    owner: someone
+spec:
+  replicas: 3
+  ports:
+    - port: 80
+      protocol: TCP
+      name: http
+    - port: 80
+      protocol: UDP
+      name: dns
+  tags:
+    - blue
+  args:
+    - --verbose
+  selector:
+    app: example
+`,
+		},
+		{
+			name:     "lists of maps are merged by their keys",
+			original: original,
+			patch: `
+apiVersion: example.dev/v1
+kind: Widget
+spec:
+  ports:
+    - port: 80
+      protocol: UDP
+      name: quic
+    - port: 443
+      protocol: TCP
+      name: https
+`,
+			expected: `
+apiVersion: example.dev/v1
+kind: Widget
+metadata:
+  name: example
+  labels:
+    team: platform
+spec:
+  replicas: 1
+  ports:
+    - port: 80
+      protocol: TCP
+      name: http
+    - port: 80
+      protocol: UDP
+      name: quic
+    - port: 443
+      protocol: TCP
+      name: https
+  tags:
+    - blue
+  args:
+    - --verbose
+  selector:
+    app: example
+`,
+		},
+		{
+			name:     "sets are joined in the order of the apply configuration",
+			original: original,
+			patch: `
+spec:
+  tags:
+    - green
+    - blue
+`,
+			expected: `
+apiVersion: example.dev/v1
+kind: Widget
+metadata:
+  name: example
+  labels:
+    team: platform
+spec:
+  replicas: 1
+  ports:
+    - port: 80
+      protocol: TCP
+      name: http
+    - port: 80
+      protocol: UDP
+      name: dns
+  tags:
+    - green
+    - blue
+  args:
+    - --verbose
+  selector:
+    app: example
+`,
+		},
+		{
+			name:     "atomic lists can not be mutated",
+			original: original,
+			patch: `
+spec:
+  args:
+    - --quiet
+`,
+			expectedErr: "atomic lists, maps or structs can not be mutated: .spec.args",
+		},
+		{
+			name:     "atomic maps can not be mutated",
+			original: original,
+			patch: `
+spec:
+  selector:
+    tier: web
+`,
+			expectedErr: "atomic lists, maps or structs can not be mutated: .spec.selector",
+		},
+		{
+			name:     "unknown fields are rejected",
+			original: original,
+			patch: `
+spec:
+  color: blue
+`,
+			expectedErr: "failed converting apply configuration to typed object",
+		},
+		{
+			name:     "apply configuration of another kind",
+			original: original,
+			patch: `
+apiVersion: example.dev/v1
+kind: Gadget
+spec:
+  replicas: 3
+`,
+			expectedErr: "are not of the same GVK",
+		},
+		{
+			name: "object without schemas",
+			original: `
+apiVersion: other.dev/v1
+kind: Widget
+metadata:
+  name: example
+`,
+			patch: `
+metadata:
+  labels:
+    owner: someone
+`,
+			expectedErr: "OpenAPI schemas not found for 'other.dev/v1'",
+		},
+	}
+
+	patcher := getTestApplyPatcher(t)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := patcher.ApplyConfiguration(decodeTestYaml(t, test.original), decodeTestYaml(t, test.patch))
+
+			if test.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.expectedErr) {
+					t.Fatalf("expected error containing '%s', got: %v", test.expectedErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			expected := normalizeTestObject(t, decodeTestYaml(t, test.expected))
+			if !reflect.DeepEqual(normalizeTestObject(t, result), expected) {
+				t.Errorf("unexpected result:\ngot:  %v\nwant: %v", normalizeTestObject(t, result), expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,263 @@
+package strategicmerge
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const (
+	// Directive keys
+	// Ref: https://github.com/kubernetes/community/blob/master/contributors/devel/sig-api-machinery/strategic-merge-patch.md
+	directivePatch                         = "$patch"
+	directiveRetainKeys                    = "$retainKeys"
+	directiveSetElementOrderPrefix         = "$setElementOrder/"
+	directiveDeleteFromPrimitiveListPrefix = "$deleteFromPrimitiveList/"
+
+	// Values for '$patch' directive
+	patchDirectiveReplace = "replace"
+	patchDirectiveDelete  = "delete"
+	patchDirectiveMerge   = "merge"
+)
+
+// isDirectiveKey return whether a key of a patch is a directive instead of a field
+func isDirectiveKey(key string) bool {
+	return key == directivePatch ||
+		key == directiveRetainKeys ||
+		strings.HasPrefix(key, directiveSetElementOrderPrefix) ||
+		strings.HasPrefix(key, directiveDeleteFromPrimitiveListPrefix)
+}
+
+// splitDirectives return the fields of a patch and its directives separately, without modifying the patch
+func splitDirectives(patch map[string]interface{}) (fields, directives map[string]interface{}) {
+	fields = make(map[string]interface{}, len(patch))
+	directives = make(map[string]interface{})
+
+	for key, value := range patch {
+		if isDirectiveKey(key) {
+			directives[key] = value
+			continue
+		}
+		fields[key] = value
+	}
+
+	return fields, directives
+}
+
+// removeDirectives return a copy of a patch value without directives, to be used when there is nothing to merge with.
+// Maps marked with '$patch: delete' are not kept, so false is returned for them
+func removeDirectives(value interface{}) (interface{}, bool) {
+	switch typedValue := value.(type) {
+	case map[string]interface{}:
+		if directive, hasDirective := typedValue[directivePatch]; hasDirective && directive == patchDirectiveDelete {
+			return nil, false
+		}
+
+		result := make(map[string]interface{}, len(typedValue))
+		for key, item := range typedValue {
+			if isDirectiveKey(key) {
+				continue
+			}
+			if cleanItem, keep := removeDirectives(item); keep {
+				result[key] = cleanItem
+			}
+		}
+		return result, true
+
+	case []interface{}:
+		result := make([]interface{}, 0, len(typedValue))
+		for _, item := range typedValue {
+			if cleanItem, keep := removeDirectives(item); keep {
+				result = append(result, cleanItem)
+			}
+		}
+		return result, true
+	}
+
+	return value, true
+}
+
+// applyRetainKeysDirective clears the fields of the original map not present in '$retainKeys' directive.
+// All the fields in the patch must be present in the directive
+func applyRetainKeysDirective(original, patchFields map[string]interface{}, retainKeys interface{}) error {
+	retainKeysList, ok := retainKeys.([]interface{})
+	if !ok {
+		return fmt.Errorf("invalid value '%v' for directive '%s': a list is expected", retainKeys, directiveRetainKeys)
+	}
+
+	retainedKeys := make(map[interface{}]struct{}, len(retainKeysList))
+	for _, key := range retainKeysList {
+		retainedKeys[key] = struct{}{}
+	}
+
+	for key, value := range patchFields {
+		if _, retained := retainedKeys[key]; !retained && value != nil {
+			return fmt.Errorf("field '%s' is present in the patch but not in directive '%s'", key, directiveRetainKeys)
+		}
+	}
+
+	for key := range original {
+		if _, retained := retainedKeys[key]; !retained {
+			delete(original, key)
+		}
+	}
+
+	return nil
+}
+
+// deleteFromPrimitiveList return a list without the items present in the list to delete
+func deleteFromPrimitiveList(original, toDelete []interface{}) []interface{} {
+	result := make([]interface{}, 0, len(original))
+	for _, item := range original {
+		if !listContainsItem(toDelete, item) {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// extractListDirectives processes the items of a patch list with '$patch' directive.
+// Items marked with 'delete' are removed from the original list, using the merge keys to find them.
+// When an item is marked with 'replace', the original list must be replaced by the rest of the items.
+// It returns the original list and the patch without the processed items
+func extractListDirectives(original, patch []interface{}, mergeKeys []string) (
+	resultOriginal, resultPatch []interface{}, replace bool, err error) {
+
+	resultOriginal = original
+	resultPatch = make([]interface{}, 0, len(patch))
+
+	for _, item := range patch {
+		itemMap, isMap := item.(map[string]interface{})
+		if !isMap {
+			resultPatch = append(resultPatch, item)
+			continue
+		}
+
+		directive, hasDirective := itemMap[directivePatch]
+		if !hasDirective {
+			resultPatch = append(resultPatch, item)
+			continue
+		}
+
+		switch directive {
+		case patchDirectiveDelete:
+			deleteKey, keyErr := generateCompositeKey(itemMap, mergeKeys)
+			if keyErr != nil {
+				return nil, nil, false, fmt.Errorf("failed to find the item to delete: %w", keyErr)
+			}
+
+			keptItems := make([]interface{}, 0, len(resultOriginal))
+			for _, originalItem := range resultOriginal {
+				if originalItemMap, ok := originalItem.(map[string]interface{}); ok {
+					originalKey, originalKeyErr := generateCompositeKey(originalItemMap, mergeKeys)
+					if originalKeyErr == nil && originalKey == deleteKey {
+						continue
+					}
+				}
+				keptItems = append(keptItems, originalItem)
+			}
+			resultOriginal = keptItems
+
+		case patchDirectiveReplace:
+			replace = true
+
+		case patchDirectiveMerge:
+			// Merging is the default behavior for these lists, so nothing to do
+
+		default:
+			return nil, nil, false, fmt.Errorf("invalid value '%v' for directive '%s'", directive, directivePatch)
+		}
+	}
+
+	return resultOriginal, resultPatch, replace, nil
+}
+
+// sortListByElementOrder sorts a merged list following '$setElementOrder' directive, as Kubernetes does.
+// Items present in the directive follow its order, while the rest keep the order they had in the original list.
+// Both groups are interleaved with best effort, keeping the order of the original list when both items are found there
+// Ref: https://git.k8s.io/design-proposals-archive/cli/preserve-order-in-strategic-merge-patch.md
+func sortListByElementOrder(merged, elementOrder, original []interface{}, mergeKeys []string) []interface{} {
+
+	var patchItems, serverOnlyItems []interface{}
+	for _, item := range merged {
+		if indexOfListItem(elementOrder, item, mergeKeys) >= 0 {
+			patchItems = append(patchItems, item)
+		} else {
+			serverOnlyItems = append(serverOnlyItems, item)
+		}
+	}
+
+	sortListByReference(patchItems, elementOrder, mergeKeys)
+	sortListByReference(serverOnlyItems, original, mergeKeys)
+
+	// Insert server-only items between patch items when both are found in the original list
+	result := make([]interface{}, 0, len(merged))
+	i, j := 0, 0
+	for i < len(serverOnlyItems) || j < len(patchItems) {
+		if i >= len(serverOnlyItems) {
+			result = append(result, patchItems[j])
+			j++
+			continue
+		}
+		if j >= len(patchItems) {
+			result = append(result, serverOnlyItems[i])
+			i++
+			continue
+		}
+
+		serverOnlyIndex := indexOfListItem(original, serverOnlyItems[i], mergeKeys)
+		patchIndex := indexOfListItem(original, patchItems[j], mergeKeys)
+		if serverOnlyIndex >= 0 && patchIndex >= 0 && serverOnlyIndex < patchIndex {
+			result = append(result, serverOnlyItems[i])
+			i++
+		} else {
+			result = append(result, patchItems[j])
+			j++
+		}
+	}
+
+	return result
+}
+
+// sortListByReference sorts a list following the order of the items in a reference list.
+// Items not present in the reference list are moved to the end
+func sortListByReference(list, reference []interface{}, mergeKeys []string) {
+	sort.SliceStable(list, func(i, j int) bool {
+		indexI := indexOfListItem(reference, list[i], mergeKeys)
+		indexJ := indexOfListItem(reference, list[j], mergeKeys)
+		if indexI < 0 || indexJ < 0 {
+			return indexJ < 0 && indexI >= 0
+		}
+		return indexI < indexJ
+	})
+}
+
+// indexOfListItem return the position of an item in a list, or -1 when it's not found.
+// Maps are compared by their merge keys when provided, and the rest of values are compared entirely
+func indexOfListItem(list []interface{}, item interface{}, mergeKeys []string) int {
+	itemMap, itemIsMap := item.(map[string]interface{})
+
+	for index, listItem := range list {
+		listItemMap, listItemIsMap := listItem.(map[string]interface{})
+		if itemIsMap && listItemIsMap && len(mergeKeys) > 0 {
+			itemKey, itemKeyErr := generateCompositeKey(itemMap, mergeKeys)
+			listItemKey, listItemKeyErr := generateCompositeKey(listItemMap, mergeKeys)
+			if itemKeyErr == nil && listItemKeyErr == nil && itemKey == listItemKey {
+				return index
+			}
+			continue
+		}
+
+		if reflect.DeepEqual(listItem, item) {
+			return index
+		}
+	}
+
+	return -1
+}
+
+// listContainsItem return whether a list contains an item, comparing them entirely
+func listContainsItem(list []interface{}, item interface{}) bool {
+	return indexOfListItem(list, item, nil) >= 0
+}
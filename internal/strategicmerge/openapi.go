@@ -0,0 +1,326 @@
+package strategicmerge
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	//
+	openapi_v3 "github.com/google/gnostic-models/openapiv3"
+	protobuf "google.golang.org/protobuf/proto"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/openapi"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/kube-openapi/pkg/util/proto"
+)
+
+const (
+	// openapiRefreshAttempts is the number of times a GroupVersion is looked up after a change.
+	// Kubernetes publishes the OpenAPI schemas of a CRD some time after it's changed
+	openapiRefreshAttempts      = 15
+	openapiRefreshRetryInterval = 2 * time.Second
+)
+
+var (
+	customResourceDefinitionGVR = schema.GroupVersionResource{
+		Group:    "apiextensions.k8s.io",
+		Version:  "v1",
+		Resource: "customresourcedefinitions",
+	}
+)
+
+// getGroupVersionFromOpenapiPath returns the GroupVersion for a path of the OpenAPI v3 discovery.
+// Paths not related to a GroupVersion are reported as not valid
+// Example: 'apis/apps/v1' -> 'apps/v1', 'api/v1' -> 'v1'
+func getGroupVersionFromOpenapiPath(path string) (schema.GroupVersion, bool) {
+	pathParts := strings.Split(strings.Trim(path, "/"), "/")
+
+	switch {
+	case len(pathParts) == 2 && pathParts[0] == "api":
+		return schema.GroupVersion{Version: pathParts[1]}, true
+	case len(pathParts) == 3 && pathParts[0] == "apis":
+		return schema.GroupVersion{Group: pathParts[1], Version: pathParts[2]}, true
+	}
+
+	return schema.GroupVersion{}, false
+}
+
+// fetchOpenapiPaths returns the OpenAPI v3 documents published by Kubernetes indexed by GroupVersion
+func (r *StrategicMergePatcher) fetchOpenapiPaths() (map[schema.GroupVersion]openapi.GroupVersion, error) {
+	paths, err := r.discoveryClient.OpenAPIV3().Paths()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[schema.GroupVersion]openapi.GroupVersion, len(paths))
+	for path, openapiGroupVersion := range paths {
+		groupVersion, isGroupVersion := getGroupVersionFromOpenapiPath(path)
+		if !isGroupVersion {
+			continue
+		}
+		result[groupVersion] = openapiGroupVersion
+	}
+
+	return result, nil
+}
+
+// fetchOpenapiModels returns Kubernetes OpenAPI models from an OpenAPI v3 document
+func (r *StrategicMergePatcher) fetchOpenapiModels(openapiGroupVersion openapi.GroupVersion) (proto.Models, error) {
+	documentBytes, err := openapiGroupVersion.Schema(openapi.ContentTypeOpenAPIV3PB)
+	if err != nil {
+		return nil, err
+	}
+
+	document := &openapi_v3.Document{}
+	err = protobuf.Unmarshal(documentBytes, document)
+	if err != nil {
+		return nil, err
+	}
+
+	return proto.NewOpenAPIV3Data(document)
+}
+
+// updateOpenapiModels fetches the OpenAPI models of every GroupVersion from Kubernetes and stores them
+// in the StrategicMergePatcher as a local cache for performing queries.
+// GroupVersions that can not be fetched, such as unavailable aggregated APIs, are queued to be retried later
+func (r *StrategicMergePatcher) updateOpenapiModels() error {
+	paths, err := r.fetchOpenapiPaths()
+	if err != nil {
+		return fmt.Errorf("failed getting OpenAPI paths from Kubernetes: %v", err.Error())
+	}
+
+	for groupVersion, openapiGroupVersion := range paths {
+		err = r.updateGroupVersionModels(groupVersion, openapiGroupVersion)
+		if err != nil {
+			log.Printf("%v", err.Error())
+			r.queueGroupVersion(groupVersion, openapiRefreshAttempts)
+		}
+	}
+
+	return nil
+}
+
+// updateGroupVersionModels fetches the OpenAPI models of a GroupVersion from Kubernetes
+// and replaces the ones stored for it in the local cache
+func (r *StrategicMergePatcher) updateGroupVersionModels(groupVersion schema.GroupVersion, openapiGroupVersion openapi.GroupVersion) error {
+	models, err := r.fetchOpenapiModels(openapiGroupVersion)
+	if err != nil {
+		return fmt.Errorf("failed getting OpenAPI models from Kubernetes for '%s': %v", groupVersion.String(), err.Error())
+	}
+
+	schemasByGVK, err := r.getMappedSchemasByGVK(groupVersion, models)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.removeGroupVersionModels(groupVersion)
+
+	r.openApiModelsByGroupVersion[groupVersion] = models
+	r.openApiURLsByGroupVersion[groupVersion] = openapiGroupVersion.ServerRelativeURL()
+	r.openApiGroupVersions[groupVersion] = openapiGroupVersion
+	for gvk, gvkSchema := range schemasByGVK {
+		r.openApiSchemasByGVK[gvk] = gvkSchema
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed getting schemas from OpenAPI models for '%s': %v", groupVersion.String(), err.Error())
+	}
+
+	return nil
+}
+
+// removeGroupVersionModels deletes everything stored for a GroupVersion in the local cache.
+// This function must be called with the lock held
+func (r *StrategicMergePatcher) removeGroupVersionModels(groupVersion schema.GroupVersion) {
+	delete(r.openApiModelsByGroupVersion, groupVersion)
+	delete(r.openApiURLsByGroupVersion, groupVersion)
+	delete(r.openApiGroupVersions, groupVersion)
+	delete(r.typeConvertersByGroupVersion, groupVersion)
+
+	for gvk := range r.openApiSchemasByGVK {
+		if gvk.GroupVersion() == groupVersion {
+			delete(r.openApiSchemasByGVK, gvk)
+		}
+	}
+}
+
+// queueGroupVersion marks a GroupVersion to have its OpenAPI models refreshed
+func (r *StrategicMergePatcher) queueGroupVersion(groupVersion schema.GroupVersion, attempts int) {
+	r.pendingMu.Lock()
+	r.pendingGroupVersions[groupVersion] = attempts
+	r.pendingMu.Unlock()
+
+	select {
+	case r.pendingSignal <- struct{}{}:
+	default:
+	}
+}
+
+// queueCustomResourceDefinitionGroupVersions marks the GroupVersions served by the given CRDs
+// to have their OpenAPI models refreshed
+func (r *StrategicMergePatcher) queueCustomResourceDefinitionGroupVersions(objects ...interface{}) {
+	for _, object := range objects {
+		if tombstone, isTombstone := object.(cache.DeletedFinalStateUnknown); isTombstone {
+			object = tombstone.Obj
+		}
+
+		crd, ok := object.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+
+		group, _, _ := unstructured.NestedString(crd.Object, "spec", "group")
+		versions, _, _ := unstructured.NestedSlice(crd.Object, "spec", "versions")
+
+		for _, version := range versions {
+			versionMap, ok := version.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			versionName, _, _ := unstructured.NestedString(versionMap, "name")
+			if versionName == "" {
+				continue
+			}
+
+			r.queueGroupVersion(schema.GroupVersion{Group: group, Version: versionName}, openapiRefreshAttempts)
+		}
+	}
+}
+
+// processPendingGroupVersions refreshes the OpenAPI models of queued GroupVersions.
+// GroupVersions whose documents did not change yet are queued again while they have attempts left.
+// It returns whether some GroupVersion is still waiting to be refreshed
+func (r *StrategicMergePatcher) processPendingGroupVersions() bool {
+	r.pendingMu.Lock()
+	pendingGroupVersions := r.pendingGroupVersions
+	r.pendingGroupVersions = map[schema.GroupVersion]int{}
+	r.pendingMu.Unlock()
+
+	if len(pendingGroupVersions) == 0 {
+		return false
+	}
+
+	requeue := func(groupVersion schema.GroupVersion, attempts int) {
+		if attempts <= 1 {
+			return
+		}
+		r.pendingMu.Lock()
+		if _, alreadyQueued := r.pendingGroupVersions[groupVersion]; !alreadyQueued {
+			r.pendingGroupVersions[groupVersion] = attempts - 1
+		}
+		r.pendingMu.Unlock()
+	}
+
+	paths, err := r.fetchOpenapiPaths()
+	if err != nil {
+		log.Printf("failed getting OpenAPI paths from Kubernetes: %v", err.Error())
+		for groupVersion, attempts := range pendingGroupVersions {
+			requeue(groupVersion, attempts)
+		}
+		goto checkPending
+	}
+
+	for groupVersion, attempts := range pendingGroupVersions {
+
+		// GroupVersion is not served anymore, or it's not published yet
+		openapiGroupVersion, published := paths[groupVersion]
+		if !published {
+			r.mu.Lock()
+			r.removeGroupVersionModels(groupVersion)
+			r.mu.Unlock()
+
+			requeue(groupVersion, attempts)
+			continue
+		}
+
+		// The URL contains a hash of the document, so unchanged URLs mean unchanged documents
+		r.mu.RLock()
+		currentURL := r.openApiURLsByGroupVersion[groupVersion]
+		r.mu.RUnlock()
+
+		if openapiGroupVersion.ServerRelativeURL() == currentURL {
+			requeue(groupVersion, attempts)
+			continue
+		}
+
+		err = r.updateGroupVersionModels(groupVersion, openapiGroupVersion)
+		if err != nil {
+			log.Printf("%v", err.Error())
+			requeue(groupVersion, attempts)
+		}
+	}
+
+checkPending:
+	r.pendingMu.Lock()
+	defer r.pendingMu.Unlock()
+
+	return len(r.pendingGroupVersions) > 0
+}
+
+// refreshPendingGroupVersions refreshes the OpenAPI models of queued GroupVersions when they are signaled,
+// retrying periodically while some of them are still waiting.
+// This function intended to be executed as a goroutine
+func (r *StrategicMergePatcher) refreshPendingGroupVersions(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.pendingSignal:
+		}
+
+		for r.processPendingGroupVersions() {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(openapiRefreshRetryInterval):
+			}
+		}
+	}
+}
+
+// watchCustomResourceDefinitions watches CRDs in Kubernetes and queues the GroupVersions they serve
+// to have their OpenAPI models refreshed when CRDs are created, changed or deleted.
+// This function intended to be executed as a goroutine
+func (r *StrategicMergePatcher) watchCustomResourceDefinitions(ctx context.Context) {
+
+	factory := dynamicinformer.NewDynamicSharedInformerFactory(r.dynamicClient, 0)
+	crdInformer := factory.ForResource(customResourceDefinitionGVR).Informer()
+
+	handlers := cache.ResourceEventHandlerDetailedFuncs{
+		AddFunc: func(eventObject interface{}, isInInitialList bool) {
+			// Existing CRDs were already covered by the initial fetch of OpenAPI models
+			if isInInitialList {
+				return
+			}
+			r.queueCustomResourceDefinitionGroupVersions(eventObject)
+		},
+		UpdateFunc: func(eventObjectOld, eventObject interface{}) {
+			convertedEventObjectOld, okOld := eventObjectOld.(*unstructured.Unstructured)
+			convertedEventObject, ok := eventObject.(*unstructured.Unstructured)
+
+			// Changes in status are not relevant for OpenAPI schemas
+			if okOld && ok && convertedEventObjectOld.GetGeneration() == convertedEventObject.GetGeneration() {
+				return
+			}
+
+			// Old versions are queued too, as they may have been removed
+			r.queueCustomResourceDefinitionGroupVersions(eventObjectOld, eventObject)
+		},
+		DeleteFunc: func(eventObject interface{}) {
+			r.queueCustomResourceDefinitionGroupVersions(eventObject)
+		},
+	}
+
+	_, err := crdInformer.AddEventHandler(handlers)
+	if err != nil {
+		log.Printf("failed adding handling functions for CRD events: %v", err.Error())
+		return
+	}
+
+	crdInformer.Run(ctx.Done())
+}
@@ -0,0 +1,156 @@
+package strategicmerge
+
+import (
+	"reflect"
+	"testing"
+
+	//
+	openapi_v3 "github.com/google/gnostic-models/openapiv3"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/util/proto"
+)
+
+// testCrdOpenapiDocument is a reduced OpenAPI v3 document, as Kubernetes publishes it for a CRD,
+// whose lists only declare how to be merged with 'x-kubernetes-list-type' and 'x-kubernetes-list-map-keys'
+const testCrdOpenapiDocument = `
+openapi: 3.0.0
+info:
+  title: Kubernetes CRD Swagger
+  version: v0.1.0
+paths: {}
+components:
+  schemas:
+    dev.example.v1.Widget:
+      type: object
+      x-kubernetes-group-version-kind:
+        - group: example.dev
+          version: v1
+          kind: Widget
+      properties:
+        apiVersion:
+          type: string
+        kind:
+          type: string
+        metadata:
+          $ref: '#/components/schemas/io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta'
+        spec:
+          type: object
+          properties:
+            ports:
+              type: array
+              x-kubernetes-list-type: map
+              x-kubernetes-list-map-keys:
+                - port
+                - protocol
+              items:
+                type: object
+                properties:
+                  port:
+                    type: integer
+                  protocol:
+                    type: string
+                  name:
+                    type: string
+    io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta:
+      type: object
+      properties:
+        name:
+          type: string
+`
+
+func TestGetGroupVersionFromOpenapiPath(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected schema.GroupVersion
+		valid    bool
+	}{
+		{path: "api/v1", expected: schema.GroupVersion{Version: "v1"}, valid: true},
+		{path: "apis/apps/v1", expected: schema.GroupVersion{Group: "apps", Version: "v1"}, valid: true},
+		{path: "/apis/example.dev/v1alpha1", expected: schema.GroupVersion{Group: "example.dev", Version: "v1alpha1"}, valid: true},
+		{path: "version", valid: false},
+		{path: "apis/example.dev", valid: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			result, valid := getGroupVersionFromOpenapiPath(test.path)
+			if valid != test.valid || result != test.expected {
+				t.Errorf("unexpected result: got '%v' (%v), want '%v' (%v)", result, valid, test.expected, test.valid)
+			}
+		})
+	}
+}
+
+func TestStrategicMergeOpenapiV3ListMapKeys(t *testing.T) {
+	document, err := openapi_v3.ParseDocument([]byte(testCrdOpenapiDocument))
+	if err != nil {
+		t.Fatalf("failed parsing OpenAPI document: %v", err)
+	}
+
+	models, err := proto.NewOpenAPIV3Data(document)
+	if err != nil {
+		t.Fatalf("failed getting models from OpenAPI document: %v", err)
+	}
+
+	patcher := &StrategicMergePatcher{}
+	groupVersion := schema.GroupVersion{Group: "example.dev", Version: "v1"}
+
+	schemasByGVK, err := patcher.getMappedSchemasByGVK(groupVersion, models)
+	if err != nil {
+		t.Fatalf("failed mapping schemas by GVK: %v", err)
+	}
+
+	widgetSchema, found := schemasByGVK[groupVersion.WithKind("Widget")]
+	if !found {
+		t.Fatalf("schema not found for GVK, got: %v", schemasByGVK)
+	}
+
+	original := `
+metadata:
+  name: example
+spec:
+  ports:
+    - port: 80
+      protocol: TCP
+      name: http
+    - port: 80
+      protocol: UDP
+      name: dns
+`
+	patch := `
+metadata:
+  name: example
+spec:
+  ports:
+    - port: 80
+      protocol: UDP
+      name: quic
+    - port: 443
+      protocol: TCP
+      name: https
+`
+	expected := `
+metadata:
+  name: example
+spec:
+  ports:
+    - port: 80
+      protocol: TCP
+      name: http
+    - port: 80
+      protocol: UDP
+      name: quic
+    - port: 443
+      protocol: TCP
+      name: https
+`
+
+	result, err := patcher.StrategicMerge(decodeTestYaml(t, original), decodeTestYaml(t, patch), *widgetSchema)
+	if err != nil {
+		t.Fatalf("strategic merge failed: %v", err)
+	}
+
+	if result, expected := normalizeTestObject(t, result), normalizeTestObject(t, decodeTestYaml(t, expected)); !reflect.DeepEqual(result, expected) {
+		t.Errorf("unexpected result:\n got: %v\nwant: %v", result, expected)
+	}
+}
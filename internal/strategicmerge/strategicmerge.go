@@ -1,19 +1,21 @@
 package strategicmerge
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"log"
 	"path/filepath"
 	"reflect"
 	"sort"
 	"strings"
 	"sync"
-	"time"
 
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/managedfields"
 	//
 	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/openapi"
 	"k8s.io/kube-openapi/pkg/util/proto"
 )
 
@@ -39,23 +41,42 @@ const (
 
 type StrategicMergePatcherDependencies struct {
 	DiscoveryClient *discovery.DiscoveryClient
+	DynamicClient   *dynamic.DynamicClient
 }
 type StrategicMergePatcher struct {
 	mu sync.RWMutex
 
 	//
 	discoveryClient *discovery.DiscoveryClient
+	dynamicClient   *dynamic.DynamicClient
 
 	// carried stuff
-	openApiModels       *proto.Models
-	openApiSchemasByGVK map[schema.GroupVersionKind]*proto.Schema
+	openApiModelsByGroupVersion map[schema.GroupVersion]proto.Models
+	openApiURLsByGroupVersion   map[schema.GroupVersion]string
+	openApiSchemasByGVK         map[schema.GroupVersionKind]*proto.Schema
+
+	// OpenAPI documents of each GroupVersion, and the TypeConverters built from them on demand for apply configurations
+	openApiGroupVersions         map[schema.GroupVersion]openapi.GroupVersion
+	typeConvertersByGroupVersion map[schema.GroupVersion]managedfields.TypeConverter
+
+	// GroupVersions waiting for their OpenAPI models to be refreshed, with the attempts left for each one
+	pendingMu            sync.Mutex
+	pendingGroupVersions map[schema.GroupVersion]int
+	pendingSignal        chan struct{}
 }
 
-func NewStrategicMergePatcher(deps *StrategicMergePatcherDependencies) (*StrategicMergePatcher, error) {
+func NewStrategicMergePatcher(ctx context.Context, deps *StrategicMergePatcherDependencies) (*StrategicMergePatcher, error) {
 
 	smp := &StrategicMergePatcher{
-		discoveryClient:     deps.DiscoveryClient,
-		openApiSchemasByGVK: map[schema.GroupVersionKind]*proto.Schema{},
+		discoveryClient:              deps.DiscoveryClient,
+		dynamicClient:                deps.DynamicClient,
+		openApiModelsByGroupVersion:  map[schema.GroupVersion]proto.Models{},
+		openApiURLsByGroupVersion:    map[schema.GroupVersion]string{},
+		openApiSchemasByGVK:          map[schema.GroupVersionKind]*proto.Schema{},
+		openApiGroupVersions:         map[schema.GroupVersion]openapi.GroupVersion{},
+		typeConvertersByGroupVersion: map[schema.GroupVersion]managedfields.TypeConverter{},
+		pendingGroupVersions:         map[schema.GroupVersion]int{},
+		pendingSignal:                make(chan struct{}, 1),
 	}
 
 	// Initial OpenAPI models parsing
@@ -64,71 +85,23 @@ func NewStrategicMergePatcher(deps *StrategicMergePatcherDependencies) (*Strateg
 		return nil, err
 	}
 
-	// Update models periodically
-	go smp.keepUpdatedOpenapiModels()
+	// Update models only for the GroupVersions whose CRDs change
+	go smp.refreshPendingGroupVersions(ctx)
+	go smp.watchCustomResourceDefinitions(ctx)
 
 	return smp, nil
 }
 
-// updateOpenapiModels fetches the Kubernetes OpenAPI models from Kubernetes and stores them
-// in the StrategicMergePatcher as a local cache for performing queries
-func (r *StrategicMergePatcher) updateOpenapiModels() error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	models, err := r.fetchOpenapiModels()
-	if err != nil {
-		return fmt.Errorf("failed getting OpenAPI models from Kubernetes: %v", err.Error())
-	}
-
-	r.openApiModels = models
-	r.openApiSchemasByGVK, err = r.getMappedSchemasByGVK(models)
-	if err != nil {
-		return fmt.Errorf("failed getting schemas from OpenAPI models: %v", err.Error())
-	}
-
-	return nil
-}
-
-// keepUpdatedOpenapiModels updates local cache of Kubernetes OpenAPI models periodically
-// This function intended to be executed as a goroutine
-func (r *StrategicMergePatcher) keepUpdatedOpenapiModels() {
-	for {
-		err := r.updateOpenapiModels()
-		if err != nil {
-			log.Printf("%v", err.Error())
-			goto takeANap
-		}
-
-	takeANap:
-		time.Sleep(5 * time.Second)
-	}
-}
-
-// fetchOpenapiModels returns Kubernetes OpenAPI models
-func (r *StrategicMergePatcher) fetchOpenapiModels() (*proto.Models, error) {
-	openapiSchema, err := r.discoveryClient.OpenAPISchema()
-	if err != nil {
-		return nil, err
-	}
-
-	models, err := proto.NewOpenAPIData(openapiSchema)
-	if err != nil {
-		return nil, err
-	}
-
-	return &models, nil
-}
-
-// getMappedSchemasByGVK receives the OpenAPI models and returns a map of Schemas with GVKs as indexes
-func (r *StrategicMergePatcher) getMappedSchemasByGVK(models *proto.Models) (result map[schema.GroupVersionKind]*proto.Schema, err error) {
+// getMappedSchemasByGVK receives the OpenAPI models of a GroupVersion and returns a map of Schemas with GVKs as indexes.
+// Only GVKs belonging to the GroupVersion are mapped, as shared models declare GVKs from other GroupVersions too
+func (r *StrategicMergePatcher) getMappedSchemasByGVK(groupVersion schema.GroupVersion, models proto.Models) (result map[schema.GroupVersionKind]*proto.Schema, err error) {
 
 	result = map[schema.GroupVersionKind]*proto.Schema{}
 
 	errorList := []error{}
 
-	for _, modelName := range (*models).ListModels() {
-		modelSchema := (*r.openApiModels).LookupModel(modelName)
+	for _, modelName := range models.ListModels() {
+		modelSchema := models.LookupModel(modelName)
 		if modelSchema == nil {
 			continue
 		}
@@ -149,6 +122,9 @@ func (r *StrategicMergePatcher) getMappedSchemasByGVK(models *proto.Models) (res
 		}
 
 		for _, gvk := range gvkList {
+			if gvk.GroupVersion() != groupVersion {
+				continue
+			}
 			result[gvk] = &modelSchema
 		}
 	}
@@ -156,14 +132,20 @@ func (r *StrategicMergePatcher) getMappedSchemasByGVK(models *proto.Models) (res
 	return result, errors.Join(errorList...)
 }
 
-// GetModelSchema returns a Schema for the given model name
+// GetModelSchema returns a Schema for the given model name, looking for it in the models of every GroupVersion
 // Example: 'io.k8s.api.core.v1.Volume' -> proto.Schema
 func (r *StrategicMergePatcher) GetModelSchema(modelName string) proto.Schema {
 
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	return (*r.openApiModels).LookupModel(modelName)
+	for _, models := range r.openApiModelsByGroupVersion {
+		if modelSchema := models.LookupModel(modelName); modelSchema != nil {
+			return modelSchema
+		}
+	}
+
+	return nil
 }
 
 // resolveSchemaReference receives a Schema and returns it back.
@@ -180,6 +162,11 @@ func (r *StrategicMergePatcher) resolveSchemaReference(schema proto.Schema) (pro
 		return schema, nil
 	}
 
+	// References are resolved first inside the document they come from
+	if resolvedSchema := ref.SubSchema(); resolvedSchema != nil {
+		return resolvedSchema, nil
+	}
+
 	modelName := filepath.Base(ref.Reference())
 	resolvedSchema := r.GetModelSchema(modelName)
 
@@ -208,8 +195,17 @@ func (r *StrategicMergePatcher) GetSchemaGVK(modelSchema proto.Schema) (result [
 
 	for _, rawGVKItem := range rawGVKListConverted {
 
-		gvkMap, ok := rawGVKItem.(map[interface{}]interface{})
-		if !ok {
+		// Extensions are decoded with string keys from OpenAPI v3 documents,
+		// but with generic keys from OpenAPI v2 ones
+		gvkMap := map[string]interface{}{}
+		switch typedGVKItem := rawGVKItem.(type) {
+		case map[string]interface{}:
+			gvkMap = typedGVKItem
+		case map[interface{}]interface{}:
+			for key, value := range typedGVKItem {
+				gvkMap[fmt.Sprint(key)] = value
+			}
+		default:
 			errorList = append(errorList, fmt.Errorf("failed asserting GVK item for schema"))
 			continue
 		}
@@ -416,6 +412,18 @@ func (r *StrategicMergePatcher) mergeLists(original, patch []interface{}, patchI
 		return mergeScalarList(original, patch), nil
 	}
 
+	// Process '$patch' directives in the items: deleted ones are removed from the original list,
+	// and the whole list is replaced when asked
+	original, patch, replaceList, err := extractListDirectives(original, patch, patchInfo.MergeKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	if replaceList {
+		replacedList, _ := removeDirectives(patch)
+		return replacedList.([]interface{}), nil
+	}
+
 	// Get item schema for recursive merging of list elements.
 	var itemSchema proto.Schema
 	if arraySchema, ok := listSchema.(*proto.Array); ok {
@@ -445,7 +453,7 @@ func (r *StrategicMergePatcher) mergeLists(original, patch []interface{}, patchI
 		patchIndex[compositeKey] = itemMap
 	}
 
-	var resultList []interface{}
+	resultList := []interface{}{}
 	var partialProcessingErrors []error
 
 	// Iterate through the original list to merge or keep items.
@@ -517,9 +525,32 @@ func (r *StrategicMergePatcher) mergeLists(original, patch []interface{}, patchI
 }
 
 // StrategicMerge performs a strategic merge on two map[string]interface{} objects.
+// Directives from Kubernetes strategic merge patch are supported: '$patch', '$retainKeys', '$setElementOrder/{field}'
+// and '$deleteFromPrimitiveList/{field}'. Null values in the patch delete the fields
 func (r *StrategicMergePatcher) StrategicMerge(original, patch map[string]interface{}, schema proto.Schema) (map[string]interface{}, error) {
 	var err error
 
+	// 0. Separate the directives from the fields, as they are not part of the schema.
+	// Directive '$patch' decides how the whole map is merged, so it's handled first
+	patch, directives := splitDirectives(patch)
+
+	if directive, hasDirective := directives[directivePatch]; hasDirective {
+		switch directive {
+		case patchDirectiveReplace:
+			replacedMap, _ := removeDirectives(patch)
+			return replacedMap.(map[string]interface{}), nil
+
+		case patchDirectiveDelete:
+			return map[string]interface{}{}, nil
+
+		case patchDirectiveMerge:
+			// Merging is the default behavior for maps, so nothing to do
+
+		default:
+			return nil, fmt.Errorf("invalid value '%v' for directive '%s'", directive, directivePatch)
+		}
+	}
+
 	// 1. Resolve Schema references if applicable.
 	// This ensures we always work with a concrete schema for field lookups.
 	schema, err = r.resolveSchemaReference(schema)
@@ -534,6 +565,30 @@ func (r *StrategicMergePatcher) StrategicMerge(original, patch map[string]interf
 
 	result = SanitizeNils(result)
 
+	// Clear the fields not retained, and the items to delete from primitive lists, before merging the rest
+	if retainKeys, hasDirective := directives[directiveRetainKeys]; hasDirective {
+		err = applyRetainKeysDirective(result, patch, retainKeys)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for directiveKey, directiveValue := range directives {
+		if !strings.HasPrefix(directiveKey, directiveDeleteFromPrimitiveListPrefix) {
+			continue
+		}
+
+		itemsToDelete, ok := directiveValue.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid value '%v' for directive '%s': a list is expected", directiveValue, directiveKey)
+		}
+
+		fieldKey := strings.TrimPrefix(directiveKey, directiveDeleteFromPrimitiveListPrefix)
+		if originalList, ok := result[fieldKey].([]interface{}); ok {
+			result[fieldKey] = deleteFromPrimitiveList(originalList, itemsToDelete)
+		}
+	}
+
 	// 2. Iterate over fields in 'patch' looking for them in the 'original' object.
 	// Depending on the type of field (map, list or primitive), perform the most suitable type of merge,
 	// then overwrite the entire field in the original object
@@ -541,6 +596,12 @@ func (r *StrategicMergePatcher) StrategicMerge(original, patch map[string]interf
 
 	for patchKey, patchValue := range patch {
 
+		// Null values delete the fields
+		if patchValue == nil {
+			delete(result, patchKey)
+			continue
+		}
+
 		originalValue, originalExists := result[patchKey]
 
 		// Get schema for the current field being processed.
@@ -568,7 +629,7 @@ func (r *StrategicMergePatcher) StrategicMerge(original, patch map[string]interf
 			patchInfo := getPatchStrategyFromSchema(fieldSchema)
 			if patchInfo.Strategy == strategyReplace {
 				// The map is "atomic", so we replace it entirely.
-				result[patchKey] = patchValue
+				result[patchKey], _ = removeDirectives(patchValue)
 			} else {
 
 				// The map is "granular" (or no strategy specified), perform a recursive strategic merge.
@@ -611,10 +672,46 @@ func (r *StrategicMergePatcher) StrategicMerge(original, patch map[string]interf
 
 		default:
 			// For primitive types or type mismatches, replace if different or new.
+			// Maps with directives not merged with anything are cleaned up
 			if !originalExists || !reflect.DeepEqual(originalValue, patchValue) {
-				result[patchKey] = patchValue
+				cleanValue, keep := removeDirectives(patchValue)
+				if !keep {
+					delete(result, patchKey)
+					continue
+				}
+				result[patchKey] = cleanValue
+			}
+		}
+	}
+
+	// 3. Sort the merged lists following '$setElementOrder' directives
+	for directiveKey, directiveValue := range directives {
+		if !strings.HasPrefix(directiveKey, directiveSetElementOrderPrefix) {
+			continue
+		}
+
+		elementOrder, ok := directiveValue.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid value '%v' for directive '%s': a list is expected", directiveValue, directiveKey)
+		}
+
+		fieldKey := strings.TrimPrefix(directiveKey, directiveSetElementOrderPrefix)
+		mergedList, ok := result[fieldKey].([]interface{})
+		if !ok {
+			continue
+		}
+
+		var fieldSchema proto.Schema
+		if kindSchema, ok := schema.(*proto.Kind); ok && kindSchema.Fields[fieldKey] != nil {
+			fieldSchema, err = r.resolveSchemaReference(kindSchema.Fields[fieldKey])
+			if err != nil {
+				return nil, fmt.Errorf("failed resolving child schema reference for patch key '%v': %v", fieldKey, err.Error())
 			}
 		}
+
+		originalList, _ := original[fieldKey].([]interface{})
+		patchInfo := getPatchStrategyFromSchema(fieldSchema)
+		result[fieldKey] = sortListByElementOrder(mergedList, elementOrder, originalList, patchInfo.MergeKeys)
 	}
 
 	if len(mergeErrors) > 0 {
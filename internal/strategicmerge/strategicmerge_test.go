@@ -0,0 +1,542 @@
+package strategicmerge
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	//
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/kube-openapi/pkg/util/proto"
+	"sigs.k8s.io/yaml"
+)
+
+// getTestPodSchema return a reduced version of the OpenAPI schema of a Pod,
+// with the same patch strategies Kubernetes defines for the fields used in the tests
+func getTestPodSchema() proto.Schema {
+	stringSchema := &proto.Primitive{Type: "string"}
+
+	envVarSchema := &proto.Kind{
+		Fields: map[string]proto.Schema{
+			"name":  stringSchema,
+			"value": stringSchema,
+		},
+	}
+
+	containerSchema := &proto.Kind{
+		Fields: map[string]proto.Schema{
+			"name":  stringSchema,
+			"image": stringSchema,
+			"args":  &proto.Array{SubType: stringSchema},
+			"env": &proto.Array{
+				BaseSchema: proto.BaseSchema{Extensions: map[string]interface{}{
+					extPatchStrategy: "merge",
+					extPatchMergeKey: "name",
+				}},
+				SubType: envVarSchema,
+			},
+		},
+	}
+
+	volumeSchema := &proto.Kind{
+		Fields: map[string]proto.Schema{
+			"name":     stringSchema,
+			"emptyDir": &proto.Kind{Fields: map[string]proto.Schema{"medium": stringSchema}},
+			"hostPath": &proto.Kind{Fields: map[string]proto.Schema{"path": stringSchema}},
+		},
+	}
+
+	return &proto.Kind{
+		Fields: map[string]proto.Schema{
+			"apiVersion": stringSchema,
+			"kind":       stringSchema,
+			"metadata": &proto.Kind{
+				Fields: map[string]proto.Schema{
+					"name":   stringSchema,
+					"labels": &proto.Map{SubType: stringSchema},
+					"finalizers": &proto.Array{
+						BaseSchema: proto.BaseSchema{Extensions: map[string]interface{}{
+							extPatchStrategy: "merge",
+						}},
+						SubType: stringSchema,
+					},
+				},
+			},
+			"spec": &proto.Kind{
+				Fields: map[string]proto.Schema{
+					"containers": &proto.Array{
+						BaseSchema: proto.BaseSchema{Extensions: map[string]interface{}{
+							extPatchStrategy: "merge",
+							extPatchMergeKey: "name",
+						}},
+						SubType: containerSchema,
+					},
+					"volumes": &proto.Array{
+						BaseSchema: proto.BaseSchema{Extensions: map[string]interface{}{
+							extPatchStrategy: "merge,retainKeys",
+							extPatchMergeKey: "name",
+						}},
+						SubType: volumeSchema,
+					},
+				},
+			},
+		},
+	}
+}
+
+// decodeTestYaml return the map represented by a YAML document
+func decodeTestYaml(t *testing.T, document string) map[string]interface{} {
+	t.Helper()
+
+	result := map[string]interface{}{}
+	err := yaml.Unmarshal([]byte(document), &result)
+	if err != nil {
+		t.Fatalf("failed decoding YAML: %v", err)
+	}
+
+	return result
+}
+
+// normalizeTestObject return an object as it's encoded and decoded as JSON, so it can be compared with others
+func normalizeTestObject(t *testing.T, object map[string]interface{}) map[string]interface{} {
+	t.Helper()
+
+	objectBytes, err := json.Marshal(object)
+	if err != nil {
+		t.Fatalf("failed encoding object: %v", err)
+	}
+
+	result := map[string]interface{}{}
+	err = json.Unmarshal(objectBytes, &result)
+	if err != nil {
+		t.Fatalf("failed decoding object: %v", err)
+	}
+
+	return result
+}
+
+// mergeWithUpstream merges a patch into an object using the strategic merge patch implementation from Kubernetes
+func mergeWithUpstream(t *testing.T, original, patch string) map[string]interface{} {
+	t.Helper()
+
+	originalBytes, err := yaml.YAMLToJSON([]byte(original))
+	if err != nil {
+		t.Fatalf("failed converting original object into JSON: %v", err)
+	}
+
+	patchBytes, err := yaml.YAMLToJSON([]byte(patch))
+	if err != nil {
+		t.Fatalf("failed converting patch into JSON: %v", err)
+	}
+
+	resultBytes, err := strategicpatch.StrategicMergePatch(originalBytes, patchBytes, corev1.Pod{})
+	if err != nil {
+		t.Fatalf("upstream strategic merge failed: %v", err)
+	}
+
+	result := map[string]interface{}{}
+	err = json.Unmarshal(resultBytes, &result)
+	if err != nil {
+		t.Fatalf("failed decoding upstream result: %v", err)
+	}
+
+	return result
+}
+
+func TestStrategicMergeDirectives(t *testing.T) {
+	tests := []struct {
+		name     string
+		original string
+		patch    string
+		expected string
+
+		// skipUpstream is set for behaviors not supported by the upstream implementation
+		skipUpstream bool
+	}{
+		{
+			name: "patch replace on map replaces the whole map",
+			original: `
+metadata:
+  labels:
+    a: "1"
+    c: "3"
+`,
+			patch: `
+metadata:
+  labels:
+    $patch: replace
+    b: "2"
+`,
+			expected: `
+metadata:
+  labels:
+    b: "2"
+`,
+		},
+		{
+			name: "patch delete on map empties the map",
+			original: `
+metadata:
+  name: example
+  labels:
+    a: "1"
+`,
+			patch: `
+metadata:
+  labels:
+    $patch: delete
+`,
+			expected: `
+metadata:
+  name: example
+  labels: {}
+`,
+		},
+		{
+			name: "patch merge on map keeps default behavior",
+			original: `
+metadata:
+  labels:
+    a: "1"
+`,
+			patch: `
+metadata:
+  labels:
+    $patch: merge
+    b: "2"
+`,
+			expected: `
+metadata:
+  labels:
+    a: "1"
+    b: "2"
+`,
+			skipUpstream: true,
+		},
+		{
+			name: "patch delete on list item removes it by merge key",
+			original: `
+spec:
+  containers:
+    - name: app
+      image: app:1.0.0
+      env:
+        - name: FOO
+          value: foo
+        - name: BAR
+          value: bar
+`,
+			patch: `
+spec:
+  containers:
+    - name: app
+      env:
+        - name: FOO
+          $patch: delete
+`,
+			expected: `
+spec:
+  containers:
+    - name: app
+      image: app:1.0.0
+      env:
+        - name: BAR
+          value: bar
+`,
+		},
+		{
+			name: "patch delete on missing list drops the item",
+			original: `
+spec:
+  containers:
+    - name: app
+      image: app:1.0.0
+`,
+			patch: `
+spec:
+  containers:
+    - name: app
+      env:
+        - name: FOO
+          $patch: delete
+`,
+			expected: `
+spec:
+  containers:
+    - name: app
+      image: app:1.0.0
+      env: []
+`,
+		},
+		{
+			name: "patch replace on list replaces the whole list",
+			original: `
+spec:
+  containers:
+    - name: app
+      image: app:1.0.0
+    - name: sidecar
+      image: sidecar:1.0.0
+`,
+			patch: `
+spec:
+  containers:
+    - $patch: replace
+    - name: other
+      image: other:1.0.0
+`,
+			expected: `
+spec:
+  containers:
+    - name: other
+      image: other:1.0.0
+`,
+		},
+		{
+			name: "retain keys clears the fields not retained",
+			original: `
+spec:
+  volumes:
+    - name: data
+      emptyDir:
+        medium: Memory
+`,
+			patch: `
+spec:
+  volumes:
+    - name: data
+      $retainKeys:
+        - name
+        - hostPath
+      hostPath:
+        path: /tmp
+`,
+			expected: `
+spec:
+  volumes:
+    - name: data
+      hostPath:
+        path: /tmp
+`,
+		},
+		{
+			name: "delete from primitive list removes the items",
+			original: `
+metadata:
+  finalizers:
+    - a
+    - b
+    - c
+`,
+			patch: `
+metadata:
+  $deleteFromPrimitiveList/finalizers:
+    - b
+`,
+			expected: `
+metadata:
+  finalizers:
+    - a
+    - c
+`,
+		},
+		{
+			name: "set element order sorts merged list",
+			original: `
+spec:
+  containers:
+    - name: a
+      image: a:1.0.0
+    - name: b
+      image: b:1.0.0
+    - name: c
+      image: c:1.0.0
+`,
+			patch: `
+spec:
+  $setElementOrder/containers:
+    - name: c
+    - name: a
+    - name: b
+  containers:
+    - name: a
+      image: a:2.0.0
+`,
+			expected: `
+spec:
+  containers:
+    - name: c
+      image: c:1.0.0
+    - name: a
+      image: a:2.0.0
+    - name: b
+      image: b:1.0.0
+`,
+		},
+		{
+			name: "set element order keeps server-only items relative order",
+			original: `
+spec:
+  containers:
+    - name: a
+      image: a:1.0.0
+    - name: b
+      image: b:1.0.0
+    - name: c
+      image: c:1.0.0
+`,
+			patch: `
+spec:
+  $setElementOrder/containers:
+    - name: c
+    - name: a
+  containers:
+    - name: c
+      image: c:2.0.0
+`,
+			expected: `
+spec:
+  containers:
+    - name: b
+      image: b:1.0.0
+    - name: c
+      image: c:2.0.0
+    - name: a
+      image: a:1.0.0
+`,
+		},
+		{
+			name: "set element order sorts primitive list",
+			original: `
+metadata:
+  finalizers:
+    - a
+    - b
+`,
+			patch: `
+metadata:
+  $setElementOrder/finalizers:
+    - b
+    - a
+`,
+			expected: `
+metadata:
+  finalizers:
+    - b
+    - a
+`,
+		},
+		{
+			name: "null value deletes the field",
+			original: `
+metadata:
+  labels:
+    a: "1"
+    b: "2"
+`,
+			patch: `
+metadata:
+  labels:
+    a: null
+`,
+			expected: `
+metadata:
+  labels:
+    b: "2"
+`,
+		},
+	}
+
+	patcher := &StrategicMergePatcher{}
+	schema := getTestPodSchema()
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expected := normalizeTestObject(t, decodeTestYaml(t, test.expected))
+
+			result, err := patcher.StrategicMerge(decodeTestYaml(t, test.original), decodeTestYaml(t, test.patch), schema)
+			if err != nil {
+				t.Fatalf("strategic merge failed: %v", err)
+			}
+
+			if result = normalizeTestObject(t, result); !reflect.DeepEqual(result, expected) {
+				t.Errorf("unexpected result:\n got: %v\nwant: %v", result, expected)
+			}
+
+			if test.skipUpstream {
+				return
+			}
+
+			if upstreamResult := mergeWithUpstream(t, test.original, test.patch); !reflect.DeepEqual(upstreamResult, expected) {
+				t.Errorf("expected result differs from upstream:\nupstream: %v\n    want: %v", upstreamResult, expected)
+			}
+		})
+	}
+}
+
+func TestStrategicMergeDirectivesErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		original string
+		patch    string
+	}{
+		{
+			name: "unknown patch directive on map",
+			original: `
+metadata:
+  labels:
+    a: "1"
+`,
+			patch: `
+metadata:
+  labels:
+    $patch: unknown
+`,
+		},
+		{
+			name: "unknown patch directive on list item",
+			original: `
+spec:
+  containers:
+    - name: app
+`,
+			patch: `
+spec:
+  containers:
+    - name: app
+      $patch: unknown
+`,
+		},
+		{
+			name: "field not present in retain keys",
+			original: `
+spec:
+  volumes:
+    - name: data
+      emptyDir: {}
+`,
+			patch: `
+spec:
+  volumes:
+    - name: data
+      $retainKeys:
+        - name
+      hostPath:
+        path: /tmp
+`,
+		},
+	}
+
+	patcher := &StrategicMergePatcher{}
+	schema := getTestPodSchema()
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := patcher.StrategicMerge(decodeTestYaml(t, test.original), decodeTestYaml(t, test.patch), schema)
+			if err == nil {
+				t.Errorf("strategic merge was expected to fail")
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	"errors"
+	"fmt"
+
+	//
+	"github.com/freepik-company/admitik/internal/accessreview"
+	"github.com/freepik-company/admitik/internal/globals"
+)
+
+// canAccess asks Kubernetes authorizer whether a user can perform an action, through a SubjectAccessReview.
+// When the user is the one performing the request, the rest of its data (uid, extra) is sent too,
+// so the review is as close as possible to the real request.
+// Results are cached for the whole request
+func canAccess(injectedData InjectedDataI, user string, groups []string,
+	verb, group, resource, namespace, name string) (bool, error) {
+
+	requestState := injectedData.GetRequestState()
+	if requestState.Fixtures != nil {
+		return requestState.Fixtures.canAccess(user, verb, group, resource, namespace, name), nil
+	}
+
+	accessReviewClient := globals.Application.AccessReviewClient
+	if accessReviewClient == nil {
+		return false, errors.New("access reviews from templates are not available")
+	}
+
+	subject := accessreview.SubjectT{
+		User:   user,
+		Groups: groups,
+	}
+
+	if requestState.Requester != nil && requestState.Requester.Username == user {
+		subject.UID = requestState.Requester.UID
+		subject.Extra = make(map[string][]string, len(requestState.Requester.Extra))
+		for key, values := range requestState.Requester.Extra {
+			subject.Extra[key] = values
+		}
+	}
+
+	return accessReviewClient.Can(&requestState.AccessReviews, subject, accessreview.ResourceAttributesT{
+		Verb:      verb,
+		Group:     group,
+		Resource:  resource,
+		Namespace: namespace,
+		Name:      name,
+	})
+}
+
+// toStringList converts a list coming from templates into a list of strings.
+// Lists can come as []string or []any, depending on the engine and where they were built
+func toStringList(value any) (result []string, err error) {
+	switch typedValue := value.(type) {
+	case nil:
+		return []string{}, nil
+	case []string:
+		return typedValue, nil
+	case []any:
+		for _, item := range typedValue {
+			itemString, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected a list of strings, but found an item of type %T", item)
+			}
+			result = append(result, itemString)
+		}
+		return result, nil
+	default:
+		return nil, fmt.Errorf("expected a list of strings, but found %T", value)
+	}
+}
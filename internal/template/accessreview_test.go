@@ -0,0 +1,183 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	//
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	//
+	"github.com/freepik-company/admitik/internal/accessreview"
+	"github.com/freepik-company/admitik/internal/globals"
+)
+
+// setTestAccessReviewClient replaces the global access review client by one whose authorizer
+// allows everything to user 'alice', or fails with the given error. Reviews are appended to the given list
+func setTestAccessReviewClient(t *testing.T, reviews *[]authorizationv1.SubjectAccessReview, reviewErr error) {
+	clientset := fake.NewClientset()
+	clientset.PrependReactor("create", "subjectaccessreviews",
+		func(action clienttesting.Action) (bool, runtime.Object, error) {
+			review := action.(clienttesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+			*reviews = append(*reviews, *review)
+
+			if reviewErr != nil {
+				return true, nil, reviewErr
+			}
+
+			review = review.DeepCopy()
+			review.Status.Allowed = review.Spec.User == "alice"
+			return true, review, nil
+		})
+
+	previousClient := globals.Application.AccessReviewClient
+	globals.Application.AccessReviewClient = accessreview.NewAccessReviewClient(context.Background(), clientset)
+	t.Cleanup(func() {
+		globals.Application.AccessReviewClient = previousClient
+	})
+}
+
+func TestCanAccessFromTemplates(t *testing.T) {
+	tests := []struct {
+		name     string
+		engine   string
+		template string
+		expected string
+	}{
+		{
+			name:     "gotmpl",
+			engine:   EngineGotmpl,
+			template: `{{ can "alice" (list "devs") "create" "" "pods" "team-a" "" }}/{{ can "alice" (list "devs") "create" "" "pods" "team-a" "" }}`,
+			expected: "true/true",
+		},
+		{
+			name:     "cel",
+			engine:   EngineCel,
+			template: `string(can("alice", ["devs"], "create", "", "pods", "team-a", "")) + "/" + string(can("alice", ["devs"], "create", "", "pods", "team-a", ""))`,
+			expected: "true/true",
+		},
+		{
+			name:     "starlark",
+			engine:   EngineStarlark,
+			template: `print(can("alice", ["devs"], "create", "", "pods", "team-a"), can("alice", ["devs"], "create", "", "pods", "team-a"))`,
+			expected: "True True",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name+" caches reviews per request", func(t *testing.T) {
+			var reviews []authorizationv1.SubjectAccessReview
+			setTestAccessReviewClient(t, &reviews, nil)
+
+			injectedData := &PolicyEvaluationDataT{}
+			injectedData.Initialize()
+
+			result, err := EvaluateTemplate(test.engine, test.template, injectedData)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result != test.expected {
+				t.Errorf("unexpected result: got '%s', want '%s'", result, test.expected)
+			}
+
+			// Both calls are served by a single review, and so are the ones of later templates of the same request
+			_, _ = EvaluateTemplate(test.engine, test.template, injectedData)
+			if len(reviews) != 1 {
+				t.Errorf("expected a single review for the request, got %d", len(reviews))
+			}
+
+			// Other requests ask again
+			otherInjectedData := &PolicyEvaluationDataT{}
+			otherInjectedData.Initialize()
+			_, _ = EvaluateTemplate(test.engine, test.template, otherInjectedData)
+			if len(reviews) != 2 {
+				t.Errorf("expected another review for another request, got %d", len(reviews))
+			}
+		})
+
+		t.Run(test.name+" reports failed reviews", func(t *testing.T) {
+			var reviews []authorizationv1.SubjectAccessReview
+			setTestAccessReviewClient(t, &reviews, errors.New("authorizer unavailable"))
+
+			injectedData := &PolicyEvaluationDataT{}
+			injectedData.Initialize()
+
+			_, err := EvaluateTemplate(test.engine, test.template, injectedData)
+			if err == nil || !strings.Contains(err.Error(), "failed creating SubjectAccessReview: authorizer unavailable") {
+				t.Errorf("expected error about the review, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestCanAccessRequester(t *testing.T) {
+	var reviews []authorizationv1.SubjectAccessReview
+	setTestAccessReviewClient(t, &reviews, nil)
+
+	injectedData := &PolicyEvaluationDataT{}
+	injectedData.Initialize()
+	injectedData.GetRequestState().Requester = &authenticationv1.UserInfo{
+		Username: "alice",
+		UID:      "1234",
+		Extra:    map[string]authenticationv1.ExtraValue{"scopes": {"a"}},
+	}
+
+	_, err := canAccess(injectedData, "alice", []string{"devs"}, "create", "", "pods", "team-a", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = canAccess(injectedData, "bob", []string{"devs"}, "create", "", "pods", "team-a", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(reviews) != 2 {
+		t.Fatalf("expected two reviews, got %d", len(reviews))
+	}
+
+	// The rest of the data of the requester is only sent when asking about it
+	if reviews[0].Spec.UID != "1234" || strings.Join(reviews[0].Spec.Extra["scopes"], ",") != "a" {
+		t.Errorf("expected requester data in the review, got %+v", reviews[0].Spec)
+	}
+	if reviews[1].Spec.UID != "" || len(reviews[1].Spec.Extra) != 0 {
+		t.Errorf("expected no requester data in the review, got %+v", reviews[1].Spec)
+	}
+}
+
+func TestCanAccessUnavailable(t *testing.T) {
+	previousClient := globals.Application.AccessReviewClient
+	globals.Application.AccessReviewClient = nil
+	t.Cleanup(func() {
+		globals.Application.AccessReviewClient = previousClient
+	})
+
+	injectedData := &PolicyEvaluationDataT{}
+	injectedData.Initialize()
+
+	_, err := EvaluateTemplate(EngineGotmpl, `{{ can "alice" (list) "create" "" "pods" "team-a" "" }}`, injectedData)
+	if err == nil || !strings.Contains(err.Error(), "access reviews from templates are not available") {
+		t.Errorf("expected error about unavailable reviews, got: %v", err)
+	}
+}
@@ -18,11 +18,15 @@ package template
 
 import (
 	"fmt"
+	"reflect"
 	"regexp"
 	"strings"
 
 	//
 	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 var (
@@ -32,42 +36,192 @@ var (
 
 func EvaluateTemplateCel(template string, injectedData InjectedDataI) (result string, err error) {
 
-	injectedDataMap := injectedData.ToMap()
+	out, err := evaluateCel(template, injectedData)
+	if err != nil {
+		return "", err
+	}
 
-	// Vars can not be used in CEL to guarantee when they are used, you can store things inside
-	delete(injectedDataMap, "vars")
+	result = fmt.Sprintf("%v", out.Value())
 
-	envOptions := []cel.EnvOption{}
-	for key := range injectedDataMap {
-		envOptions = append(envOptions, cel.Variable(key, cel.DynType))
+	return result, nil
+}
+
+// EvaluateExpressionCel evaluates a CEL expression and returns its result as a Golang native value.
+// Returned values are JSON compatible: maps, lists, strings, numbers, bools or nil
+func EvaluateExpressionCel(expression string, injectedData InjectedDataI) (result any, err error) {
+
+	out, err := evaluateCel(expression, injectedData)
+	if err != nil {
+		return nil, err
 	}
 
-	env, err := cel.NewEnv(envOptions...)
+	nativeValue, err := out.ConvertToNative(reflect.TypeOf(&structpb.Value{}))
 	if err != nil {
-		return "", fmt.Errorf("environment creation error: %s", err.Error())
+		return nil, fmt.Errorf("result conversion error: %s", err.Error())
 	}
 
+	return nativeValue.(*structpb.Value).AsInterface(), nil
+}
+
+// evaluateCel compiles and executes a CEL expression using injected data as variables
+func evaluateCel(expression string, injectedData InjectedDataI) (out ref.Val, err error) {
+
+	injectedDataMap := injectedData.ToMap()
+
 	// Compile and execute the code
-	ast, issues := env.Compile(template)
-	if issues != nil && issues.Err() != nil {
-		return "", fmt.Errorf("type-check error: %s", issues.Err())
+	env, ast, err := compileCel(expression, injectedData)
+	if err != nil {
+		return nil, err
 	}
 
 	prg, err := env.Program(ast)
 	if err != nil {
-		return "", fmt.Errorf("program construction error: %s", err.Error())
+		return nil, fmt.Errorf("program construction error: %s", err.Error())
 	}
 
 	// The `out` var contains the output of a successful evaluation.
-	out, _, err := prg.Eval(injectedDataMap)
+	out, _, err = prg.Eval(injectedDataMap)
+	if err != nil {
+		return nil, fmt.Errorf("program evaluation error: %s", err.Error())
+	}
 
+	return out, nil
+}
+
+// compileCel compiles a CEL expression in an environment with the injected data declared as variables
+func compileCel(expression string, injectedData InjectedDataI) (env *cel.Env, ast *cel.Ast, err error) {
+
+	envOptions := []cel.EnvOption{}
+	for key := range injectedData.ToMap() {
+		envOptions = append(envOptions, cel.Variable(key, cel.DynType))
+	}
+	envOptions = append(envOptions, getCelFunctions(injectedData)...)
+
+	mutationOptions, err := getCelMutationOptions()
 	if err != nil {
-		return "", fmt.Errorf("program evaluation error: %s", err.Error())
+		return nil, nil, fmt.Errorf("environment creation error: %s", err.Error())
 	}
+	envOptions = append(envOptions, mutationOptions...)
 
-	result = fmt.Sprintf("%v", out.Value())
+	env, err = cel.NewEnv(envOptions...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("environment creation error: %s", err.Error())
+	}
 
-	return result, nil
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, nil, fmt.Errorf("type-check error: %s", issues.Err())
+	}
+
+	return env, ast, nil
+}
+
+// getCelFunctions return the functions added to CEL environment.
+// They are built per evaluation, as some of them need to access the state of the request
+func getCelFunctions(injectedData InjectedDataI) []cel.EnvOption {
+	// Changes related functions: changed(path), added(path), removed(path)
+	pathFunction := func(name string, function func(InjectedDataI, string) bool) cel.EnvOption {
+		return cel.Function(name,
+			cel.Overload(name+"_string", []*cel.Type{cel.StringType}, cel.BoolType,
+				cel.UnaryBinding(func(arg ref.Val) ref.Val {
+					path, ok := arg.Value().(string)
+					if !ok {
+						return types.NewErr("%s: path must be a string", name)
+					}
+					return types.Bool(function(injectedData, path))
+				}),
+			),
+		)
+	}
+
+	functions := []cel.EnvOption{
+		pathFunction("changed", isPathChanged),
+		pathFunction("added", isPathAdded),
+		pathFunction("removed", isPathRemoved),
+
+		// can(user, groups, verb, group, resource, namespace, name)
+		cel.Function("can",
+			cel.Overload("can_string_list_string_string_string_string_string",
+				[]*cel.Type{cel.StringType, cel.ListType(cel.StringType),
+					cel.StringType, cel.StringType, cel.StringType, cel.StringType, cel.StringType},
+				cel.BoolType,
+				cel.FunctionBinding(func(args ...ref.Val) ref.Val {
+					groups, err := args[1].ConvertToNative(reflect.TypeOf([]string{}))
+					if err != nil {
+						return types.NewErr("can: %s", err.Error())
+					}
+
+					stringArgs := make([]string, 0, len(args))
+					for _, arg := range args {
+						stringArg, _ := arg.Value().(string)
+						stringArgs = append(stringArgs, stringArg)
+					}
+
+					allowed, err := canAccess(injectedData, stringArgs[0], groups.([]string),
+						stringArgs[2], stringArgs[3], stringArgs[4], stringArgs[5], stringArgs[6])
+					if err != nil {
+						return types.NewErr("can: %s", err.Error())
+					}
+					return types.Bool(allowed)
+				}),
+			),
+		),
+	}
+
+	return append(functions, getCelHelperFunctions()...)
+}
+
+// GetCelFunctionNames return the names of the functions added by Admitik to CEL environment.
+// They are not available outside Admitik, such as in Kubernetes ValidatingAdmissionPolicy objects
+func GetCelFunctionNames() []string {
+	names := []string{"changed", "added", "removed", "can", "jsonpatch.escapeKey"}
+	for _, helper := range helperFunctions {
+		names = append(names, helper.Name)
+	}
+	return names
+}
+
+// getCelHelperFunctions return the helpers shared with the rest of the engines, adapted to CEL
+func getCelHelperFunctions() []cel.EnvOption {
+	celTypes := map[string]*cel.Type{
+		helperTypeString: cel.StringType,
+		helperTypeInt:    cel.IntType,
+		helperTypeBool:   cel.BoolType,
+		helperTypeMap:    cel.MapType(cel.StringType, cel.DynType),
+		helperTypeAny:    cel.DynType,
+	}
+
+	functions := make([]cel.EnvOption, 0, len(helperFunctions))
+	for _, helper := range helperFunctions {
+		argTypes := make([]*cel.Type, 0, len(helper.ArgTypes))
+		for _, argType := range helper.ArgTypes {
+			argTypes = append(argTypes, celTypes[argType])
+		}
+
+		function := helper.Function
+		functions = append(functions, cel.Function(helper.Name,
+			cel.Overload(helper.Name+"_"+strings.Join(helper.ArgTypes, "_"), argTypes, celTypes[helper.ResultType],
+				cel.FunctionBinding(func(args ...ref.Val) ref.Val {
+					nativeArgs := make([]any, 0, len(args))
+					for _, arg := range args {
+						nativeArg, err := arg.ConvertToNative(reflect.TypeOf(&structpb.Value{}))
+						if err != nil {
+							return types.NewErr("%s: %s", helper.Name, err.Error())
+						}
+						nativeArgs = append(nativeArgs, nativeArg.(*structpb.Value).AsInterface())
+					}
+
+					result, err := function(nativeArgs...)
+					if err != nil {
+						return types.NewErr("%s", err.Error())
+					}
+					return types.DefaultTypeAdapter.NativeToValue(result)
+				}),
+			),
+		))
+	}
+
+	return functions
 }
 
 // EvaluateAndReplaceCelExpressions finds {{cel: ... }} patterns and evaluates each using EvaluateTemplateCel
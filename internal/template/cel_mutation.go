@@ -0,0 +1,113 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	"strings"
+
+	//
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+)
+
+const (
+	// celObjectTypeName represents the root type used to express apply configurations, as in MutatingAdmissionPolicy.
+	// Nested types are expressed as paths to the field, such as: Object.spec.template{...}
+	celObjectTypeName = "Object"
+
+	// celJsonPatchTypeName represents the type used to express JSON Patch operations, as in MutatingAdmissionPolicy
+	celJsonPatchTypeName = "JSONPatch"
+)
+
+// celMutationTypeProvider extends CEL default types with the ones used by MutatingAdmissionPolicy to express mutations.
+// Fields of these types are not checked against the schemas of the resources, they are dynamic.
+// Values are built as maps, so they are converted into JSON as any other map
+type celMutationTypeProvider struct {
+	*types.Registry
+}
+
+// isMutationType return whether a type name is one of the types used to express mutations
+func (p *celMutationTypeProvider) isMutationType(structType string) bool {
+	return structType == celObjectTypeName || structType == celJsonPatchTypeName ||
+		strings.HasPrefix(structType, celObjectTypeName+".")
+}
+
+// FindStructType implements types.Provider
+func (p *celMutationTypeProvider) FindStructType(structType string) (*types.Type, bool) {
+	if p.isMutationType(structType) {
+		return types.NewTypeTypeWithParam(types.NewObjectType(structType)), true
+	}
+	return p.Registry.FindStructType(structType)
+}
+
+// FindStructFieldNames implements types.Provider
+func (p *celMutationTypeProvider) FindStructFieldNames(structType string) ([]string, bool) {
+	if p.isMutationType(structType) {
+		return []string{}, true
+	}
+	return p.Registry.FindStructFieldNames(structType)
+}
+
+// FindStructFieldType implements types.Provider
+func (p *celMutationTypeProvider) FindStructFieldType(structType, fieldName string) (*types.FieldType, bool) {
+	if p.isMutationType(structType) {
+		return &types.FieldType{Type: types.DynType}, true
+	}
+	return p.Registry.FindStructFieldType(structType, fieldName)
+}
+
+// NewValue implements types.Provider
+func (p *celMutationTypeProvider) NewValue(structType string, fields map[string]ref.Val) ref.Val {
+	if !p.isMutationType(structType) {
+		return p.Registry.NewValue(structType, fields)
+	}
+
+	entries := make(map[ref.Val]ref.Val, len(fields))
+	for fieldName, fieldValue := range fields {
+		entries[types.String(fieldName)] = fieldValue
+	}
+	return types.NewRefValMap(p, entries)
+}
+
+// getCelMutationOptions return the options to express mutations in CEL as MutatingAdmissionPolicy does:
+// Object{...} and JSONPatch{...} types, and jsonpatch.escapeKey(key) function
+func getCelMutationOptions() ([]cel.EnvOption, error) {
+	registry, err := types.NewRegistry()
+	if err != nil {
+		return nil, err
+	}
+	provider := &celMutationTypeProvider{Registry: registry}
+
+	return []cel.EnvOption{
+		cel.CustomTypeProvider(provider),
+		cel.CustomTypeAdapter(provider),
+
+		// jsonpatch.escapeKey(key) escapes a key to be used in a JSON Pointer
+		cel.Function("jsonpatch.escapeKey",
+			cel.Overload("jsonpatch_escapeKey_string", []*cel.Type{cel.StringType}, cel.StringType,
+				cel.UnaryBinding(func(arg ref.Val) ref.Val {
+					key, ok := arg.Value().(string)
+					if !ok {
+						return types.NewErr("jsonpatch.escapeKey: key must be a string")
+					}
+					return types.String(strings.NewReplacer("~", "~0", "/", "~1").Replace(key))
+				}),
+			),
+		),
+	}, nil
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	//
+	"github.com/wI2L/jsondiff"
+)
+
+// PopulateChanges computes the differences between 'oldObject' and 'object', storing them into 'changes'.
+// Each change follows JSON Patch format: {op, path, value, oldValue}, where paths are JSON Pointers.
+// It is intended to be called only on updates: once per request, and again each time the object is patched
+func (ida *TriggerInjectedDataT) PopulateChanges() error {
+
+	patch, err := jsondiff.Compare(ida.OldObject, ida.Object)
+	if err != nil {
+		return fmt.Errorf("failed comparing 'oldObject' and 'object': %s", err.Error())
+	}
+
+	ida.Changes = make([]any, 0, len(patch))
+	for _, operation := range patch {
+		change := map[string]any{
+			"op":   operation.Type,
+			"path": operation.Path,
+		}
+
+		if operation.Type == jsondiff.OperationAdd || operation.Type == jsondiff.OperationReplace {
+			change["value"] = operation.Value
+		}
+		if operation.Type == jsondiff.OperationRemove || operation.Type == jsondiff.OperationReplace {
+			change["oldValue"] = operation.OldValue
+		}
+
+		ida.Changes = append(ida.Changes, change)
+	}
+
+	return nil
+}
+
+// isPathChanged return true when the value under a JSON Pointer is different between 'oldObject' and 'object'.
+// Changes of nested values are considered changes of their parents too
+func isPathChanged(injectedData InjectedDataI, path string) bool {
+	injectedDataMap := injectedData.ToMap()
+	changes, _ := injectedDataMap["changes"].([]any)
+
+	for _, change := range changes {
+		changePath, _ := change.(map[string]any)["path"].(string)
+
+		// Items appended to arrays have '-' as index, so the whole array is compared instead
+		if arrayPath, _, isAppend := strings.Cut(changePath+"/", "/-/"); isAppend {
+			changePath = arrayPath
+		}
+
+		// The path itself or something below it changed
+		if changePath == path || strings.HasPrefix(changePath, path+"/") {
+			return true
+		}
+
+		// Something above the path changed, so the values must be compared
+		if changePath == "" || strings.HasPrefix(path, changePath+"/") {
+			oldValue, oldFound := resolvePointer(injectedDataMap["oldObject"], path)
+			newValue, newFound := resolvePointer(injectedDataMap["object"], path)
+			if oldFound != newFound || !reflect.DeepEqual(oldValue, newValue) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// isPathAdded return true when the JSON Pointer exists in 'object', but not in 'oldObject'
+func isPathAdded(injectedData InjectedDataI, path string) bool {
+	if !isPathChanged(injectedData, path) {
+		return false
+	}
+
+	injectedDataMap := injectedData.ToMap()
+	_, oldFound := resolvePointer(injectedDataMap["oldObject"], path)
+	_, newFound := resolvePointer(injectedDataMap["object"], path)
+
+	return !oldFound && newFound
+}
+
+// isPathRemoved return true when the JSON Pointer exists in 'oldObject', but not in 'object'
+func isPathRemoved(injectedData InjectedDataI, path string) bool {
+	if !isPathChanged(injectedData, path) {
+		return false
+	}
+
+	injectedDataMap := injectedData.ToMap()
+	_, oldFound := resolvePointer(injectedDataMap["oldObject"], path)
+	_, newFound := resolvePointer(injectedDataMap["object"], path)
+
+	return oldFound && !newFound
+}
+
+// resolvePointer return the value under a JSON Pointer (RFC 6901) in a document
+func resolvePointer(document any, path string) (value any, found bool) {
+	if path == "" {
+		return document, true
+	}
+
+	if !strings.HasPrefix(path, "/") {
+		return nil, false
+	}
+
+	value = document
+	for _, token := range strings.Split(path[1:], "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+
+		switch typedValue := value.(type) {
+		case map[string]any:
+			value, found = typedValue[token]
+			if !found {
+				return nil, false
+			}
+		case []any:
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(typedValue) {
+				return nil, false
+			}
+			value = typedValue[index]
+		default:
+			return nil, false
+		}
+	}
+
+	return value, true
+}
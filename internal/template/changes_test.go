@@ -0,0 +1,303 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// getTestChangesInjectedData return the injected data of an update from 'oldObject' to 'object', given as JSON.
+// Empty 'oldObject' represents a creation, where changes are not populated
+func getTestChangesInjectedData(t *testing.T, oldObject, object string) *PolicyEvaluationDataT {
+	injectedData := &PolicyEvaluationDataT{}
+	injectedData.Initialize()
+
+	err := json.Unmarshal([]byte(object), &injectedData.Object)
+	if err != nil {
+		t.Fatalf("failed decoding object: %s", err.Error())
+	}
+
+	if oldObject == "" {
+		return injectedData
+	}
+
+	err = json.Unmarshal([]byte(oldObject), &injectedData.OldObject)
+	if err != nil {
+		t.Fatalf("failed decoding old object: %s", err.Error())
+	}
+
+	err = injectedData.PopulateChanges()
+	if err != nil {
+		t.Fatalf("failed populating changes: %s", err.Error())
+	}
+
+	return injectedData
+}
+
+func TestPathChanges(t *testing.T) {
+	const baseObject = `{
+		"metadata": {"labels": {"team": "a", "example.com/owner": "alice", "tilde~key": "x"}},
+		"spec": {"replicas": 1, "containers": [{"name": "app", "image": "nginx:1"}, {"name": "sidecar", "image": "envoy:1"}]}
+	}`
+
+	tests := []struct {
+		name      string
+		oldObject string
+		object    string
+		path      string
+
+		expectedChanged bool
+		expectedAdded   bool
+		expectedRemoved bool
+	}{
+		{
+			name:      "unchanged value",
+			oldObject: baseObject,
+			object:    baseObject,
+			path:      "/spec/replicas",
+		},
+		{
+			name:            "replaced value",
+			oldObject:       baseObject,
+			object:          `{"metadata": {"labels": {"team": "a", "example.com/owner": "alice", "tilde~key": "x"}}, "spec": {"replicas": 2, "containers": [{"name": "app", "image": "nginx:1"}, {"name": "sidecar", "image": "envoy:1"}]}}`,
+			path:            "/spec/replicas",
+			expectedChanged: true,
+		},
+		{
+			name:            "parents of changed values are changed",
+			oldObject:       baseObject,
+			object:          `{"metadata": {"labels": {"team": "a", "example.com/owner": "alice", "tilde~key": "x"}}, "spec": {"replicas": 2, "containers": [{"name": "app", "image": "nginx:1"}, {"name": "sidecar", "image": "envoy:1"}]}}`,
+			path:            "/spec",
+			expectedChanged: true,
+		},
+		{
+			name:      "siblings of changed values are unchanged",
+			oldObject: baseObject,
+			object:    `{"metadata": {"labels": {"team": "a", "example.com/owner": "alice", "tilde~key": "x"}}, "spec": {"replicas": 2, "containers": [{"name": "app", "image": "nginx:1"}, {"name": "sidecar", "image": "envoy:1"}]}}`,
+			path:      "/spec/containers",
+		},
+		{
+			name:            "added value",
+			oldObject:       baseObject,
+			object:          `{"metadata": {"labels": {"team": "a", "example.com/owner": "alice", "tilde~key": "x", "tier": "back"}}, "spec": {"replicas": 1, "containers": [{"name": "app", "image": "nginx:1"}, {"name": "sidecar", "image": "envoy:1"}]}}`,
+			path:            "/metadata/labels/tier",
+			expectedChanged: true,
+			expectedAdded:   true,
+		},
+		{
+			name:            "children of added values are added",
+			oldObject:       `{"metadata": {"name": "example"}}`,
+			object:          `{"metadata": {"name": "example", "labels": {"team": "a"}}}`,
+			path:            "/metadata/labels/team",
+			expectedChanged: true,
+			expectedAdded:   true,
+		},
+		{
+			name:            "removed value",
+			oldObject:       baseObject,
+			object:          `{"metadata": {"labels": {"example.com/owner": "alice", "tilde~key": "x"}}, "spec": {"replicas": 1, "containers": [{"name": "app", "image": "nginx:1"}, {"name": "sidecar", "image": "envoy:1"}]}}`,
+			path:            "/metadata/labels/team",
+			expectedChanged: true,
+			expectedRemoved: true,
+		},
+		{
+			name:            "key escaped with ~1",
+			oldObject:       baseObject,
+			object:          `{"metadata": {"labels": {"team": "a", "example.com/owner": "bob", "tilde~key": "x"}}, "spec": {"replicas": 1, "containers": [{"name": "app", "image": "nginx:1"}, {"name": "sidecar", "image": "envoy:1"}]}}`,
+			path:            "/metadata/labels/example.com~1owner",
+			expectedChanged: true,
+		},
+		{
+			name:            "key escaped with ~0",
+			oldObject:       baseObject,
+			object:          `{"metadata": {"labels": {"team": "a", "example.com/owner": "alice"}}, "spec": {"replicas": 1, "containers": [{"name": "app", "image": "nginx:1"}, {"name": "sidecar", "image": "envoy:1"}]}}`,
+			path:            "/metadata/labels/tilde~0key",
+			expectedChanged: true,
+			expectedRemoved: true,
+		},
+		{
+			name:      "unescaped key is not resolved",
+			oldObject: baseObject,
+			object:    `{"metadata": {"labels": {"team": "a", "example.com/owner": "bob", "tilde~key": "x"}}, "spec": {"replicas": 1, "containers": [{"name": "app", "image": "nginx:1"}, {"name": "sidecar", "image": "envoy:1"}]}}`,
+			path:      "/metadata/labels/example.com/owner",
+		},
+		{
+			name:            "changed array item",
+			oldObject:       baseObject,
+			object:          `{"metadata": {"labels": {"team": "a", "example.com/owner": "alice", "tilde~key": "x"}}, "spec": {"replicas": 1, "containers": [{"name": "app", "image": "nginx:2"}, {"name": "sidecar", "image": "envoy:1"}]}}`,
+			path:            "/spec/containers/0/image",
+			expectedChanged: true,
+		},
+		{
+			name:      "other array items are unchanged",
+			oldObject: baseObject,
+			object:    `{"metadata": {"labels": {"team": "a", "example.com/owner": "alice", "tilde~key": "x"}}, "spec": {"replicas": 1, "containers": [{"name": "app", "image": "nginx:2"}, {"name": "sidecar", "image": "envoy:1"}]}}`,
+			path:      "/spec/containers/1",
+		},
+		{
+			name:            "item appended to an array",
+			oldObject:       baseObject,
+			object:          `{"metadata": {"labels": {"team": "a", "example.com/owner": "alice", "tilde~key": "x"}}, "spec": {"replicas": 1, "containers": [{"name": "app", "image": "nginx:1"}, {"name": "sidecar", "image": "envoy:1"}, {"name": "debug", "image": "busybox"}]}}`,
+			path:            "/spec/containers/2",
+			expectedChanged: true,
+			expectedAdded:   true,
+		},
+		{
+			name:            "array with an appended item",
+			oldObject:       baseObject,
+			object:          `{"metadata": {"labels": {"team": "a", "example.com/owner": "alice", "tilde~key": "x"}}, "spec": {"replicas": 1, "containers": [{"name": "app", "image": "nginx:1"}, {"name": "sidecar", "image": "envoy:1"}, {"name": "debug", "image": "busybox"}]}}`,
+			path:            "/spec/containers",
+			expectedChanged: true,
+		},
+		{
+			name:      "items before the appended one are unchanged",
+			oldObject: baseObject,
+			object:    `{"metadata": {"labels": {"team": "a", "example.com/owner": "alice", "tilde~key": "x"}}, "spec": {"replicas": 1, "containers": [{"name": "app", "image": "nginx:1"}, {"name": "sidecar", "image": "envoy:1"}, {"name": "debug", "image": "busybox"}]}}`,
+			path:      "/spec/containers/0",
+		},
+		{
+			name:            "item removed from an array",
+			oldObject:       baseObject,
+			object:          `{"metadata": {"labels": {"team": "a", "example.com/owner": "alice", "tilde~key": "x"}}, "spec": {"replicas": 1, "containers": [{"name": "app", "image": "nginx:1"}]}}`,
+			path:            "/spec/containers/1",
+			expectedChanged: true,
+			expectedRemoved: true,
+		},
+		{
+			name:            "whole object is changed by any change",
+			oldObject:       baseObject,
+			object:          `{"metadata": {"labels": {"team": "b", "example.com/owner": "alice", "tilde~key": "x"}}, "spec": {"replicas": 1, "containers": [{"name": "app", "image": "nginx:1"}, {"name": "sidecar", "image": "envoy:1"}]}}`,
+			path:            "",
+			expectedChanged: true,
+		},
+		{
+			name:   "nothing changes on creation",
+			object: baseObject,
+			path:   "/spec/replicas",
+		},
+		{
+			name:   "nothing is added on creation",
+			object: baseObject,
+			path:   "/metadata/labels/team",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			injectedData := getTestChangesInjectedData(t, test.oldObject, test.object)
+
+			if result := isPathChanged(injectedData, test.path); result != test.expectedChanged {
+				t.Errorf("expected changed to be %v, got %v", test.expectedChanged, result)
+			}
+			if result := isPathAdded(injectedData, test.path); result != test.expectedAdded {
+				t.Errorf("expected added to be %v, got %v", test.expectedAdded, result)
+			}
+			if result := isPathRemoved(injectedData, test.path); result != test.expectedRemoved {
+				t.Errorf("expected removed to be %v, got %v", test.expectedRemoved, result)
+			}
+		})
+	}
+}
+
+func TestResolvePointer(t *testing.T) {
+	document := map[string]any{
+		"metadata": map[string]any{
+			"labels": map[string]any{"example.com/owner": "alice", "tilde~key": "x", "": "empty"},
+		},
+		"spec": map[string]any{
+			"containers": []any{
+				map[string]any{"name": "app"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		path          string
+		expected      any
+		expectedFound bool
+	}{
+		{
+			name:          "empty pointer is the whole document",
+			path:          "",
+			expected:      document,
+			expectedFound: true,
+		},
+		{
+			name:          "key escaped with ~1",
+			path:          "/metadata/labels/example.com~1owner",
+			expected:      "alice",
+			expectedFound: true,
+		},
+		{
+			name:          "key escaped with ~0",
+			path:          "/metadata/labels/tilde~0key",
+			expected:      "x",
+			expectedFound: true,
+		},
+		{
+			name:          "empty key",
+			path:          "/metadata/labels/",
+			expected:      "empty",
+			expectedFound: true,
+		},
+		{
+			name:          "array index",
+			path:          "/spec/containers/0/name",
+			expected:      "app",
+			expectedFound: true,
+		},
+		{
+			name: "array index out of range",
+			path: "/spec/containers/1",
+		},
+		{
+			name: "negative array index",
+			path: "/spec/containers/-1",
+		},
+		{
+			name: "end of array marker",
+			path: "/spec/containers/-",
+		},
+		{
+			name: "key inside a string",
+			path: "/metadata/labels/tilde~0key/name",
+		},
+		{
+			name: "missing key",
+			path: "/metadata/annotations",
+		},
+		{
+			name: "pointer without leading slash",
+			path: "metadata",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			value, found := resolvePointer(document, test.path)
+
+			if found != test.expectedFound {
+				t.Fatalf("expected found to be %v, got %v", test.expectedFound, found)
+			}
+			if found && test.path != "" && value != test.expected {
+				t.Errorf("expected value %v, got %v", test.expected, value)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	//
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/cuecontext"
+	cueerrors "cuelang.org/go/cue/errors"
+)
+
+const (
+	// cuePatchField is the top-level field exported as the result when the template defines it
+	cuePatchField = "patch"
+
+	// cueUnificationSucceeded is printed when the injected data meets the template
+	cueUnificationSucceeded = "true"
+)
+
+// EvaluateTemplateCue unifies a CUE template with the injected data.
+// Injected data is available under the same keys as other engines (object, oldObject, operation, sources, vars),
+// so the template can constrain them directly, and reference them even without declaring them.
+// When the template defines a top-level 'patch' field, its concrete value (defaults applied) is printed as JSON,
+// failing when the injected data doesn't meet the rest of the template.
+// Otherwise, 'true' is printed when unification succeeds, or the unification errors, one per line, when it fails
+func EvaluateTemplateCue(template string, injectedData InjectedDataI) (result string, err error) {
+
+	cueCtx := cuecontext.New()
+
+	inputValue, err := getCueInjectedData(cueCtx, injectedData)
+	if err != nil {
+		return result, err
+	}
+
+	templateValue := cueCtx.CompileString(template, cue.Filename("template.cue"), cue.Scope(inputValue))
+	if templateValue.Err() != nil {
+		return result, fmt.Errorf("error compiling CUE template: %s", templateValue.Err().Error())
+	}
+
+	unifiedValue := templateValue.Unify(inputValue)
+
+	patchValue := unifiedValue.LookupPath(cue.ParsePath(cuePatchField))
+
+	// Unification errors anywhere make patches unreliable, so the whole value is validated before exporting them
+	err = unifiedValue.Validate()
+	if err != nil {
+		if !patchValue.Exists() {
+			return getCueErrorsString(err, "\n"), nil
+		}
+		return result, fmt.Errorf("error evaluating CUE template: %s", getCueErrorsString(err, ", "))
+	}
+
+	if !patchValue.Exists() {
+		return cueUnificationSucceeded, nil
+	}
+
+	// Patches are exported only when they are concrete
+	err = patchValue.Validate(cue.Concrete(true))
+	if err != nil {
+		return result, fmt.Errorf("error evaluating CUE patch: %s", getCueErrorsString(err, ", "))
+	}
+
+	patchBytes, err := patchValue.MarshalJSON()
+	if err != nil {
+		return result, fmt.Errorf("error converting CUE patch into JSON: %s", err.Error())
+	}
+
+	return string(patchBytes), nil
+}
+
+// getCueInjectedData return the injected data as a CUE value.
+// It's compiled from JSON, as JSON is valid CUE, so integers are kept as integers instead of floats
+func getCueInjectedData(cueCtx *cue.Context, injectedData InjectedDataI) (cue.Value, error) {
+	injectedDataBytes, err := json.Marshal(injectedData.ToMap())
+	if err != nil {
+		return cue.Value{}, fmt.Errorf("error converting injected data into JSON: %s", err.Error())
+	}
+
+	inputValue := cueCtx.CompileBytes(injectedDataBytes, cue.Filename("input.json"))
+	if inputValue.Err() != nil {
+		return cue.Value{}, fmt.Errorf("error converting injected data into CUE: %s", inputValue.Err().Error())
+	}
+
+	return inputValue, nil
+}
+
+// getCueErrorsString return the errors of a CUE evaluation joined by the given separator
+func getCueErrorsString(err error, separator string) string {
+	errorList := []string{}
+	for _, cueErr := range cueerrors.Errors(err) {
+		errorList = append(errorList, cueErr.Error())
+	}
+	return strings.Join(errorList, separator)
+}
+
+// validateCue compiles a CUE template with the keys of the injected data in scope, as done on evaluation
+func validateCue(template string, injectedData InjectedDataI) error {
+	cueCtx := cuecontext.New()
+
+	inputValue, err := getCueInjectedData(cueCtx, injectedData)
+	if err != nil {
+		return err
+	}
+
+	templateValue := cueCtx.CompileString(template, cue.Filename("template.cue"), cue.Scope(inputValue))
+	if templateValue.Err() != nil {
+		return fmt.Errorf("error compiling CUE template: %s", templateValue.Err().Error())
+	}
+
+	return nil
+}
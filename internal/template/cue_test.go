@@ -0,0 +1,118 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	"testing"
+)
+
+// getTestCueObject return the default object of the engine tests with the given replicas and image
+func getTestCueObject(replicas int, image string) map[string]any {
+	object := getTestEngineObject()
+	object["spec"] = map[string]any{
+		"replicas": replicas,
+		"template": map[string]any{
+			"spec": map[string]any{
+				"containers": []any{
+					map[string]any{"name": "app", "image": image},
+				},
+			},
+		},
+	}
+	return object
+}
+
+func TestEvaluateTemplateCue(t *testing.T) {
+	const deploymentSchema = `
+object: spec: {
+  replicas?: int & >=1 & <=10
+  template: spec: containers: [...{
+    image: =~"^registry\\.example\\.com/"
+  }]
+}
+`
+
+	runTemplateEngineTests(t, EvaluateTemplateCue, []templateEngineTestT{
+		{
+			name:     "successful unification prints true",
+			template: deploymentSchema,
+			expected: "true",
+		},
+		{
+			name:     "unification errors are printed",
+			template: `object: spec: replicas?: int & >=1 & <=10`,
+			object:   getTestCueObject(20, "registry.example.com/nginx"),
+			expected: "object.spec.replicas: invalid value 20 (out of bound <=10)",
+		},
+		{
+			name:     "unification errors are printed one per line",
+			template: deploymentSchema,
+			object:   getTestCueObject(20, "docker.io/nginx"),
+			expected: "object.spec.replicas: invalid value 20 (out of bound <=10)\n" +
+				`object.spec.template.spec.containers.0.image: invalid value "docker.io/nginx" (out of bound =~"^registry\\.example\\.com/")`,
+		},
+		{
+			name:     "non concrete fields do not fail unification",
+			template: `extra: int`,
+			expected: "true",
+		},
+		{
+			name: "patch field is printed as JSON with defaults applied",
+			template: `
+#Defaults: {
+  replicas:             *2 | int
+  revisionHistoryLimit: *3 | int
+}
+patch: spec: #Defaults & {
+  replicas: object.spec.replicas
+}
+`,
+			expected: `{"spec":{"replicas":3,"revisionHistoryLimit":3}}`,
+		},
+		{
+			name:     "injected data can be referenced without declaring it",
+			template: `patch: metadata: annotations: owner: vars.owner`,
+			expected: `{"metadata":{"annotations":{"owner":"someone"}}}`,
+		},
+		{
+			name:     "patch is exported when non concrete fields exist outside of it",
+			template: "extra: int\npatch: metadata: annotations: owner: vars.owner",
+			expected: `{"metadata":{"annotations":{"owner":"someone"}}}`,
+		},
+		{
+			name:        "patch is not exported when the rest of the template is not met",
+			template:    deploymentSchema + "patch: metadata: annotations: owner: vars.owner",
+			object:      getTestCueObject(20, "registry.example.com/nginx"),
+			expectedErr: "error evaluating CUE template: object.spec.replicas: invalid value 20",
+		},
+		{
+			name:        "non concrete patches are reported",
+			template:    `patch: spec: replicas: int`,
+			expectedErr: "error evaluating CUE patch",
+		},
+		{
+			name:        "patches with non concrete defaults are reported",
+			template:    `patch: spec: replicas: *object.spec.missing | int`,
+			expectedErr: "error evaluating CUE patch",
+		},
+		{
+			name:        "compilation errors are reported",
+			template:    `object: spec: {`,
+			expectedErr: "error compiling CUE template",
+		},
+	})
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// FixturesT represents the data returned by the functions that reach Kubernetes, such as 'lookup' or 'can'.
+// They are used during policy tests, so results do not depend on the state of the cluster
+type FixturesT struct {
+	// Objects are returned by lookup functions
+	Objects []map[string]any
+
+	// AccessReviews are the answers given by 'can' functions. Actions not listed are denied
+	AccessReviews []AccessReviewFixtureT
+}
+
+// AccessReviewFixtureT represents the answer to an action. Empty user matches any user
+type AccessReviewFixtureT struct {
+	User      string
+	Verb      string
+	Group     string
+	Resource  string
+	Namespace string
+	Name      string
+	Allowed   bool
+}
+
+// getObject return an object from the fixtures, or an empty map when it's not found, as lookup does
+func (f *FixturesT) getObject(apiVersion, kind, namespace, name string) map[string]any {
+	for _, object := range f.Objects {
+		typedObject := unstructured.Unstructured{Object: object}
+
+		if !isFixtureOfType(&typedObject, apiVersion, kind) || typedObject.GetName() != name {
+			continue
+		}
+
+		// Cluster scoped objects have no namespace, so they match any
+		if typedObject.GetNamespace() != "" && typedObject.GetNamespace() != namespace {
+			continue
+		}
+
+		return object
+	}
+
+	return map[string]any{}
+}
+
+// listObjects return a List object containing the fixtures matching the filters under 'items'.
+// Empty namespace means all the namespaces
+func (f *FixturesT) listObjects(apiVersion, kind, namespace, labelSelector string) (map[string]any, error) {
+	selector, err := labels.Parse(labelSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	items := []any{}
+	for _, object := range f.Objects {
+		typedObject := unstructured.Unstructured{Object: object}
+
+		if !isFixtureOfType(&typedObject, apiVersion, kind) {
+			continue
+		}
+
+		if namespace != "" && typedObject.GetNamespace() != "" && typedObject.GetNamespace() != namespace {
+			continue
+		}
+
+		if !selector.Matches(labels.Set(typedObject.GetLabels())) {
+			continue
+		}
+
+		items = append(items, object)
+	}
+
+	return map[string]any{
+		"apiVersion": apiVersion,
+		"kind":       kind + "List",
+		"items":      items,
+	}, nil
+}
+
+// canAccess return the answer of the first fixture matching the action
+func (f *FixturesT) canAccess(user, verb, group, resource, namespace, name string) bool {
+	for _, review := range f.AccessReviews {
+		if review.User != "" && review.User != user {
+			continue
+		}
+
+		if review.Verb == verb && review.Group == group && review.Resource == resource &&
+			review.Namespace == namespace && review.Name == name {
+			return review.Allowed
+		}
+	}
+
+	return false
+}
+
+// isFixtureOfType checks whether an object has the given apiVersion and kind
+func isFixtureOfType(object *unstructured.Unstructured, apiVersion, kind string) bool {
+	return object.GetAPIVersion() == apiVersion && object.GetKind() == kind
+}
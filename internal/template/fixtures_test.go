@@ -0,0 +1,200 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	"testing"
+)
+
+func getTestFixturesInjectedData() *PolicyEvaluationDataT {
+	injectedData := &PolicyEvaluationDataT{}
+	injectedData.Initialize()
+	injectedData.GetRequestState().Fixtures = &FixturesT{
+		Objects: []map[string]any{
+			{
+				"apiVersion": "v1",
+				"kind":       "Namespace",
+				"metadata":   map[string]any{"name": "team-a", "labels": map[string]any{"owner": "team-a"}},
+			},
+			{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+				"metadata":   map[string]any{"name": "settings", "namespace": "team-a"},
+			},
+			{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+				"metadata":   map[string]any{"name": "settings", "namespace": "team-b", "labels": map[string]any{"shared": "true"}},
+			},
+		},
+		AccessReviews: []AccessReviewFixtureT{
+			{User: "alice", Verb: "create", Resource: "pods", Namespace: "team-a", Allowed: true},
+			{Verb: "get", Resource: "secrets", Namespace: "team-a", Allowed: true},
+		},
+	}
+	return injectedData
+}
+
+func TestLookupObjectFixtures(t *testing.T) {
+	tests := []struct {
+		name       string
+		apiVersion string
+		kind       string
+		namespace  string
+		objectName string
+
+		// expectedNames are the names of the returned objects. Lists return all of them
+		expectedNames []string
+	}{
+		{
+			name:          "cluster scoped object ignores the namespace",
+			apiVersion:    "v1",
+			kind:          "Namespace",
+			namespace:     "anything",
+			objectName:    "team-a",
+			expectedNames: []string{"team-a"},
+		},
+		{
+			name:          "namespaced object in another namespace is not found",
+			apiVersion:    "v1",
+			kind:          "ConfigMap",
+			namespace:     "team-c",
+			objectName:    "settings",
+			expectedNames: []string{},
+		},
+		{
+			name:          "list filters by namespace",
+			apiVersion:    "v1",
+			kind:          "ConfigMap",
+			namespace:     "team-b",
+			expectedNames: []string{"settings"},
+		},
+		{
+			name:          "list without namespace returns all of them",
+			apiVersion:    "v1",
+			kind:          "ConfigMap",
+			expectedNames: []string{"settings", "settings"},
+		},
+		{
+			name:          "objects of other versions are not found",
+			apiVersion:    "v2",
+			kind:          "Namespace",
+			objectName:    "team-a",
+			expectedNames: []string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := lookupObject(getTestFixturesInjectedData(), test.apiVersion, test.kind, test.namespace, test.objectName)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+
+			names := []string{}
+			if test.objectName == "" {
+				for _, item := range result["items"].([]any) {
+					names = append(names, item.(map[string]any)["metadata"].(map[string]any)["name"].(string))
+				}
+			} else if metadata, found := result["metadata"]; found {
+				names = append(names, metadata.(map[string]any)["name"].(string))
+			}
+
+			if len(names) != len(test.expectedNames) {
+				t.Fatalf("expected %v, got %v", test.expectedNames, names)
+			}
+			for i := range names {
+				if names[i] != test.expectedNames[i] {
+					t.Errorf("expected %v, got %v", test.expectedNames, names)
+				}
+			}
+		})
+	}
+}
+
+func TestLookupObjectListFixturesLabelSelector(t *testing.T) {
+	result, err := lookupObjectList(getTestFixturesInjectedData(), "v1", "ConfigMap", "", "shared=true")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	items := result["items"].([]any)
+	if len(items) != 1 {
+		t.Fatalf("expected 1 item, got %d", len(items))
+	}
+
+	_, err = lookupObjectList(getTestFixturesInjectedData(), "v1", "ConfigMap", "", "=broken")
+	if err == nil {
+		t.Errorf("expected an error for an invalid label selector")
+	}
+}
+
+func TestCanAccessFixtures(t *testing.T) {
+	tests := []struct {
+		name      string
+		user      string
+		verb      string
+		resource  string
+		namespace string
+		expected  bool
+	}{
+		{
+			name:      "listed action for the user is allowed",
+			user:      "alice",
+			verb:      "create",
+			resource:  "pods",
+			namespace: "team-a",
+			expected:  true,
+		},
+		{
+			name:      "listed action for another user is denied",
+			user:      "bob",
+			verb:      "create",
+			resource:  "pods",
+			namespace: "team-a",
+			expected:  false,
+		},
+		{
+			name:      "action listed without user is allowed for anyone",
+			user:      "bob",
+			verb:      "get",
+			resource:  "secrets",
+			namespace: "team-a",
+			expected:  true,
+		},
+		{
+			name:      "action not listed is denied",
+			user:      "alice",
+			verb:      "delete",
+			resource:  "pods",
+			namespace: "team-a",
+			expected:  false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := canAccess(getTestFixturesInjectedData(), test.user, nil, test.verb, "", test.resource, test.namespace, "")
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+			if result != test.expected {
+				t.Errorf("expected %v, got %v", test.expected, result)
+			}
+		})
+	}
+}
@@ -19,14 +19,36 @@ package template
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"log"
+	"slices"
 	"strings"
+	"sync"
 	"text/template"
 
 	//
 	"github.com/BurntSushi/toml"
 	"github.com/Masterminds/sprig/v3"
+	"golang.org/x/exp/maps"
 	"sigs.k8s.io/yaml"
+
+	//
+	"github.com/freepik-company/admitik/internal/globals"
+)
+
+const (
+	// includeMaxDepth limits the nested calls to 'include' to avoid infinite recursion
+	includeMaxDepth = 1000
+)
+
+var (
+	// libraryTemplatesCache stores Go templates coming from TemplateLibrary resources already parsed.
+	// It's rebuilt only when the registry revision changes, and cloned on each evaluation
+	libraryTemplatesCache = struct {
+		mu       sync.Mutex
+		revision uint64
+		set      *template.Template
+	}{}
 )
 
 // FOLKS, ATTENTION HERE:
@@ -44,11 +66,59 @@ func EvaluateTemplateGotmpl(templateString string, injectedData InjectedDataI) (
 		return ""
 	}
 
+	// Start from the templates defined in libraries, so they can be used by 'template' and 'include'
+	librarySet, err := getLibraryTemplates()
+	if err != nil {
+		return result, err
+	}
+
+	// include function is defined as clojure to intercept the set of templates being executed
+	includeDepth := 0
+	include := func(name string, data interface{}) (string, error) {
+		includeDepth++
+		defer func() { includeDepth-- }()
+
+		if includeDepth > includeMaxDepth {
+			return "", fmt.Errorf("rendering template '%s' has reached the max nested depth: %d", name, includeMaxDepth)
+		}
+
+		includeBuffer := new(bytes.Buffer)
+		if err := librarySet.ExecuteTemplate(includeBuffer, name, data); err != nil {
+			return "", err
+		}
+		return includeBuffer.String(), nil
+	}
+
+	// lookup function is defined as clojure to account the calls done per request
+	lookup := func(apiVersion, kind, namespace, name string) (map[string]interface{}, error) {
+		return lookupObject(injectedData, apiVersion, kind, namespace, name)
+	}
+
+	// can function is defined as clojure to cache the reviews done per request
+	can := func(user string, groups interface{}, verb, group, resource, namespace, name string) (bool, error) {
+		groupList, err := toStringList(groups)
+		if err != nil {
+			return false, err
+		}
+		return canAccess(injectedData, user, groupList, verb, group, resource, namespace, name)
+	}
+
+	// Changes related functions are defined as clojures to intercept 'data'
+	changed := func(path string) bool { return isPathChanged(injectedData, path) }
+	added := func(path string) bool { return isPathAdded(injectedData, path) }
+	removed := func(path string) bool { return isPathRemoved(injectedData, path) }
+
 	templateFunctionsMap := GetFunctionsMap()
 	templateFunctionsMap["setVar"] = setVar
+	templateFunctionsMap["include"] = include
+	templateFunctionsMap["lookup"] = lookup
+	templateFunctionsMap["can"] = can
+	templateFunctionsMap["changed"] = changed
+	templateFunctionsMap["added"] = added
+	templateFunctionsMap["removed"] = removed
 
 	// Create a Template object from the given string
-	parsedTemplate, err := template.New("main").Funcs(templateFunctionsMap).Parse(templateString)
+	parsedTemplate, err := librarySet.Funcs(templateFunctionsMap).New("main").Parse(templateString)
 	if err != nil {
 		return result, err
 	}
@@ -64,6 +134,63 @@ func EvaluateTemplateGotmpl(templateString string, injectedData InjectedDataI) (
 	return buffer.String(), nil
 }
 
+// getLibraryTemplates return a new set of templates containing the ones defined in TemplateLibrary resources.
+// Each item is available by its '{library}/{name}', and its 'define' blocks by their own names.
+// Items that can not be parsed are skipped, as they are reported in the status of their libraries
+func getLibraryTemplates() (*template.Template, error) {
+	libraryTemplatesCache.mu.Lock()
+	defer libraryTemplatesCache.mu.Unlock()
+
+	registry := globals.Application.TemplateLibraryRegistry
+	if registry == nil {
+		return template.New("library").Funcs(getPlaceholderFunctionsMap()), nil
+	}
+
+	revision := registry.GetRevision()
+	if libraryTemplatesCache.set == nil || libraryTemplatesCache.revision != revision {
+		librarySet := template.New("library").Funcs(getPlaceholderFunctionsMap())
+
+		libraryTemplates := registry.GetGotmplTemplates()
+		libraryTemplateNames := maps.Keys(libraryTemplates)
+		slices.Sort(libraryTemplateNames)
+
+		for _, templateName := range libraryTemplateNames {
+			// Parse each item in a separated set first, so a broken one does not break the rest
+			if _, err := ParseLibraryTemplateGotmpl(templateName, libraryTemplates[templateName]); err != nil {
+				log.Printf("skipping library template '%s': %s", templateName, err.Error())
+				continue
+			}
+
+			_, _ = librarySet.New(templateName).Parse(libraryTemplates[templateName])
+		}
+
+		libraryTemplatesCache.set = librarySet
+		libraryTemplatesCache.revision = revision
+	}
+
+	return libraryTemplatesCache.set.Clone()
+}
+
+// ParseLibraryTemplateGotmpl parses a Go template coming from a TemplateLibrary
+// to check it can be used later by policies
+func ParseLibraryTemplateGotmpl(name string, templateString string) (*template.Template, error) {
+	return template.New(name).Funcs(getPlaceholderFunctionsMap()).Parse(templateString)
+}
+
+// getPlaceholderFunctionsMap return the functions map with placeholders for the functions that are defined
+// on each evaluation. Go templates need all the functions to be known at parse time
+func getPlaceholderFunctionsMap() template.FuncMap {
+	f := GetFunctionsMap()
+	f["setVar"] = func(string, interface{}) string { return "" }
+	f["include"] = func(string, interface{}) (string, error) { return "", nil }
+	f["lookup"] = func(string, string, string, string) (map[string]interface{}, error) { return nil, nil }
+	f["can"] = func(string, interface{}, string, string, string, string, string) (bool, error) { return false, nil }
+	f["changed"] = func(string) bool { return false }
+	f["added"] = func(string) bool { return false }
+	f["removed"] = func(string) bool { return false }
+	return f
+}
+
 // GetFunctionsMap return a map with equivalency between functions for inside templating and real Golang ones
 func GetFunctionsMap() template.FuncMap {
 	f := sprig.TxtFuncMap()
@@ -93,6 +220,11 @@ func GetFunctionsMap() template.FuncMap {
 		f[k] = v
 	}
 
+	// Helpers shared with the rest of the engines
+	for _, helper := range helperFunctions {
+		f[helper.Name] = helper.Function
+	}
+
 	return f
 }
 
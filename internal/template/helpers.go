@@ -0,0 +1,281 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+
+	//
+	"github.com/Masterminds/semver/v3"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// FOLKS, ATTENTION HERE:
+// Following functions are registered in all the engines (CEL, gotmpl and Starlark) with the same names and semantics.
+// They are implemented once here, and each engine adapts its own types to them.
+// To add a new helper, just add it to the list below.
+
+const (
+	helperTypeString = "string"
+	helperTypeInt    = "int"
+	helperTypeBool   = "bool"
+	helperTypeMap    = "map"
+	helperTypeAny    = "any"
+)
+
+// helperFunctionT represents a helper function available in all the engines
+type helperFunctionT struct {
+	Name string
+
+	// Types are needed by typed engines, such as CEL, to declare the functions
+	ArgTypes   []string
+	ResultType string
+
+	Function func(args ...any) (any, error)
+}
+
+var (
+	helperFunctions = []helperFunctionT{
+		{
+			// parseImage("nginx:1.25") -> {registry: "docker.io", repository: "library/nginx", tag: "1.25", digest: ""}
+			Name:       "parseImage",
+			ArgTypes:   []string{helperTypeString},
+			ResultType: helperTypeMap,
+			Function:   helperParseImage,
+		},
+		{
+			// compareQuantity("500m", "1") -> -1
+			Name:       "compareQuantity",
+			ArgTypes:   []string{helperTypeString, helperTypeString},
+			ResultType: helperTypeInt,
+			Function:   helperCompareQuantity,
+		},
+		{
+			// compareSemver("v1.2.3", "1.10.0") -> -1
+			Name:       "compareSemver",
+			ArgTypes:   []string{helperTypeString, helperTypeString},
+			ResultType: helperTypeInt,
+			Function:   helperCompareSemver,
+		},
+		{
+			// matchLabelSelector("app=web,tier in (front)", {"app": "web", "tier": "front"}) -> true
+			// matchLabelSelector({"matchLabels": {"app": "web"}}, {"app": "web"}) -> true
+			Name:       "matchLabelSelector",
+			ArgTypes:   []string{helperTypeAny, helperTypeAny},
+			ResultType: helperTypeBool,
+			Function:   helperMatchLabelSelector,
+		},
+		{
+			// cidrContains("10.0.0.0/8", "10.1.2.3") -> true
+			// cidrContains("10.0.0.0/8", "10.1.0.0/16") -> true
+			Name:       "cidrContains",
+			ArgTypes:   []string{helperTypeString, helperTypeString},
+			ResultType: helperTypeBool,
+			Function:   helperCidrContains,
+		},
+	}
+)
+
+// getHelperStringArgs return the arguments as strings, checking the amount of them
+func getHelperStringArgs(name string, expected int, args []any) ([]string, error) {
+	if len(args) != expected {
+		return nil, fmt.Errorf("%s: expected %d arguments, got %d", name, expected, len(args))
+	}
+
+	result := make([]string, 0, len(args))
+	for index, arg := range args {
+		argString, ok := arg.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s: argument %d must be a string, got %T", name, index+1, arg)
+		}
+		result = append(result, argString)
+	}
+	return result, nil
+}
+
+// helperParseImage splits a container image reference into its parts, following Docker normalization rules:
+// images without registry come from 'docker.io', official ones are under 'library/',
+// and 'latest' tag is assumed when neither tag nor digest are present
+func helperParseImage(args ...any) (any, error) {
+	stringArgs, err := getHelperStringArgs("parseImage", 1, args)
+	if err != nil {
+		return nil, err
+	}
+
+	image := strings.TrimSpace(stringArgs[0])
+	if image == "" {
+		return nil, errors.New("parseImage: image reference is empty")
+	}
+
+	result := map[string]any{
+		"registry":   "docker.io",
+		"repository": "",
+		"tag":        "",
+		"digest":     "",
+	}
+
+	// Digest goes at the end, after '@'
+	name, digest, _ := strings.Cut(image, "@")
+	result["digest"] = digest
+
+	// Tag goes after the last ':', only when it's not part of the registry (host:port)
+	if tagIndex := strings.LastIndex(name, ":"); tagIndex > strings.LastIndex(name, "/") {
+		result["tag"] = name[tagIndex+1:]
+		name = name[:tagIndex]
+	}
+
+	// First component is the registry only when it looks like a host
+	if firstComponent, rest, found := strings.Cut(name, "/"); found &&
+		(strings.ContainsAny(firstComponent, ".:") || firstComponent == "localhost") {
+		result["registry"] = firstComponent
+		name = rest
+	}
+
+	if result["registry"] == "docker.io" && !strings.Contains(name, "/") {
+		name = "library/" + name
+	}
+	result["repository"] = name
+
+	if name == "" || strings.HasSuffix(name, "/") {
+		return nil, fmt.Errorf("parseImage: invalid image reference '%s'", image)
+	}
+
+	if result["tag"] == "" && result["digest"] == "" {
+		result["tag"] = "latest"
+	}
+
+	return result, nil
+}
+
+// helperCompareQuantity compares two Kubernetes quantities, returning -1, 0 or 1
+func helperCompareQuantity(args ...any) (any, error) {
+	stringArgs, err := getHelperStringArgs("compareQuantity", 2, args)
+	if err != nil {
+		return nil, err
+	}
+
+	quantityA, err := resource.ParseQuantity(stringArgs[0])
+	if err != nil {
+		return nil, fmt.Errorf("compareQuantity: invalid quantity '%s': %s", stringArgs[0], err.Error())
+	}
+
+	quantityB, err := resource.ParseQuantity(stringArgs[1])
+	if err != nil {
+		return nil, fmt.Errorf("compareQuantity: invalid quantity '%s': %s", stringArgs[1], err.Error())
+	}
+
+	return int64(quantityA.Cmp(quantityB)), nil
+}
+
+// helperCompareSemver compares two semantic versions, returning -1, 0 or 1.
+// Leading 'v' is accepted
+func helperCompareSemver(args ...any) (any, error) {
+	stringArgs, err := getHelperStringArgs("compareSemver", 2, args)
+	if err != nil {
+		return nil, err
+	}
+
+	versionA, err := semver.NewVersion(stringArgs[0])
+	if err != nil {
+		return nil, fmt.Errorf("compareSemver: invalid version '%s': %s", stringArgs[0], err.Error())
+	}
+
+	versionB, err := semver.NewVersion(stringArgs[1])
+	if err != nil {
+		return nil, fmt.Errorf("compareSemver: invalid version '%s': %s", stringArgs[1], err.Error())
+	}
+
+	return int64(versionA.Compare(versionB)), nil
+}
+
+// helperMatchLabelSelector checks whether a set of labels matches a selector.
+// The selector can be a string, as in kubectl ('app=web,tier in (front)'),
+// or a LabelSelector structure ({matchLabels, matchExpressions}), as in Kubernetes objects
+func helperMatchLabelSelector(args ...any) (any, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("matchLabelSelector: expected 2 arguments, got %d", len(args))
+	}
+
+	var selector labels.Selector
+	var err error
+
+	switch typedSelector := args[0].(type) {
+	case string:
+		selector, err = labels.Parse(typedSelector)
+	default:
+		labelSelector := metav1.LabelSelector{}
+		if err = convertViaJSON(typedSelector, &labelSelector); err == nil {
+			selector, err = metav1.LabelSelectorAsSelector(&labelSelector)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("matchLabelSelector: invalid selector: %s", err.Error())
+	}
+
+	labelSet := map[string]string{}
+	if args[1] != nil {
+		if err = convertViaJSON(args[1], &labelSet); err != nil {
+			return nil, fmt.Errorf("matchLabelSelector: labels must be a map of strings: %s", err.Error())
+		}
+	}
+
+	return selector.Matches(labels.Set(labelSet)), nil
+}
+
+// helperCidrContains checks whether a CIDR contains an IP address or another CIDR
+func helperCidrContains(args ...any) (any, error) {
+	stringArgs, err := getHelperStringArgs("cidrContains", 2, args)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix, err := netip.ParsePrefix(stringArgs[0])
+	if err != nil {
+		return nil, fmt.Errorf("cidrContains: invalid CIDR '%s': %s", stringArgs[0], err.Error())
+	}
+	prefix = prefix.Masked()
+
+	if strings.Contains(stringArgs[1], "/") {
+		innerPrefix, err := netip.ParsePrefix(stringArgs[1])
+		if err != nil {
+			return nil, fmt.Errorf("cidrContains: invalid CIDR '%s': %s", stringArgs[1], err.Error())
+		}
+		return innerPrefix.Bits() >= prefix.Bits() && prefix.Contains(innerPrefix.Addr()), nil
+	}
+
+	address, err := netip.ParseAddr(stringArgs[1])
+	if err != nil {
+		return nil, fmt.Errorf("cidrContains: invalid IP address '%s': %s", stringArgs[1], err.Error())
+	}
+
+	return prefix.Contains(address), nil
+}
+
+// convertViaJSON converts a generic value into a typed one doing a JSON round trip
+func convertViaJSON(source any, target any) error {
+	sourceBytes, err := json.Marshal(source)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(sourceBytes, target)
+}
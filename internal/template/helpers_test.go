@@ -0,0 +1,327 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// helperTestT represents a test case for a helper function
+type helperTestT struct {
+	name     string
+	args     []any
+	expected any
+
+	// expectedErr is a part of the expected error message. Empty when no error is expected
+	expectedErr string
+}
+
+// runHelperTests calls the helper with the arguments of each test case, checking its result or error
+func runHelperTests(t *testing.T, helper func(args ...any) (any, error), tests []helperTestT) {
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := helper(test.args...)
+
+			if test.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.expectedErr) {
+					t.Fatalf("expected error containing '%s', got: %v", test.expectedErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Errorf("unexpected result: got %v (%T), want %v (%T)", result, result, test.expected, test.expected)
+			}
+		})
+	}
+}
+
+// getTestImage return the expected result of parseImage
+func getTestImage(registry, repository, tag, digest string) map[string]any {
+	return map[string]any{"registry": registry, "repository": repository, "tag": tag, "digest": digest}
+}
+
+func TestHelperParseImage(t *testing.T) {
+	runHelperTests(t, helperParseImage, []helperTestT{
+		{
+			name:     "official image without tag",
+			args:     []any{"nginx"},
+			expected: getTestImage("docker.io", "library/nginx", "latest", ""),
+		},
+		{
+			name:     "official image with tag",
+			args:     []any{"nginx:1.25"},
+			expected: getTestImage("docker.io", "library/nginx", "1.25", ""),
+		},
+		{
+			name:     "user image in Docker Hub",
+			args:     []any{"someone/app:1.0"},
+			expected: getTestImage("docker.io", "someone/app", "1.0", ""),
+		},
+		{
+			name:     "registry with port and tag",
+			args:     []any{"registry.example.com:5000/team/app:1.0"},
+			expected: getTestImage("registry.example.com:5000", "team/app", "1.0", ""),
+		},
+		{
+			name:     "registry port is not a tag",
+			args:     []any{"registry.example.com:5000/team/app"},
+			expected: getTestImage("registry.example.com:5000", "team/app", "latest", ""),
+		},
+		{
+			name:     "localhost registry",
+			args:     []any{"localhost/app"},
+			expected: getTestImage("localhost", "app", "latest", ""),
+		},
+		{
+			name:     "digest without tag",
+			args:     []any{"gcr.io/project/app@sha256:abc123"},
+			expected: getTestImage("gcr.io", "project/app", "", "sha256:abc123"),
+		},
+		{
+			name:     "digest with tag",
+			args:     []any{"app:1.0@sha256:abc123"},
+			expected: getTestImage("docker.io", "library/app", "1.0", "sha256:abc123"),
+		},
+		{
+			name:     "digest with registry port",
+			args:     []any{"registry.example.com:5000/app@sha256:abc123"},
+			expected: getTestImage("registry.example.com:5000", "app", "", "sha256:abc123"),
+		},
+		{
+			name:        "empty reference",
+			args:        []any{" "},
+			expectedErr: "parseImage: image reference is empty",
+		},
+		{
+			name:        "reference without repository",
+			args:        []any{"registry.example.com/"},
+			expectedErr: "parseImage: invalid image reference",
+		},
+		{
+			name:        "non string argument",
+			args:        []any{42},
+			expectedErr: "parseImage: argument 1 must be a string",
+		},
+		{
+			name:        "wrong amount of arguments",
+			args:        []any{"nginx", "apache"},
+			expectedErr: "parseImage: expected 1 arguments, got 2",
+		},
+	})
+}
+
+func TestHelperCompareQuantity(t *testing.T) {
+	runHelperTests(t, helperCompareQuantity, []helperTestT{
+		{
+			name:     "lower",
+			args:     []any{"500m", "1"},
+			expected: int64(-1),
+		},
+		{
+			name:     "equal with different units",
+			args:     []any{"1Gi", "1024Mi"},
+			expected: int64(0),
+		},
+		{
+			name:     "greater",
+			args:     []any{"2", "1500m"},
+			expected: int64(1),
+		},
+		{
+			name:     "decimal and binary suffixes",
+			args:     []any{"1G", "1Gi"},
+			expected: int64(-1),
+		},
+		{
+			name:        "invalid first quantity",
+			args:        []any{"abc", "1"},
+			expectedErr: "compareQuantity: invalid quantity 'abc'",
+		},
+		{
+			name:        "invalid second quantity",
+			args:        []any{"1", "1 Gi"},
+			expectedErr: "compareQuantity: invalid quantity '1 Gi'",
+		},
+		{
+			name:        "empty quantity",
+			args:        []any{"", "1"},
+			expectedErr: "compareQuantity: invalid quantity ''",
+		},
+	})
+}
+
+func TestHelperCompareSemver(t *testing.T) {
+	runHelperTests(t, helperCompareSemver, []helperTestT{
+		{
+			name:     "numeric comparison with leading v",
+			args:     []any{"v1.2.3", "1.10.0"},
+			expected: int64(-1),
+		},
+		{
+			name:     "equal",
+			args:     []any{"1.2.0", "v1.2.0"},
+			expected: int64(0),
+		},
+		{
+			name:     "partial versions are completed",
+			args:     []any{"1.2", "1.2.0"},
+			expected: int64(0),
+		},
+		{
+			name:     "pre-release is lower than release",
+			args:     []any{"1.0.0-alpha", "1.0.0"},
+			expected: int64(-1),
+		},
+		{
+			name:     "pre-releases are compared",
+			args:     []any{"1.0.0-beta.2", "1.0.0-alpha.10"},
+			expected: int64(1),
+		},
+		{
+			name:     "build metadata is ignored",
+			args:     []any{"1.0.0+build.1", "1.0.0+build.2"},
+			expected: int64(0),
+		},
+		{
+			name:        "invalid version",
+			args:        []any{"latest", "1.0.0"},
+			expectedErr: "compareSemver: invalid version 'latest'",
+		},
+	})
+}
+
+func TestHelperMatchLabelSelector(t *testing.T) {
+	runHelperTests(t, helperMatchLabelSelector, []helperTestT{
+		{
+			name:     "string selector matches",
+			args:     []any{"app=web,tier in (front)", map[string]any{"app": "web", "tier": "front"}},
+			expected: true,
+		},
+		{
+			name:     "string selector does not match",
+			args:     []any{"app=web,tier in (front)", map[string]any{"app": "web", "tier": "back"}},
+			expected: false,
+		},
+		{
+			name:     "empty string selector matches everything",
+			args:     []any{"", map[string]any{"app": "web"}},
+			expected: true,
+		},
+		{
+			name:     "matchLabels",
+			args:     []any{map[string]any{"matchLabels": map[string]any{"app": "web"}}, map[string]string{"app": "web", "tier": "front"}},
+			expected: true,
+		},
+		{
+			name: "matchExpressions",
+			args: []any{
+				map[string]any{"matchExpressions": []any{
+					map[string]any{"key": "tier", "operator": "NotIn", "values": []any{"back"}},
+				}},
+				map[string]any{"tier": "back"},
+			},
+			expected: false,
+		},
+		{
+			name:     "missing labels are empty",
+			args:     []any{"app!=web", nil},
+			expected: true,
+		},
+		{
+			name:        "invalid string selector",
+			args:        []any{"app in (", map[string]any{}},
+			expectedErr: "matchLabelSelector: invalid selector",
+		},
+		{
+			name:        "invalid operator",
+			args:        []any{map[string]any{"matchExpressions": []any{map[string]any{"key": "app", "operator": "Like"}}}, map[string]any{}},
+			expectedErr: "matchLabelSelector: invalid selector",
+		},
+		{
+			name:        "labels with non string values",
+			args:        []any{"app=web", map[string]any{"app": 1}},
+			expectedErr: "matchLabelSelector: labels must be a map of strings",
+		},
+		{
+			name:        "wrong amount of arguments",
+			args:        []any{"app=web"},
+			expectedErr: "matchLabelSelector: expected 2 arguments, got 1",
+		},
+	})
+}
+
+func TestHelperCidrContains(t *testing.T) {
+	runHelperTests(t, helperCidrContains, []helperTestT{
+		{
+			name:     "address inside",
+			args:     []any{"10.0.0.0/8", "10.1.2.3"},
+			expected: true,
+		},
+		{
+			name:     "address outside",
+			args:     []any{"10.0.0.0/8", "11.0.0.1"},
+			expected: false,
+		},
+		{
+			name:     "narrower CIDR inside",
+			args:     []any{"10.0.0.0/8", "10.1.0.0/16"},
+			expected: true,
+		},
+		{
+			name:     "wider CIDR is not contained",
+			args:     []any{"10.1.0.0/16", "10.0.0.0/8"},
+			expected: false,
+		},
+		{
+			name:     "CIDR with host bits is masked",
+			args:     []any{"10.1.2.3/8", "10.200.0.1"},
+			expected: true,
+		},
+		{
+			name:     "IPv6 address inside",
+			args:     []any{"2001:db8::/32", "2001:db8::1"},
+			expected: true,
+		},
+		{
+			name:     "IPv4 address in IPv6 CIDR",
+			args:     []any{"2001:db8::/32", "10.0.0.1"},
+			expected: false,
+		},
+		{
+			name:        "invalid CIDR",
+			args:        []any{"10.0.0.0", "10.0.0.1"},
+			expectedErr: "cidrContains: invalid CIDR '10.0.0.0'",
+		},
+		{
+			name:        "invalid inner CIDR",
+			args:        []any{"10.0.0.0/8", "10.0.0.0/33"},
+			expectedErr: "cidrContains: invalid CIDR '10.0.0.0/33'",
+		},
+		{
+			name:        "invalid address",
+			args:        []any{"10.0.0.0/8", "10.0.0.256"},
+			expectedErr: "cidrContains: invalid IP address '10.0.0.256'",
+		},
+	})
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	//
+	"github.com/itchyny/gojq"
+)
+
+const (
+	// jqEvaluationTimeout limits the time a jq program can run, so programs that never end,
+	// such as 'repeat(.)', don't hold the request until Kubernetes gives up on the webhook
+	jqEvaluationTimeout = 2 * time.Second
+
+	// jqMaxResults limits the number of values a jq program can emit
+	jqMaxResults = 1000
+)
+
+// EvaluateTemplateJq evaluates a jq program against the injected data.
+// Injected data is passed as the input document, so it can be reached as '.object', '.sources', etc.
+// It is also exposed through jq variables ('$object', '$sources', etc.) to be usable inside nested filters.
+// Strings are printed raw (like 'jq -r'), the rest of values are printed as JSON.
+// When the program emits several values, they are joined by new lines.
+// Programs are stopped when they exceed jqEvaluationTimeout or emit more than jqMaxResults values
+func EvaluateTemplateJq(template string, injectedData InjectedDataI) (result string, err error) {
+
+	// gojq only understands the types produced by encoding/json
+	injectedDataMap, err := getNormalizedInjectedData(injectedData)
+	if err != nil {
+		return result, err
+	}
+
+	// Variables order must match between compilation and execution
+	variableNames := make([]string, 0, len(injectedDataMap))
+	for key := range injectedDataMap {
+		variableNames = append(variableNames, key)
+	}
+	sort.Strings(variableNames)
+
+	variableDeclarations := make([]string, 0, len(variableNames))
+	variableValues := make([]any, 0, len(variableNames))
+	for _, name := range variableNames {
+		variableDeclarations = append(variableDeclarations, "$"+name)
+		variableValues = append(variableValues, injectedDataMap[name])
+	}
+
+	query, err := gojq.Parse(template)
+	if err != nil {
+		return result, fmt.Errorf("error parsing jq program: %s", err.Error())
+	}
+
+	code, err := gojq.Compile(query, gojq.WithVariables(variableDeclarations))
+	if err != nil {
+		return result, fmt.Errorf("error compiling jq program: %s", err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), jqEvaluationTimeout)
+	defer cancel()
+
+	var outputList []string
+	iter := code.RunWithContext(ctx, injectedDataMap, variableValues...)
+	for {
+		value, ok := iter.Next()
+		if !ok {
+			break
+		}
+
+		if valueErr, isErr := value.(error); isErr {
+			if errors.Is(valueErr, context.DeadlineExceeded) {
+				return result, fmt.Errorf("error evaluating jq program: timeout of %s exceeded", jqEvaluationTimeout)
+			}
+			return result, fmt.Errorf("error evaluating jq program: %s", valueErr.Error())
+		}
+
+		if len(outputList) >= jqMaxResults {
+			return result, fmt.Errorf("error evaluating jq program: more than %d results emitted", jqMaxResults)
+		}
+
+		if valueString, isString := value.(string); isString {
+			outputList = append(outputList, valueString)
+			continue
+		}
+
+		valueBytes, err := json.Marshal(value)
+		if err != nil {
+			return result, fmt.Errorf("error converting jq output into JSON: %s", err.Error())
+		}
+		outputList = append(outputList, string(valueBytes))
+	}
+
+	return strings.Join(outputList, "\n"), nil
+}
+
+// getNormalizedInjectedData return the injected data with the types produced by encoding/json, doing a round trip.
+// Engines working on JSON documents, such as jq or Rego, only understand those types
+func getNormalizedInjectedData(injectedData InjectedDataI) (map[string]any, error) {
+	injectedDataBytes, err := json.Marshal(injectedData.ToMap())
+	if err != nil {
+		return nil, fmt.Errorf("error converting injected data into JSON: %s", err.Error())
+	}
+
+	injectedDataMap := map[string]any{}
+	err = json.Unmarshal(injectedDataBytes, &injectedDataMap)
+	if err != nil {
+		return nil, fmt.Errorf("error converting injected data from JSON: %s", err.Error())
+	}
+
+	return injectedDataMap, nil
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestEvaluateTemplateJq(t *testing.T) {
+	runTemplateEngineTests(t, EvaluateTemplateJq, []templateEngineTestT{
+		{
+			name:     "strings are printed raw",
+			template: `.object.metadata.name`,
+			expected: "example",
+		},
+		{
+			name:     "non string values are printed as JSON",
+			template: `.object.metadata.labels`,
+			expected: `{"team":"platform"}`,
+		},
+		{
+			name:     "several values are joined by new lines",
+			template: `.sources["0"][] | .metadata.name`,
+			expected: "first\nsecond",
+		},
+		{
+			name:     "programs emitting nothing print an empty string",
+			template: `empty`,
+			expected: "",
+		},
+		{
+			name:     "injected data is reachable through variables",
+			template: `[.sources["0"][] | select(.metadata.name == $object.metadata.name)] | length`,
+			expected: "0",
+		},
+		{
+			name:     "vars are reachable",
+			template: `$vars.owner`,
+			expected: "someone",
+		},
+		{
+			name:        "parse errors are reported",
+			template:    `.object.metadata.name |`,
+			expectedErr: "error parsing jq program",
+		},
+		{
+			name:        "compilation errors are reported",
+			template:    `$undeclared`,
+			expectedErr: "error compiling jq program",
+		},
+		{
+			name:        "runtime errors are reported",
+			template:    `error("broken")`,
+			expectedErr: "broken",
+		},
+		{
+			name:        "endless programs are stopped",
+			template:    `def f: f; f`,
+			expectedErr: fmt.Sprintf("timeout of %s exceeded", jqEvaluationTimeout),
+		},
+		{
+			name:     "values collected into a single result are not limited",
+			template: fmt.Sprintf(`[range(%d)] | length`, jqMaxResults+1),
+			expected: fmt.Sprintf("%d", jqMaxResults+1),
+		},
+		{
+			name:     "programs emitting the maximum of results are allowed",
+			template: fmt.Sprintf(`range(%d) | select(. == %d)`, jqMaxResults, jqMaxResults-1),
+			expected: fmt.Sprintf("%d", jqMaxResults-1),
+		},
+		{
+			name:        "programs emitting more than the maximum of results are stopped",
+			template:    fmt.Sprintf(`range(%d)`, jqMaxResults+1),
+			expectedErr: fmt.Sprintf("more than %d results emitted", jqMaxResults),
+		},
+		{
+			name:        "endless emitters are stopped",
+			template:    `repeat(.)`,
+			expectedErr: "results emitted",
+		},
+	})
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	"errors"
+
+	//
+	"github.com/freepik-company/admitik/internal/globals"
+)
+
+// lookupObject return an object from Kubernetes following Helm's 'lookup' behavior.
+// When the name is empty, a List object is returned, containing the objects under 'items'.
+// Empty map is returned when the object does not exist
+func lookupObject(injectedData InjectedDataI, apiVersion, kind, namespace, name string) (map[string]any, error) {
+	if name == "" {
+		return lookupObjectList(injectedData, apiVersion, kind, namespace, "")
+	}
+
+	if fixtures := injectedData.GetRequestState().Fixtures; fixtures != nil {
+		return fixtures.getObject(apiVersion, kind, namespace, name), nil
+	}
+
+	lookupClient := globals.Application.KubeLookupClient
+	if lookupClient == nil {
+		return nil, errors.New("lookups from templates are not available")
+	}
+
+	return lookupClient.Get(&injectedData.GetRequestState().LookupCalls, apiVersion, kind, namespace, name)
+}
+
+// lookupObjectList return a List object from Kubernetes, containing the objects under 'items'
+func lookupObjectList(injectedData InjectedDataI, apiVersion, kind, namespace, labelSelector string) (map[string]any, error) {
+	if fixtures := injectedData.GetRequestState().Fixtures; fixtures != nil {
+		return fixtures.listObjects(apiVersion, kind, namespace, labelSelector)
+	}
+
+	lookupClient := globals.Application.KubeLookupClient
+	if lookupClient == nil {
+		return nil, errors.New("lookups from templates are not available")
+	}
+
+	return lookupClient.List(&injectedData.GetRequestState().LookupCalls, apiVersion, kind, namespace, labelSelector)
+}
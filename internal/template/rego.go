@@ -0,0 +1,194 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	//
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/rego"
+)
+
+const (
+	// regoDefaultQuery is the rule queried when the template does not declare one
+	regoDefaultQuery = "data.admitik.result"
+
+	// regoQueryCommentPrefix is the prefix of the comment, in the first line of the template, declaring the query
+	regoQueryCommentPrefix = "# query:"
+
+	// regoEvaluationTimeout limits the time a Rego query can run
+	regoEvaluationTimeout = 2 * time.Second
+
+	// regoQueryCacheMaxSize limits the number of compiled modules kept in memory.
+	// The cache is emptied when it's reached, as it only grows when policies are changed
+	regoQueryCacheMaxSize = 500
+)
+
+var (
+	// regoUnsafeBuiltins are the built-in functions disabled in templates, as they reach the network
+	// or expose the environment of the controller
+	regoUnsafeBuiltins = map[string]struct{}{
+		"http.send":          {},
+		"net.lookup_ip_addr": {},
+		"opa.runtime":        {},
+	}
+
+	regoQueryCache      = map[string]*rego.PreparedEvalQuery{}
+	regoQueryCacheMutex sync.Mutex
+)
+
+// EvaluateTemplateRego evaluates a Rego module against the injected data, querying the rule declared
+// in the first line of the template as '# query: data.<package>.<rule>' (or 'data.admitik.result' by default).
+// Injected data is passed as 'input', so it can be reached as 'input.object', 'input.sources["0"]', etc.
+// Strings are printed raw, the rest of values are printed as JSON.
+// Items of sets and lists, such as Gatekeeper's 'violation' rules, are printed one per line.
+// Undefined results are printed as an empty string
+func EvaluateTemplateRego(template string, injectedData InjectedDataI) (result string, err error) {
+
+	query, err := getRegoPreparedQuery(template)
+	if err != nil {
+		return result, err
+	}
+
+	input, err := getNormalizedInjectedData(injectedData)
+	if err != nil {
+		return result, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), regoEvaluationTimeout)
+	defer cancel()
+
+	resultSet, err := query.Eval(ctx, rego.EvalInput(input))
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return result, fmt.Errorf("error evaluating Rego query: timeout of %s exceeded", regoEvaluationTimeout)
+		}
+		return result, fmt.Errorf("error evaluating Rego query: %s", err.Error())
+	}
+
+	// Undefined rules produce no results
+	if len(resultSet) == 0 || len(resultSet[0].Expressions) == 0 {
+		return "", nil
+	}
+
+	value := resultSet[0].Expressions[0].Value
+
+	valueList, isList := value.([]any)
+	if !isList {
+		return renderRegoValue(value)
+	}
+
+	outputList := make([]string, 0, len(valueList))
+	for _, item := range valueList {
+		itemString, err := renderRegoValue(item)
+		if err != nil {
+			return result, err
+		}
+		outputList = append(outputList, itemString)
+	}
+
+	return strings.Join(outputList, "\n"), nil
+}
+
+// renderRegoValue return a value produced by Rego as a string: raw for strings, and as JSON for the rest
+func renderRegoValue(value any) (string, error) {
+	if valueString, isString := value.(string); isString {
+		return valueString, nil
+	}
+
+	valueBytes, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("error converting Rego output into JSON: %s", err.Error())
+	}
+
+	return string(valueBytes), nil
+}
+
+// getRegoQuery return the query declared in the first line of a Rego template, or the default one
+func getRegoQuery(template string) string {
+	firstLine, _, _ := strings.Cut(strings.TrimSpace(template), "\n")
+	firstLine = strings.TrimSpace(firstLine)
+
+	if !strings.HasPrefix(firstLine, regoQueryCommentPrefix) {
+		return regoDefaultQuery
+	}
+
+	return strings.TrimSpace(strings.TrimPrefix(firstLine, regoQueryCommentPrefix))
+}
+
+// getRegoPreparedQuery return the compiled query for a Rego template.
+// Compiling Rego is expensive, so compiled queries are cached by the hash of their template
+func getRegoPreparedQuery(template string) (*rego.PreparedEvalQuery, error) {
+
+	templateHash := sha256.Sum256([]byte(template))
+	cacheKey := hex.EncodeToString(templateHash[:])
+
+	regoQueryCacheMutex.Lock()
+	query, found := regoQueryCache[cacheKey]
+	regoQueryCacheMutex.Unlock()
+
+	if found {
+		return query, nil
+	}
+
+	query, err := compileRego(template)
+	if err != nil {
+		return nil, err
+	}
+
+	regoQueryCacheMutex.Lock()
+	if len(regoQueryCache) >= regoQueryCacheMaxSize {
+		regoQueryCache = map[string]*rego.PreparedEvalQuery{}
+	}
+	regoQueryCache[cacheKey] = query
+	regoQueryCacheMutex.Unlock()
+
+	return query, nil
+}
+
+// compileRego compiles a Rego template with its query.
+// Modules are compiled with Rego v1 syntax, falling back to v0 syntax to keep existing Gatekeeper libraries working.
+// Modules calling regoUnsafeBuiltins are rejected
+func compileRego(template string) (*rego.PreparedEvalQuery, error) {
+
+	var compileErrors []error
+	for _, regoVersion := range []ast.RegoVersion{ast.RegoV1, ast.RegoV0} {
+		query, err := rego.New(
+			rego.Query(getRegoQuery(template)),
+			rego.Module("template.rego", template),
+			rego.SetRegoVersion(regoVersion),
+			rego.UnsafeBuiltins(regoUnsafeBuiltins),
+		).PrepareForEval(context.Background())
+
+		if err == nil {
+			return &query, nil
+		}
+		compileErrors = append(compileErrors, err)
+	}
+
+	// Errors for the latest syntax are the ones worth reporting
+	return nil, fmt.Errorf("error compiling Rego module: %s", compileErrors[0].Error())
+}
@@ -0,0 +1,193 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestEvaluateTemplateRego(t *testing.T) {
+	runTemplateEngineTests(t, EvaluateTemplateRego, []templateEngineTestT{
+		{
+			name: "default query is used when not declared",
+			template: `
+package admitik
+
+result := input.object.metadata.name
+`,
+			expected: "example",
+		},
+		{
+			name: "declared query is used",
+			template: `# query: data.custom.name
+package custom
+
+name := upper(input.object.metadata.name)
+`,
+			expected: "EXAMPLE",
+		},
+		{
+			name: "non string values are printed as JSON",
+			template: `
+package admitik
+
+result := {"replicas": input.object.spec.replicas}
+`,
+			expected: `{"replicas":3}`,
+		},
+		{
+			name: "set items are printed one per line",
+			template: `# query: data.k8srequiredlabels.violation
+package k8srequiredlabels
+
+violation contains msg if {
+  some label in ["team", "cost-center"]
+  not input.object.metadata.labels[label]
+  msg := sprintf("missing label '%s'", [label])
+}
+`,
+			expected: "missing label 'cost-center'",
+		},
+		{
+			name: "empty sets are printed as an empty string",
+			template: `# query: data.k8srequiredlabels.violation
+package k8srequiredlabels
+
+violation contains msg if {
+  not input.object.metadata.name
+  msg := "missing name"
+}
+`,
+			expected: "",
+		},
+		{
+			name: "undefined results are printed as an empty string",
+			template: `
+package admitik
+
+result := "never" if { false }
+`,
+			expected: "",
+		},
+		{
+			name: "sources and vars are reachable",
+			template: `
+package admitik
+
+result := concat("/", [input.sources["0"][0].metadata.name, input.vars.owner])
+`,
+			expected: "first/someone",
+		},
+		{
+			name: "modules with v0 syntax are supported",
+			template: `# query: data.legacy.deny
+package legacy
+
+deny[msg] {
+  input.object.metadata.name == "example"
+  msg := "name not allowed"
+}
+`,
+			expected: "name not allowed",
+		},
+		{
+			name: "compilation errors are reported",
+			template: `
+package admitik
+
+result := 
+`,
+			expectedErr: "error compiling Rego module",
+		},
+		{
+			name: "modules failing with both syntaxes report errors of the latest one",
+			template: `
+package admitik
+
+deny[msg] {
+  msg := undeclared
+}
+`,
+			expectedErr: "`if` keyword is required before rule body",
+		},
+		{
+			name: "network built-in functions are rejected",
+			template: `
+package admitik
+
+result := http.send({"method": "GET", "url": "http://example.com"})
+`,
+			expectedErr: "unsafe built-in function calls in expression: http.send",
+		},
+		{
+			name: "network built-in functions are rejected with v0 syntax too",
+			template: `
+package admitik
+
+result = ips {
+  ips := net.lookup_ip_addr("example.com")
+}
+`,
+			expectedErr: "error compiling Rego module",
+		},
+		{
+			name: "runtime built-in function is rejected",
+			template: `
+package admitik
+
+result := opa.runtime().env
+`,
+			expectedErr: "unsafe built-in function calls in expression: opa.runtime",
+		},
+		{
+			name: "endless queries are stopped",
+			template: `
+package admitik
+
+result if {
+  some i in numbers.range(1, 100000)
+  some j in numbers.range(1, 100000)
+  i == j + 100000
+}
+`,
+			expectedErr: fmt.Sprintf("timeout of %s exceeded", regoEvaluationTimeout),
+		},
+	})
+}
+
+func TestGetRegoPreparedQueryCache(t *testing.T) {
+	template := `
+package admitik
+
+result := "cached"
+`
+
+	first, err := getRegoPreparedQuery(template)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	second, err := getRegoPreparedQuery(template)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if first != second {
+		t.Errorf("compiled query was expected to be reused")
+	}
+}
@@ -47,7 +47,11 @@ import (
 	modStarletString "github.com/1set/starlet/lib/string"
 
 	// own modules
+	modSelfKube "github.com/freepik-company/admitik/internal/template/starlarkmods/kube"
 	modSelfYaml "github.com/freepik-company/admitik/internal/template/starlarkmods/yaml"
+
+	//
+	"github.com/freepik-company/admitik/internal/globals"
 )
 
 func EvaluateTemplateStarlark(template string, injectedData InjectedDataI) (result string, err error) {
@@ -97,31 +101,66 @@ func EvaluateTemplateStarlark(template string, injectedData InjectedDataI) (resu
 
 ` + strings.Join(initLines, "\n") + "\n\n" + template
 
-	// This dictionary defines the pre-declared environment (injected libraries included).
-	starletBase64, _ := modStarletBase64.LoadModule()
-	starletCsv, _ := modStarletCsv.LoadModule()
-	starletHashlib, _ := modStarletHashlib.LoadModule()
-	starletHttp, _ := modStarletHttp.LoadModule()
-	starletLog, _ := modStarletLog.LoadModule()
-	starletNet, _ := modStarletNet.LoadModule()
-	starletRandom, _ := modStarletRandom.LoadModule()
-	starletRe, _ := modStarletRe.LoadModule()
-	starletString, _ := modStarletString.LoadModule()
-
 	// Add modules to predeclared
-	predeclaredData["math"] = modStarlarkMath.Module
-	predeclaredData["json"] = modStarlarkJson.Module
-	predeclaredData["time"] = modStarlarkTime.Module
-	predeclaredData["base64"] = starletBase64["base64"]
-	predeclaredData["csv"] = starletCsv["csv"]
-	predeclaredData["hashlib"] = starletHashlib["hashlib"]
-	predeclaredData["http"] = starletHttp["http"]
-	predeclaredData["log"] = starletLog["log"]
-	predeclaredData["net"] = starletNet["net"]
-	predeclaredData["random"] = starletRandom["random"]
-	predeclaredData["re"] = starletRe["re"]
-	predeclaredData["string"] = starletString["string"]
-	predeclaredData["yaml"] = modSelfYaml.Module
+	for moduleName, module := range getStarlarkModules() {
+		predeclaredData[moduleName] = module
+	}
+
+	// Module 'kube' is built per evaluation, as the calls are accounted per request
+	predeclaredData["kube"] = modSelfKube.NewModule(
+		func(apiVersion, kind, namespace, name string) (map[string]any, error) {
+			return lookupObject(injectedData, apiVersion, kind, namespace, name)
+		},
+		func(apiVersion, kind, namespace, labelSelector string) (map[string]any, error) {
+			return lookupObjectList(injectedData, apiVersion, kind, namespace, labelSelector)
+		})
+
+	// Function 'can' is built per evaluation, as the reviews are cached per request
+	predeclaredData["can"] = starlark.NewBuiltin("can", func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var user, verb, group, resource, namespace, name string
+		var groups *starlark.List
+		err := starlark.UnpackArgs(b.Name(), args, kwargs,
+			"user", &user, "groups", &groups, "verb", &verb, "group", &group, "resource", &resource,
+			"namespace?", &namespace, "name?", &name)
+		if err != nil {
+			return nil, err
+		}
+
+		groupsConverted, err := starletconv.Unmarshal(groups)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", b.Name(), err)
+		}
+		groupList, err := toStringList(groupsConverted)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", b.Name(), err)
+		}
+
+		allowed, err := canAccess(injectedData, user, groupList, verb, group, resource, namespace, name)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", b.Name(), err)
+		}
+		return starlark.Bool(allowed), nil
+	})
+
+	// Changes related functions are built per evaluation, as they need the injected data
+	for functionName, function := range map[string]func(InjectedDataI, string) bool{
+		"changed": isPathChanged,
+		"added":   isPathAdded,
+		"removed": isPathRemoved,
+	} {
+		predeclaredData[functionName] = starlark.NewBuiltin(functionName, func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			var path string
+			if err := starlark.UnpackArgs(b.Name(), args, kwargs, "path", &path); err != nil {
+				return nil, err
+			}
+			return starlark.Bool(function(injectedData, path)), nil
+		})
+	}
+
+	// Helpers shared with the rest of the engines
+	for _, helper := range helperFunctions {
+		predeclaredData[helper.Name] = newStarlarkHelperBuiltin(helper)
+	}
 
 	// Execute Starlark program in a file.
 	// Printed stuff will be captured as the result
@@ -131,6 +170,7 @@ func EvaluateTemplateStarlark(template string, injectedData InjectedDataI) (resu
 		Print: func(_ *starlark.Thread, msg string) {
 			starlarkPrints = append(starlarkPrints, msg)
 		},
+		Load: newStarlarkLibraryLoader(),
 	}
 
 	executionGlobals, err := starlark.ExecFileOptions(&starlarksyntax.FileOptions{}, thread, "template.star", template, predeclaredData)
@@ -156,3 +196,115 @@ func EvaluateTemplateStarlark(template string, injectedData InjectedDataI) (resu
 
 	return strings.Join(starlarkPrints, ""), nil
 }
+
+// getStarlarkModules return the modules available in the pre-declared environment
+func getStarlarkModules() starlark.StringDict {
+	starletBase64, _ := modStarletBase64.LoadModule()
+	starletCsv, _ := modStarletCsv.LoadModule()
+	starletHashlib, _ := modStarletHashlib.LoadModule()
+	starletHttp, _ := modStarletHttp.LoadModule()
+	starletLog, _ := modStarletLog.LoadModule()
+	starletNet, _ := modStarletNet.LoadModule()
+	starletRandom, _ := modStarletRandom.LoadModule()
+	starletRe, _ := modStarletRe.LoadModule()
+	starletString, _ := modStarletString.LoadModule()
+
+	return starlark.StringDict{
+		"math":    modStarlarkMath.Module,
+		"json":    modStarlarkJson.Module,
+		"time":    modStarlarkTime.Module,
+		"base64":  starletBase64["base64"],
+		"csv":     starletCsv["csv"],
+		"hashlib": starletHashlib["hashlib"],
+		"http":    starletHttp["http"],
+		"log":     starletLog["log"],
+		"net":     starletNet["net"],
+		"random":  starletRandom["random"],
+		"re":      starletRe["re"],
+		"string":  starletString["string"],
+		"yaml":    modSelfYaml.Module,
+	}
+}
+
+// starlarkLoadEntry represents the result of loading a module.
+// A nil entry in the cache means the module is being loaded, and it is used to detect cycles
+type starlarkLoadEntry struct {
+	globals starlark.StringDict
+	err     error
+}
+
+// newStarlarkLibraryLoader return a function to resolve 'load()' statements against TemplateLibrary resources.
+// Modules are referenced as '{library}/{name}' and executed only once per evaluation.
+// They can load other modules, and have the same pre-declared modules as templates, but not the injected data,
+// so they are expected to define functions receiving it as params
+func newStarlarkLibraryLoader() func(thread *starlark.Thread, module string) (starlark.StringDict, error) {
+	cache := make(map[string]*starlarkLoadEntry)
+
+	var load func(thread *starlark.Thread, module string) (starlark.StringDict, error)
+	load = func(thread *starlark.Thread, module string) (starlark.StringDict, error) {
+		entry, found := cache[module]
+		if found {
+			if entry == nil {
+				return nil, fmt.Errorf("cycle in load graph for module '%s'", module)
+			}
+			return entry.globals, entry.err
+		}
+
+		registry := globals.Application.TemplateLibraryRegistry
+		if registry == nil {
+			return nil, fmt.Errorf("module '%s' not found: template libraries are not available", module)
+		}
+
+		code, codeFound := registry.GetStarlarkModule(module)
+		if !codeFound {
+			return nil, fmt.Errorf("module '%s' not found in template libraries", module)
+		}
+
+		// Mark the module as being loaded
+		cache[module] = nil
+
+		moduleThread := &starlark.Thread{
+			Name:  "module:" + module,
+			Print: thread.Print,
+			Load:  load,
+		}
+
+		moduleGlobals, err := starlark.ExecFileOptions(&starlarksyntax.FileOptions{}, moduleThread, module, code, getStarlarkModules())
+		cache[module] = &starlarkLoadEntry{globals: moduleGlobals, err: err}
+
+		return moduleGlobals, err
+	}
+
+	return load
+}
+
+// ParseLibraryModuleStarlark parses a Starlark module coming from a TemplateLibrary
+// to check it can be used later by policies
+func ParseLibraryModuleStarlark(name string, code string) error {
+	_, err := (&starlarksyntax.FileOptions{}).Parse(name, code, 0)
+	return err
+}
+
+// newStarlarkHelperBuiltin adapts a helper shared with the rest of the engines to a Starlark builtin
+func newStarlarkHelperBuiltin(helper helperFunctionT) *starlark.Builtin {
+	return starlark.NewBuiltin(helper.Name, func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		if len(kwargs) > 0 {
+			return nil, fmt.Errorf("%s: unexpected keyword arguments", b.Name())
+		}
+
+		nativeArgs := make([]any, 0, len(args))
+		for _, arg := range args {
+			nativeArg, err := starletconv.Unmarshal(arg)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", b.Name(), err)
+			}
+			nativeArgs = append(nativeArgs, nativeArg)
+		}
+
+		result, err := helper.Function(nativeArgs...)
+		if err != nil {
+			return nil, err
+		}
+		return starletconv.Marshal(result)
+	})
+}
@@ -0,0 +1,74 @@
+package kube
+
+import (
+	"fmt"
+
+	starletconv "github.com/1set/starlet/dataconv"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+// GetFunc return an object from Kubernetes. Empty map means not found
+type GetFunc func(apiVersion, kind, namespace, name string) (map[string]any, error)
+
+// ListFunc return a List object from Kubernetes, containing the objects under 'items'
+type ListFunc func(apiVersion, kind, namespace, labelSelector string) (map[string]any, error)
+
+// NewModule return a 'kube' module whose functions are backed by the given ones.
+// It's built on each evaluation, as the calls are accounted per request
+func NewModule(get GetFunc, list ListFunc) *starlarkstruct.Module {
+	return &starlarkstruct.Module{
+		Name: "kube",
+		Members: starlark.StringDict{
+			"get":  starlark.NewBuiltin("kube.get", kubeGet(get)),
+			"list": starlark.NewBuiltin("kube.list", kubeList(list)),
+		},
+	}
+}
+
+// kubeGet return the object as a dict, or None when it does not exist.
+// Usage: kube.get(api_version, kind, namespace, name)
+func kubeGet(get GetFunc) func(*starlark.Thread, *starlark.Builtin, starlark.Tuple, []starlark.Tuple) (starlark.Value, error) {
+	return func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var apiVersion, kind, namespace, name string
+		err := starlark.UnpackArgs(b.Name(), args, kwargs,
+			"api_version", &apiVersion, "kind", &kind, "namespace", &namespace, "name", &name)
+		if err != nil {
+			return nil, err
+		}
+
+		object, err := get(apiVersion, kind, namespace, name)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", b.Name(), err)
+		}
+
+		if len(object) == 0 {
+			return starlark.None, nil
+		}
+		return starletconv.Marshal(object)
+	}
+}
+
+// kubeList return the objects as a list of dicts.
+// Usage: kube.list(api_version, kind, namespace="", label_selector="")
+func kubeList(list ListFunc) func(*starlark.Thread, *starlark.Builtin, starlark.Tuple, []starlark.Tuple) (starlark.Value, error) {
+	return func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var apiVersion, kind, namespace, labelSelector string
+		err := starlark.UnpackArgs(b.Name(), args, kwargs,
+			"api_version", &apiVersion, "kind", &kind, "namespace?", &namespace, "label_selector?", &labelSelector)
+		if err != nil {
+			return nil, err
+		}
+
+		objectList, err := list(apiVersion, kind, namespace, labelSelector)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", b.Name(), err)
+		}
+
+		items, _ := objectList["items"].([]any)
+		if items == nil {
+			items = []any{}
+		}
+		return starletconv.Marshal(items)
+	}
+}
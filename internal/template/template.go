@@ -21,6 +21,10 @@ const (
 	EngineGotmpl       string = "gotmpl"
 	EnginePlain        string = "plain"
 	EngineStarlark     string = "starlark"
+	EngineJq           string = "jq"
+	EngineRego         string = "rego"
+	EngineWasm         string = "wasm"
+	EngineCue          string = "cue"
 	EnginePlainWithCel string = "plain+cel"
 )
 
@@ -35,6 +39,14 @@ func EvaluateTemplate(engine string, template string, injectedData InjectedDataI
 		result, err = EvaluateTemplatePlain(template, injectedData)
 	case EngineStarlark:
 		result, err = EvaluateTemplateStarlark(template, injectedData)
+	case EngineJq:
+		result, err = EvaluateTemplateJq(template, injectedData)
+	case EngineRego:
+		result, err = EvaluateTemplateRego(template, injectedData)
+	case EngineWasm:
+		result, err = EvaluateTemplateWasm(template, injectedData)
+	case EngineCue:
+		result, err = EvaluateTemplateCue(template, injectedData)
 
 	// Separated as it's a compound type
 	case EnginePlainWithCel:
@@ -0,0 +1,99 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	"strings"
+	"testing"
+)
+
+// templateEngineTestT represents a test case for the engines rendering a template into a string
+type templateEngineTestT struct {
+	name     string
+	template string
+
+	// object replaces the default object of the injected data when defined
+	object map[string]any
+
+	expected string
+
+	// expectedErr is a part of the expected error message. Empty when no error is expected
+	expectedErr string
+}
+
+// getTestEngineObject return the object injected in the engine tests by default
+func getTestEngineObject() map[string]any {
+	return map[string]any{
+		"metadata": map[string]any{
+			"name":   "example",
+			"labels": map[string]any{"team": "platform"},
+		},
+		"spec": map[string]any{
+			"replicas": 3,
+			"template": map[string]any{
+				"spec": map[string]any{
+					"containers": []any{
+						map[string]any{"name": "app", "image": "registry.example.com/nginx"},
+					},
+				},
+			},
+		},
+	}
+}
+
+// getTestEngineInjectedData return the data injected in the engine tests, with two sources and a var
+func getTestEngineInjectedData(object map[string]any) *PolicyEvaluationDataT {
+	injectedData := &PolicyEvaluationDataT{}
+	injectedData.Initialize()
+
+	injectedData.Object = object
+	if injectedData.Object == nil {
+		injectedData.Object = getTestEngineObject()
+	}
+
+	injectedData.Sources[0] = []map[string]any{
+		{"metadata": map[string]any{"name": "first"}},
+		{"metadata": map[string]any{"name": "second"}},
+	}
+	injectedData.SetVar("owner", "someone")
+
+	return injectedData
+}
+
+// runTemplateEngineTests evaluates the test cases with the given engine, checking their results or errors
+func runTemplateEngineTests(t *testing.T, evaluate func(string, InjectedDataI) (string, error), tests []templateEngineTestT) {
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := evaluate(test.template, getTestEngineInjectedData(test.object))
+
+			if test.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.expectedErr) {
+					t.Fatalf("expected error containing '%s', got: %v", test.expectedErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if result != test.expected {
+				t.Errorf("unexpected result:\n got: %s\nwant: %s", result, test.expected)
+			}
+		})
+	}
+}
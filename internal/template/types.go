@@ -1,5 +1,13 @@
 package template
 
+import (
+	authenticationv1 "k8s.io/api/authentication/v1"
+
+	//
+	"github.com/freepik-company/admitik/internal/accessreview"
+	"github.com/freepik-company/admitik/internal/kubelookup"
+)
+
 type InjectedDataI interface {
 	// General methods
 	Initialize()
@@ -11,6 +19,25 @@ type InjectedDataI interface {
 	GetVars() (vars map[string]any)
 	SetVar(key string, value any)
 	SetVars(vars map[string]any)
+
+	// GetRequestState return the state shared by all the evaluations of the same request
+	GetRequestState() *RequestStateT
+}
+
+// RequestStateT holds data shared by all the templates evaluated for the same request or event.
+// It's referenced by pointer, so it survives the copies done for each policy, and it's never exposed to templates
+type RequestStateT struct {
+	// LookupCalls counts the calls done to Kubernetes by lookup functions
+	LookupCalls kubelookup.CallCounterT
+
+	// AccessReviews stores the results of the SubjectAccessReviews done by 'can' functions
+	AccessReviews accessreview.ResultCacheT
+
+	// Requester is the user performing the request, when it comes from an admission request
+	Requester *authenticationv1.UserInfo
+
+	// Fixtures replace the data coming from Kubernetes, when set. They are used during policy tests
+	Fixtures *FixturesT
 }
 
 // TriggerInjectedDataT contains the base data injected into policy templates during evaluation.
@@ -22,11 +49,27 @@ type TriggerInjectedDataT struct {
 
 	Object    map[string]any
 	OldObject map[string]any
+
+	// OriginalObject is the object as it came in the request. It only differs from Object
+	// during mutations, as each policy sees the object patched by the previous ones
+	OriginalObject map[string]any
+
+	// UserInfo is the user performing the request, when it comes from an admission request
+	UserInfo map[string]any
+
+	// Changes is the list of differences between OldObject and Object, only on updates
+	Changes []any
+
+	RequestState *RequestStateT
 }
 
 func (ida *TriggerInjectedDataT) Initialize() {
 	ida.Object = make(map[string]any)
 	ida.OldObject = make(map[string]any)
+	ida.UserInfo = make(map[string]any)
+	ida.Changes = make([]any, 0)
+
+	ida.RequestState = &RequestStateT{}
 }
 
 func (ida *TriggerInjectedDataT) ToMap() map[string]any {
@@ -35,6 +78,12 @@ func (ida *TriggerInjectedDataT) ToMap() map[string]any {
 	tmp["operation"] = ida.Operation
 	tmp["object"] = ida.Object
 	tmp["oldObject"] = ida.OldObject
+	tmp["originalObject"] = ida.OriginalObject
+	if ida.OriginalObject == nil {
+		tmp["originalObject"] = ida.Object
+	}
+	tmp["userInfo"] = ida.UserInfo
+	tmp["changes"] = ida.Changes
 
 	return tmp
 }
@@ -44,6 +93,13 @@ func (ida *TriggerInjectedDataT) GetVars() (vars map[string]any) { return nil }
 func (ida *TriggerInjectedDataT) SetVar(key string, value any)   {}
 func (ida *TriggerInjectedDataT) SetVars(vars map[string]any)    {}
 
+func (ida *TriggerInjectedDataT) GetRequestState() *RequestStateT {
+	if ida.RequestState == nil {
+		ida.RequestState = &RequestStateT{}
+	}
+	return ida.RequestState
+}
+
 // PolicyEvaluationDataT extends TriggerInjectedDataT with additional context for policy evaluation.
 // Provides access to source data collections and user-defined variables for use in conditions,
 // mutations, and resource generation templates.
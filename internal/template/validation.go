@@ -0,0 +1,88 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	"fmt"
+	"sort"
+	"text/template"
+
+	//
+	"github.com/itchyny/gojq"
+	starlarksyntax "go.starlark.net/syntax"
+)
+
+// ValidateTemplate checks a template for the given engine without evaluating it.
+// This way, broken templates are found when policies are created instead of when traffic arrives.
+// Templates are checked against the data injected into policies, so they can use sources and vars
+func ValidateTemplate(engine string, templateString string) (err error) {
+
+	injectedData := &PolicyEvaluationDataT{}
+	injectedData.Initialize()
+
+	switch engine {
+	case EngineCel, "":
+		_, _, err = compileCel(templateString, injectedData)
+	case EngineGotmpl:
+		_, err = template.New("main").Funcs(getPlaceholderFunctionsMap()).Parse(templateString)
+	case EnginePlain:
+		err = nil
+	case EngineStarlark:
+		_, err = (&starlarksyntax.FileOptions{}).Parse("template.star", templateString, 0)
+	case EngineJq:
+		err = validateJq(templateString, injectedData)
+	case EngineRego:
+		_, err = compileRego(templateString)
+	case EngineWasm:
+		err = validateWasm(templateString)
+	case EngineCue:
+		err = validateCue(templateString, injectedData)
+	case EnginePlainWithCel:
+		for _, match := range CellBracketExpressionRegexCompiled.FindAllStringSubmatch(templateString, -1) {
+			_, _, err = compileCel(match[1], injectedData)
+			if err != nil {
+				break
+			}
+		}
+	default:
+		err = fmt.Errorf("unknown engine '%s'", engine)
+	}
+
+	return err
+}
+
+// validateJq parses and compiles a jq program with the same variables used on evaluation
+func validateJq(templateString string, injectedData InjectedDataI) error {
+
+	variableDeclarations := []string{}
+	for key := range injectedData.ToMap() {
+		variableDeclarations = append(variableDeclarations, "$"+key)
+	}
+	sort.Strings(variableDeclarations)
+
+	query, err := gojq.Parse(templateString)
+	if err != nil {
+		return fmt.Errorf("error parsing jq program: %s", err.Error())
+	}
+
+	_, err = gojq.Compile(query, gojq.WithVariables(variableDeclarations))
+	if err != nil {
+		return fmt.Errorf("error compiling jq program: %s", err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,369 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	//
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+
+	//
+	"github.com/freepik-company/admitik/internal/globals"
+)
+
+const (
+	// wasmDefaultMemoryPages is the memory available for modules when not set, in pages of 64KiB (16MiB)
+	wasmDefaultMemoryPages = 256
+	wasmMaxMemoryPages     = 1024
+
+	// wasmDefaultTimeout is the time a module can run when not set.
+	// WebAssembly runtimes in pure Go don't meter executed instructions (fuel is not supported), so execution is bounded by time
+	wasmDefaultTimeout = 1 * time.Second
+	wasmMaxTimeout     = 5 * time.Second
+
+	// wasmMaxOutputBytes limits what a module can write to stdout and stderr
+	wasmMaxOutputBytes = 1024 * 1024
+
+	// wasmConfigMapCacheTTL is the time modules read from ConfigMaps are kept before reading them again
+	wasmConfigMapCacheTTL = 30 * time.Second
+
+	// wasmModuleCacheMaxSize limits the number of compiled modules kept in memory.
+	// Once it's reached, new modules are compiled on each evaluation
+	wasmModuleCacheMaxSize = 100
+)
+
+// wasmTemplateT represents the template of the 'wasm' engine, which points to the module to run
+type wasmTemplateT struct {
+	Module struct {
+		ConfigMapRef *struct {
+			Namespace string `json:"namespace"`
+			Name      string `json:"name"`
+			Key       string `json:"key"`
+		} `json:"configMapRef,omitempty"`
+
+		// Inline is the module encoded in base64
+		Inline string `json:"inline,omitempty"`
+	} `json:"module"`
+
+	Limits struct {
+		MemoryPages uint32          `json:"memoryPages,omitempty"`
+		Timeout     metav1.Duration `json:"timeout,omitempty"`
+	} `json:"limits,omitempty"`
+}
+
+// wasmCompiledModuleT represents a module compiled for a runtime with specific memory limits
+type wasmCompiledModuleT struct {
+	runtime wazero.Runtime
+	module  wazero.CompiledModule
+}
+
+// wasmConfigMapModuleT represents a module read from a ConfigMap
+type wasmConfigMapModuleT struct {
+	module    []byte
+	expiresAt time.Time
+}
+
+var (
+	wasmModuleCache      = map[string]*wasmCompiledModuleT{}
+	wasmModuleCacheMutex sync.Mutex
+
+	wasmConfigMapCache      = map[string]*wasmConfigMapModuleT{}
+	wasmConfigMapCacheMutex sync.Mutex
+)
+
+// wasmLimitedBufferT is a buffer that fails when writing more than its limit
+type wasmLimitedBufferT struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *wasmLimitedBufferT) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.limit {
+		return 0, fmt.Errorf("output exceeds %d bytes", b.limit)
+	}
+	return b.Buffer.Write(p)
+}
+
+// EvaluateTemplateWasm runs a WebAssembly module (WASI preview1 command) against the injected data.
+// The template is a YAML document pointing to the module, inline or in a ConfigMap, and setting its limits.
+// Injected data is written to stdin as JSON, and whatever the module writes to stdout is the result.
+// Stderr is forwarded to logs. Neither filesystem, network, clock nor random sources are available
+func EvaluateTemplateWasm(template string, injectedData InjectedDataI) (result string, err error) {
+
+	wasmTemplate, err := parseWasmTemplate(template)
+	if err != nil {
+		return result, err
+	}
+
+	moduleBytes, err := getWasmModuleBytes(wasmTemplate)
+	if err != nil {
+		return result, err
+	}
+
+	compiledModule, cached, err := getWasmCompiledModule(moduleBytes, wasmTemplate.Limits.MemoryPages)
+	if err != nil {
+		return result, err
+	}
+	if !cached {
+		defer compiledModule.runtime.Close(context.Background())
+	}
+
+	input, err := json.Marshal(injectedData.ToMap())
+	if err != nil {
+		return result, fmt.Errorf("error converting injected data into JSON: %s", err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), wasmTemplate.Limits.Timeout.Duration)
+	defer cancel()
+
+	stdout := &wasmLimitedBufferT{limit: wasmMaxOutputBytes}
+	stderr := &wasmLimitedBufferT{limit: wasmMaxOutputBytes}
+
+	// Modules are anonymous, so the same one can be instantiated concurrently
+	moduleConfig := wazero.NewModuleConfig().
+		WithName("").
+		WithArgs("admitik").
+		WithStdin(bytes.NewReader(input)).
+		WithStdout(stdout).
+		WithStderr(stderr)
+
+	moduleInstance, err := compiledModule.runtime.InstantiateModule(ctx, compiledModule.module, moduleConfig)
+	if moduleInstance != nil {
+		defer moduleInstance.Close(context.Background())
+	}
+
+	if stderr.Len() > 0 {
+		logger := log.FromContext(globals.Application.Context).WithValues("engine", EngineWasm)
+		logger.Info("wasm module wrote to stderr", "stderr", strings.TrimSpace(stderr.String()))
+	}
+
+	if err != nil {
+		exitErr := &sys.ExitError{}
+		if !errors.As(err, &exitErr) {
+			return result, fmt.Errorf("error running wasm module: %s", err.Error())
+		}
+
+		switch exitErr.ExitCode() {
+		case 0:
+		case sys.ExitCodeDeadlineExceeded:
+			return result, fmt.Errorf("error running wasm module: timeout of %s exceeded", wasmTemplate.Limits.Timeout.Duration)
+		default:
+			return result, fmt.Errorf("error running wasm module: exited with code %d", exitErr.ExitCode())
+		}
+	}
+
+	return stdout.String(), nil
+}
+
+// parseWasmTemplate decodes the template of the 'wasm' engine, validating it and filling the defaults
+func parseWasmTemplate(template string) (wasmTemplate *wasmTemplateT, err error) {
+	wasmTemplate = &wasmTemplateT{}
+
+	err = yaml.UnmarshalStrict([]byte(template), wasmTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding wasm template: %s", err.Error())
+	}
+
+	configMapRef := wasmTemplate.Module.ConfigMapRef
+	if (configMapRef == nil) == (wasmTemplate.Module.Inline == "") {
+		return nil, errors.New("error decoding wasm template: exactly one of 'module.configMapRef' or 'module.inline' is required")
+	}
+
+	if configMapRef != nil && (configMapRef.Namespace == "" || configMapRef.Name == "" || configMapRef.Key == "") {
+		return nil, errors.New("error decoding wasm template: 'namespace', 'name' and 'key' are required in 'module.configMapRef'")
+	}
+
+	if wasmTemplate.Limits.MemoryPages == 0 {
+		wasmTemplate.Limits.MemoryPages = wasmDefaultMemoryPages
+	}
+	if wasmTemplate.Limits.MemoryPages > wasmMaxMemoryPages {
+		return nil, fmt.Errorf("error decoding wasm template: 'limits.memoryPages' can not exceed %d", wasmMaxMemoryPages)
+	}
+
+	if wasmTemplate.Limits.Timeout.Duration == 0 {
+		wasmTemplate.Limits.Timeout.Duration = wasmDefaultTimeout
+	}
+	if wasmTemplate.Limits.Timeout.Duration < 0 || wasmTemplate.Limits.Timeout.Duration > wasmMaxTimeout {
+		return nil, fmt.Errorf("error decoding wasm template: 'limits.timeout' must be positive and can not exceed %s", wasmMaxTimeout)
+	}
+
+	return wasmTemplate, nil
+}
+
+// getWasmModuleBytes return the module pointed by the template.
+// Modules read from ConfigMaps are cached for a short time, so Kubernetes is not asked on each evaluation
+func getWasmModuleBytes(wasmTemplate *wasmTemplateT) ([]byte, error) {
+
+	if wasmTemplate.Module.Inline != "" {
+		moduleBytes, err := base64.StdEncoding.DecodeString(strings.TrimSpace(wasmTemplate.Module.Inline))
+		if err != nil {
+			return nil, fmt.Errorf("error decoding inline wasm module: %s", err.Error())
+		}
+		return moduleBytes, nil
+	}
+
+	configMapRef := wasmTemplate.Module.ConfigMapRef
+	cacheKey := strings.Join([]string{configMapRef.Namespace, configMapRef.Name, configMapRef.Key}, "/")
+
+	wasmConfigMapCacheMutex.Lock()
+	cachedModule, found := wasmConfigMapCache[cacheKey]
+	wasmConfigMapCacheMutex.Unlock()
+
+	if found && time.Now().Before(cachedModule.expiresAt) {
+		return cachedModule.module, nil
+	}
+
+	coreClient := globals.Application.KubeRawCoreClient
+	if coreClient == nil {
+		return nil, errors.New("wasm modules from ConfigMaps are not available")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), wasmDefaultTimeout)
+	defer cancel()
+
+	configMap, err := coreClient.CoreV1().ConfigMaps(configMapRef.Namespace).Get(ctx, configMapRef.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error reading wasm module from ConfigMap: %s", err.Error())
+	}
+
+	moduleBytes, found := configMap.BinaryData[configMapRef.Key]
+	if !found {
+		return nil, fmt.Errorf("error reading wasm module from ConfigMap: key '%s' not found in binaryData", configMapRef.Key)
+	}
+
+	wasmConfigMapCacheMutex.Lock()
+	wasmConfigMapCache[cacheKey] = &wasmConfigMapModuleT{
+		module:    moduleBytes,
+		expiresAt: time.Now().Add(wasmConfigMapCacheTTL),
+	}
+	wasmConfigMapCacheMutex.Unlock()
+
+	return moduleBytes, nil
+}
+
+// getWasmCompiledModule return a module compiled for a runtime with the given memory limit.
+// Compiling is expensive, so compiled modules are cached by the hash of their bytes and their limit.
+// Modules are compiled without holding the cache lock, so a slow module does not block the evaluation of others.
+// When the cache is full, the module is not cached, and the caller must close its runtime
+func getWasmCompiledModule(moduleBytes []byte, memoryPages uint32) (compiledModule *wasmCompiledModuleT, cached bool, err error) {
+
+	moduleHash := sha256.Sum256(moduleBytes)
+	cacheKey := fmt.Sprintf("%s/%d", hex.EncodeToString(moduleHash[:]), memoryPages)
+
+	wasmModuleCacheMutex.Lock()
+	cachedModule, found := wasmModuleCache[cacheKey]
+	wasmModuleCacheMutex.Unlock()
+
+	if found {
+		return cachedModule, true, nil
+	}
+
+	compiledModule, err = compileWasm(moduleBytes, memoryPages)
+	if err != nil {
+		return nil, false, err
+	}
+
+	wasmModuleCacheMutex.Lock()
+	defer wasmModuleCacheMutex.Unlock()
+
+	// The same module could have been compiled concurrently, so the cached one is kept
+	if cachedModule, found := wasmModuleCache[cacheKey]; found {
+		_ = compiledModule.runtime.Close(context.Background())
+		return cachedModule, true, nil
+	}
+
+	if len(wasmModuleCache) >= wasmModuleCacheMaxSize {
+		return compiledModule, false, nil
+	}
+
+	wasmModuleCache[cacheKey] = compiledModule
+	return compiledModule, true, nil
+}
+
+// compileWasm compiles a module in a new runtime with the given memory limit and WASI available.
+// Running modules are closed when their context is done, so timeouts stop endless modules
+func compileWasm(moduleBytes []byte, memoryPages uint32) (*wasmCompiledModuleT, error) {
+	ctx := context.Background()
+
+	runtimeConfig := wazero.NewRuntimeConfig().
+		WithMemoryLimitPages(memoryPages).
+		WithCloseOnContextDone(true)
+
+	runtime := wazero.NewRuntimeWithConfig(ctx, runtimeConfig)
+
+	_, err := wasi_snapshot_preview1.Instantiate(ctx, runtime)
+	if err != nil {
+		_ = runtime.Close(ctx)
+		return nil, fmt.Errorf("error preparing WASI for wasm module: %s", err.Error())
+	}
+
+	module, err := runtime.CompileModule(ctx, moduleBytes)
+	if err != nil {
+		_ = runtime.Close(ctx)
+		return nil, fmt.Errorf("error compiling wasm module: %s", err.Error())
+	}
+
+	return &wasmCompiledModuleT{
+		runtime: runtime,
+		module:  module,
+	}, nil
+}
+
+// validateWasm checks the template of the 'wasm' engine, compiling the module when it's inline
+func validateWasm(template string) error {
+	wasmTemplate, err := parseWasmTemplate(template)
+	if err != nil {
+		return err
+	}
+
+	if wasmTemplate.Module.Inline == "" {
+		return nil
+	}
+
+	moduleBytes, err := getWasmModuleBytes(wasmTemplate)
+	if err != nil {
+		return err
+	}
+
+	compiledModule, cached, err := getWasmCompiledModule(moduleBytes, wasmTemplate.Limits.MemoryPages)
+	if err != nil {
+		return err
+	}
+
+	// Not cached modules are compiled again on evaluation, so they are just checked here
+	if !cached {
+		_ = compiledModule.runtime.Close(context.Background())
+	}
+
+	return nil
+}
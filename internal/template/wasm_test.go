@@ -0,0 +1,287 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Helpers to assemble tiny WebAssembly modules for the tests
+// Ref: https://webassembly.github.io/spec/core/binary/index.html
+
+func wasmUnsignedLEB(value uint32) (result []byte) {
+	for {
+		b := byte(value & 0x7f)
+		value >>= 7
+		if value != 0 {
+			b |= 0x80
+		}
+		result = append(result, b)
+		if value == 0 {
+			return result
+		}
+	}
+}
+
+func wasmSignedLEB(value int32) (result []byte) {
+	for {
+		b := byte(value & 0x7f)
+		value >>= 7
+		if (value == 0 && b&0x40 == 0) || (value == -1 && b&0x40 != 0) {
+			return append(result, b)
+		}
+		result = append(result, b|0x80)
+	}
+}
+
+func wasmVector(items ...[]byte) []byte {
+	result := wasmUnsignedLEB(uint32(len(items)))
+	for _, item := range items {
+		result = append(result, item...)
+	}
+	return result
+}
+
+func wasmName(name string) []byte {
+	return append(wasmUnsignedLEB(uint32(len(name))), name...)
+}
+
+func wasmSection(id byte, content []byte) []byte {
+	return append(append([]byte{id}, wasmUnsignedLEB(uint32(len(content)))...), content...)
+}
+
+func wasmI32Const(value int32) []byte {
+	return append([]byte{0x41}, wasmSignedLEB(value)...)
+}
+
+func wasmJoin(parts ...[]byte) (result []byte) {
+	for _, part := range parts {
+		result = append(result, part...)
+	}
+	return result
+}
+
+// getTestWasmModule return a WASI command whose '_start' function runs the given instructions.
+// It imports 'fd_read' and 'fd_write' as functions 0 and 1, and exports a memory of one page
+// initialized with the given data at the given offset
+func getTestWasmModule(instructions []byte, dataOffset int32, data string) string {
+	const i32 = 0x7f
+
+	ioFunctionType := wasmJoin([]byte{0x60}, wasmVector([]byte{i32}, []byte{i32}, []byte{i32}, []byte{i32}), wasmVector([]byte{i32}))
+	startFunctionType := wasmJoin([]byte{0x60}, wasmVector(), wasmVector())
+
+	body := wasmJoin(wasmVector(), instructions, []byte{0x0b})
+
+	module := wasmJoin(
+		[]byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00},
+		wasmSection(1, wasmVector(ioFunctionType, startFunctionType)),
+		wasmSection(2, wasmVector(
+			wasmJoin(wasmName("wasi_snapshot_preview1"), wasmName("fd_read"), []byte{0x00, 0x00}),
+			wasmJoin(wasmName("wasi_snapshot_preview1"), wasmName("fd_write"), []byte{0x00, 0x00}),
+		)),
+		wasmSection(3, wasmVector([]byte{0x01})),
+		wasmSection(5, wasmVector([]byte{0x00, 0x01})),
+		wasmSection(7, wasmVector(
+			wasmJoin(wasmName("_start"), []byte{0x00, 0x02}),
+			wasmJoin(wasmName("memory"), []byte{0x02, 0x00}),
+		)),
+		wasmSection(10, wasmVector(wasmJoin(wasmUnsignedLEB(uint32(len(body))), body))),
+		wasmSection(11, wasmVector(
+			wasmJoin([]byte{0x00}, wasmI32Const(dataOffset), []byte{0x0b}, wasmName(data)),
+		)),
+	)
+
+	return base64.StdEncoding.EncodeToString(module)
+}
+
+// getTestWasmEchoModule return a module writing to stdout what it reads from stdin, up to 4KiB.
+// The I/O vector lives at 0, the count of bytes at 16, and the buffer at 64
+func getTestWasmEchoModule() string {
+	instructions := wasmJoin(
+		// iovec = {64, 4096}
+		wasmI32Const(0), wasmI32Const(64), []byte{0x36, 0x02, 0x00},
+		wasmI32Const(4), wasmI32Const(4096), []byte{0x36, 0x02, 0x00},
+		// fd_read(stdin, iovec, 1, &count)
+		wasmI32Const(0), wasmI32Const(0), wasmI32Const(1), wasmI32Const(16), []byte{0x10, 0x00, 0x1a},
+		// iovec.length = count
+		wasmI32Const(4), wasmI32Const(16), []byte{0x28, 0x02, 0x00}, []byte{0x36, 0x02, 0x00},
+		// fd_write(stdout, iovec, 1, &count)
+		wasmI32Const(1), wasmI32Const(0), wasmI32Const(1), wasmI32Const(16), []byte{0x10, 0x01, 0x1a},
+	)
+	return getTestWasmModule(instructions, 1024, "")
+}
+
+// getTestWasmPrintModule return a module writing a fixed text to stdout.
+// The I/O vector lives at 0, the count of bytes at 16, and the text at 64
+func getTestWasmPrintModule(text string) string {
+	instructions := wasmJoin(
+		// iovec = {64, len(text)}
+		wasmI32Const(0), wasmI32Const(64), []byte{0x36, 0x02, 0x00},
+		wasmI32Const(4), wasmI32Const(int32(len(text))), []byte{0x36, 0x02, 0x00},
+		// fd_write(stdout, iovec, 1, &count)
+		wasmI32Const(1), wasmI32Const(0), wasmI32Const(1), wasmI32Const(16), []byte{0x10, 0x01, 0x1a},
+	)
+	return getTestWasmModule(instructions, 64, text)
+}
+
+// getTestWasmEndlessModule return a module that never ends
+func getTestWasmEndlessModule() string {
+	// loop { br 0 }
+	return getTestWasmModule([]byte{0x03, 0x40, 0x0c, 0x00, 0x0b}, 64, "")
+}
+
+func TestEvaluateTemplateWasm(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		expected string
+
+		// expectedErr is a part of the expected error message. Empty when no error is expected
+		expectedErr string
+	}{
+		{
+			name: "stdout is the result",
+			template: fmt.Sprintf(`
+module:
+  inline: %s
+`, getTestWasmPrintModule("valid")),
+			expected: "valid",
+		},
+		{
+			name: "injected data is written to stdin as JSON",
+			template: fmt.Sprintf(`
+module:
+  inline: %s
+limits:
+  memoryPages: 16
+  timeout: 500ms
+`, getTestWasmEchoModule()),
+			expected: `"name":"example"`,
+		},
+		{
+			name: "endless modules are stopped",
+			template: fmt.Sprintf(`
+module:
+  inline: %s
+limits:
+  timeout: 100ms
+`, getTestWasmEndlessModule()),
+			expectedErr: "timeout of 100ms exceeded",
+		},
+		{
+			name: "invalid modules are reported",
+			template: fmt.Sprintf(`
+module:
+  inline: %s
+`, base64.StdEncoding.EncodeToString([]byte("not a module"))),
+			expectedErr: "error compiling wasm module",
+		},
+		{
+			name: "module source is required",
+			template: `
+limits:
+  memoryPages: 16
+`,
+			expectedErr: "exactly one of",
+		},
+		{
+			name: "memory limit is bounded",
+			template: fmt.Sprintf(`
+module:
+  inline: %s
+limits:
+  memoryPages: 100000
+`, getTestWasmPrintModule("valid")),
+			expectedErr: "'limits.memoryPages' can not exceed",
+		},
+		{
+			name: "unknown fields are rejected",
+			template: fmt.Sprintf(`
+module:
+  inline: %s
+limits:
+  fuel: 1000
+`, getTestWasmPrintModule("valid")),
+			expectedErr: "unknown field",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			injectedData := &PolicyEvaluationDataT{}
+			injectedData.Initialize()
+			injectedData.Object = map[string]any{
+				"metadata": map[string]any{
+					"name": "example",
+				},
+			}
+
+			result, err := EvaluateTemplateWasm(test.template, injectedData)
+
+			if test.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.expectedErr) {
+					t.Fatalf("expected error containing '%s', got: %v", test.expectedErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !strings.Contains(result, test.expected) {
+				t.Errorf("unexpected result: got '%s', want it containing '%s'", result, test.expected)
+			}
+		})
+	}
+}
+
+func TestGetWasmCompiledModuleConcurrently(t *testing.T) {
+	moduleBytes, err := base64.StdEncoding.DecodeString(getTestWasmPrintModule("concurrent"))
+	if err != nil {
+		t.Fatalf("failed decoding test module: %s", err.Error())
+	}
+
+	// A memory limit not used by other tests, so the module is not cached yet
+	const memoryPages = 3
+
+	compiledModules := make([]*wasmCompiledModuleT, 8)
+	errs := make([]error, len(compiledModules))
+
+	var wg sync.WaitGroup
+	for i := range compiledModules {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			compiledModules[i], _, errs[i] = getWasmCompiledModule(moduleBytes, memoryPages)
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range compiledModules {
+		if errs[i] != nil {
+			t.Fatalf("unexpected error: %v", errs[i])
+		}
+		if compiledModules[i] != compiledModules[0] {
+			t.Errorf("modules compiled concurrently were expected to be replaced by the cached one")
+		}
+	}
+}